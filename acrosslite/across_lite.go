@@ -15,7 +15,8 @@ import (
 // https://www.litsoft.com/.  It is described in
 // https://www.litsoft.com/across/docs/AcrossTextFormat.pdf#31
 type AcrossLite struct {
-	Size         int
+	Rows         int
+	Cols         int
 	Name         string
 	Title        string
 	Author       string
@@ -23,6 +24,9 @@ type AcrossLite struct {
 	Grid         []string
 	AcrossClues  map[int]string
 	DownClues    map[int]string
+	Rebus        map[int]string // Full text of multi-letter cells, by cell index
+	Circles      map[int]bool   // Circled cells, by cell index
+	Notes        string
 	CreatedDate  time.Time
 	ModifiedDate time.Time
 }
@@ -43,6 +47,8 @@ func NewAcrossLite() *AcrossLite {
 	pal.Grid = make([]string, 0)
 	pal.AcrossClues = make(map[int]string)
 	pal.DownClues = make(map[int]string)
+	pal.Rebus = make(map[int]string)
+	pal.Circles = make(map[int]bool)
 
 	return pal
 }
@@ -51,6 +57,14 @@ func NewAcrossLite() *AcrossLite {
 // Methods
 // ---------------------------------------------------------------------
 
+// CellIndex returns the zero-based index of a cell in the grid, reading
+// from left to right and top to bottom.  The row and column are
+// relative to 1, not 0.  This is the key used in the Rebus and Circles
+// maps.
+func (pal *AcrossLite) CellIndex(r, c int) int {
+	return (r-1)*pal.Cols + (c - 1)
+}
+
 // GetAcrossClues returns a map of across word numbers to their clues.
 func (pal *AcrossLite) GetAcrossClues() map[int]string {
 	return pal.AcrossClues
@@ -64,17 +78,17 @@ func (pal *AcrossLite) GetAuthor() string {
 // GetCell returns the letter at a given point in the grid.  These are
 // relative to 1, not 0, so
 //
-//	r = 1, 2, ..., n and c = 1, 2, ..., n
+//	r = 1, 2, ..., nRows and c = 1, 2, ..., nCols
 //
 // If the letter value is '\x00', it is a black cell.  Otherwise, it is
 // converted to uppercase.  If the letter is not a black cell and not in
 // the alphabet A-Z, an error is returned.
 func (pal *AcrossLite) GetCell(r, c int) (byte, error) {
-	n := pal.GetSize()
-	if n < 1 {
+	nRows, nCols := pal.GetRows(), pal.GetCols()
+	if nRows < 1 || nCols < 1 {
 		return 0, fmt.Errorf("puzzle size has not yet been set")
 	}
-	if r < 1 || r > n || c < 1 || c > n {
+	if r < 1 || r > nRows || c < 1 || c > nCols {
 		return 0, fmt.Errorf("invalid index: r=%d,c=%d", r, c)
 	}
 	i, j := r-1, c-1
@@ -87,6 +101,11 @@ func (pal *AcrossLite) GetCell(r, c int) (byte, error) {
 	return letter, nil
 }
 
+// GetCols returns the number of columns in this puzzle
+func (pal *AcrossLite) GetCols() int {
+	return pal.Cols
+}
+
 // GetCopyright returns the copyright line
 func (pal *AcrossLite) GetCopyright() string {
 	return pal.Copyright
@@ -120,6 +139,11 @@ func (pal *AcrossLite) GetModifiedDate() time.Time {
 	return pal.ModifiedDate
 }
 
+// GetNotes returns the free-form notes for the puzzle
+func (pal *AcrossLite) GetNotes() string {
+	return pal.Notes
+}
+
 // GetName returns the puzzle name, which will be used as part of the
 // key in the database representation.
 //
@@ -136,9 +160,21 @@ func (pal *AcrossLite) GetNotepad() string {
 	return "{" + strings.Join(parts, ",") + "}"
 }
 
-// GetSize returns the number of rows or columns in this puzzle
-func (pal *AcrossLite) GetSize() int {
-	return pal.Size
+// GetRebus returns the full text of a multi-letter cell, or the empty
+// string if the cell holds a single letter.  The grid itself contains
+// only the first letter of such a cell.
+func (pal *AcrossLite) GetRebus(r, c int) string {
+	return pal.Rebus[pal.CellIndex(r, c)]
+}
+
+// GetRows returns the number of rows in this puzzle
+func (pal *AcrossLite) GetRows() int {
+	return pal.Rows
+}
+
+// IsCircled returns true if the cell at the given point is circled.
+func (pal *AcrossLite) IsCircled(r, c int) bool {
+	return pal.Circles[pal.CellIndex(r, c)]
 }
 
 // GetTitle returns the puzzle title, which is a descriptive string that
@@ -165,7 +201,7 @@ func (pal *AcrossLite) SetAuthor(author string) {
 // SetCell sets the letter at a given point in the grid.  These are
 // relative to 1, not 0, so
 //
-//	r = 1, 2, ..., n and c = 1, 2, ..., n
+//	r = 1, 2, ..., nRows and c = 1, 2, ..., nCols
 //
 // If the letter value is '\x00', it is a black cell, which must be
 // represented by '.' in this struct element, according to the
@@ -173,11 +209,11 @@ func (pal *AcrossLite) SetAuthor(author string) {
 func (pal *AcrossLite) SetCell(r, c int, letter byte) error {
 
 	// Size must have already been parsed
-	n := pal.GetSize()
-	if n < 1 {
+	nRows, nCols := pal.GetRows(), pal.GetCols()
+	if nRows < 1 || nCols < 1 {
 		return fmt.Errorf("puzzle size has not yet been set")
 	}
-	if r < 1 || r > n || c < 1 || c > n {
+	if r < 1 || r > nRows || c < 1 || c > nCols {
 		return fmt.Errorf("invalid index: r=%d,c=%d", r, c)
 	}
 
@@ -206,6 +242,18 @@ func (pal *AcrossLite) SetCell(r, c int, letter byte) error {
 	return nil
 }
 
+// SetCircled marks or unmarks the cell at the given point as circled.
+func (pal *AcrossLite) SetCircled(r, c int, circled bool) {
+	if pal.Circles == nil {
+		pal.Circles = make(map[int]bool)
+	}
+	if circled {
+		pal.Circles[pal.CellIndex(r, c)] = true
+	} else {
+		delete(pal.Circles, pal.CellIndex(r, c))
+	}
+}
+
 // SetCopyright sets the copyright line
 func (pal *AcrossLite) SetCopyright(copyright string) {
 	pal.Copyright = copyright
@@ -231,12 +279,39 @@ func (pal *AcrossLite) SetName(name string) {
 	pal.Name = name
 }
 
-// SetSize sets the number of rows or columns in this puzzle
-func (pal *AcrossLite) SetSize(n int) {
-	pal.Size = n
-	pal.Grid = make([]string, n)
-	for i := 0; i < n; i++ {
-		pal.Grid[i] = strings.Repeat(" ", n)
+// SetNotes sets the free-form notes for the puzzle
+func (pal *AcrossLite) SetNotes(notes string) {
+	pal.Notes = notes
+}
+
+// SetRebus sets the full text of a multi-letter cell.  The grid itself
+// is set to the first letter of the text.  Setting text of length one
+// or less removes the rebus.
+func (pal *AcrossLite) SetRebus(r, c int, text string) error {
+	if pal.Rebus == nil {
+		pal.Rebus = make(map[int]string)
+	}
+	if len(text) <= 1 {
+		delete(pal.Rebus, pal.CellIndex(r, c))
+		if len(text) == 1 {
+			return pal.SetCell(r, c, text[0])
+		}
+		return nil
+	}
+	if err := pal.SetCell(r, c, text[0]); err != nil {
+		return err
+	}
+	pal.Rebus[pal.CellIndex(r, c)] = text
+	return nil
+}
+
+// SetSize sets the number of rows and columns in this puzzle
+func (pal *AcrossLite) SetSize(nRows, nCols int) {
+	pal.Rows = nRows
+	pal.Cols = nCols
+	pal.Grid = make([]string, nRows)
+	for i := 0; i < nRows; i++ {
+		pal.Grid[i] = strings.Repeat(" ", nCols)
 	}
 }
 
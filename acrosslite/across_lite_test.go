@@ -10,7 +10,8 @@ import (
 
 /*
 	type fields struct {
-		Size         int
+		Rows         int
+		Cols         int
 		Name         string
 		Title        string
 		Author       string
@@ -25,7 +26,8 @@ import (
 
 func CreateTestObject() *AcrossLite {
 	var pal = NewAcrossLite()
-	pal.Size = 15
+	pal.Rows = 15
+	pal.Cols = 15
 	pal.Name = "disney"
 	pal.Title = "Failed the Audition"
 	pal.Author = "Jack London"
@@ -447,7 +449,7 @@ func TestAcrossLite_SetCell(t *testing.T) {
 		{
 			pal: func() *AcrossLite {
 				pal := CreateTestObject()
-				pal.SetSize(0)
+				pal.SetSize(0, 0)
 				return pal
 			}(),
 			wantErr: true,
@@ -468,7 +470,7 @@ func TestAcrossLite_SetCell(t *testing.T) {
 				assert.Nil(t, err)
 				assert.Equal(t, want, have)
 			case true:
-				if pal.GetSize() == 0 {
+				if pal.GetRows() == 0 {
 					err := pal.SetCell(1, 1, 'a')
 					assert.NotNil(t, err)
 				} else {
@@ -484,20 +486,30 @@ func TestAcrossLite_SetSize(t *testing.T) {
 	tests := []struct {
 		name    string
 		pal     *AcrossLite
-		newSize int
+		newRows int
+		newCols int
 	}{
 		{
+			name:    "square",
 			pal:     CreateTestObject(),
-			newSize: 21,
+			newRows: 21,
+			newCols: 21,
+		},
+		{
+			name:    "rectangular",
+			pal:     CreateTestObject(),
+			newRows: 15,
+			newCols: 21,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pal := tt.pal
-			want := tt.newSize
-			pal.SetSize(tt.newSize)
-			have := pal.GetSize()
-			assert.Equal(t, want, have)
+			pal.SetSize(tt.newRows, tt.newCols)
+			assert.Equal(t, tt.newRows, pal.GetRows())
+			assert.Equal(t, tt.newCols, pal.GetCols())
+			assert.Equal(t, tt.newRows, len(pal.GetGrid()))
+			assert.Equal(t, tt.newCols, len(pal.GetGrid()[0]))
 		})
 	}
 }
@@ -31,6 +31,9 @@ func Write(pal *al.AcrossLite, writer io.Writer) error {
 	sb.WriteString(WriteCopyright(pal) + "\n")
 	sb.WriteString(WriteSize(pal) + "\n")
 	sb.WriteString(WriteGrid(pal) + "\n")
+	if rebus := WriteRebus(pal); rebus != "" {
+		sb.WriteString(rebus + "\n")
+	}
 	sb.WriteString(WriteAcrossClues(pal) + "\n")
 	sb.WriteString(WriteDownClues(pal) + "\n")
 	sb.WriteString(WriteNotepad(pal) + "\n")
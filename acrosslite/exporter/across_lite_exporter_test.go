@@ -7,6 +7,7 @@ import (
 	"time"
 
 	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/philhanna/cwcomp/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +18,7 @@ func getTestStructure() (*al.AcrossLite, error) {
 	pal.SetTitle("My title")
 	pal.SetAuthor("Phil Hanna")
 	pal.SetCopyright("2023")
-	pal.SetSize(n)
+	pal.SetSize(n, n)
 	cellStrings := []string{
 		".NOW.   C",
 		"BLUE.   O",
@@ -64,3 +65,44 @@ func TestExport(t *testing.T) {
 	Write(pal, w)
 	w.Flush()
 }
+
+func TestWriteRebus(t *testing.T) {
+	pal, _ := getTestStructure()
+	assert.Equal(t, "", WriteRebus(pal))
+
+	pal.SetRebus(1, 2, "NEW")
+	pal.SetRebus(2, 1, "BE")
+	pal.SetRebus(9, 1, "WE")
+	pal.SetRebus(4, 1, "BE")
+	want := "<REBUS>\n    1:NEW:N\n    2:BE:B\n    3:WE:W"
+	assert.Equal(t, want, WriteRebus(pal))
+
+	grid := WriteGrid(pal)
+	assert.Contains(t, grid, "    .1OW.   C\n")
+	assert.Contains(t, grid, "    2LUE.   O\n")
+	assert.Contains(t, grid, "    2       .\n")
+}
+
+func TestWriteRebus_Circles(t *testing.T) {
+	pal, _ := getTestStructure()
+	pal.SetCircled(2, 2, true)
+	pal.SetCircled(2, 3, true)
+	assert.Equal(t, "<REBUS>\n    MARK;", WriteRebus(pal))
+	assert.Contains(t, WriteGrid(pal), "    BluE.   O\n")
+}
+
+func TestNewAcrossLite(t *testing.T) {
+	pal, _ := getTestStructure()
+	pal.SetRebus(1, 2, "NEW")
+	pal.SetCircled(2, 2, true)
+	puzzle, err := model.ImportPuzzle(pal)
+	assert.Nil(t, err)
+
+	have := NewAcrossLite(puzzle)
+	assert.Equal(t, pal.Grid[1], have.Grid[1])
+	assert.Equal(t, "NEW", have.GetRebus(1, 2))
+	assert.True(t, have.IsCircled(2, 2))
+	assert.False(t, have.IsCircled(2, 3))
+	assert.Equal(t, "Not green but", have.AcrossClues[8])
+	assert.Equal(t, "Not why but", have.DownClues[20])
+}
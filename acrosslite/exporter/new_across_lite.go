@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"io"
+	"time"
+
+	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/philhanna/cwcomp/model"
+)
+
+// NewAcrossLite creates an AcrossLite structure from a puzzle in the
+// model, including its rebus and circled cells.  AcrossLite has no way
+// to represent shaded or barred cells, so those styles are left out.
+func NewAcrossLite(puzzle *model.Puzzle) *al.AcrossLite {
+	nRows, nCols := puzzle.GetRows(), puzzle.GetCols()
+
+	pal := al.NewAcrossLite()
+	pal.SetName(puzzle.GetPuzzleName())
+	pal.SetTitle(puzzle.GetPuzzleName())
+	pal.SetSize(nRows, nCols)
+	for r := 1; r <= nRows; r++ {
+		for c := 1; c <= nCols; c++ {
+			point := model.NewPoint(r, c)
+			if puzzle.IsBlackCell(point) {
+				pal.SetCell(r, c, model.BLACK_CELL)
+				continue
+			}
+			pal.SetRebus(r, c, puzzle.GetLetter(point))
+			pal.SetCircled(r, c, puzzle.GetCellStyle(point).Circled)
+		}
+	}
+
+	// Clues are keyed by word number
+	acrossClues := make(map[int]string)
+	downClues := make(map[int]string)
+	cells := model.PuzzleToSimpleMatrix(puzzle)
+	for _, nc := range model.GetNumberedCells(cells) {
+		if nc.StartA {
+			acrossClues[nc.Seq] = getClue(puzzle, nc.Seq, model.ACROSS)
+		}
+		if nc.StartD {
+			downClues[nc.Seq] = getClue(puzzle, nc.Seq, model.DOWN)
+		}
+	}
+	pal.SetAcrossClues(acrossClues)
+	pal.SetDownClues(downClues)
+
+	now := time.Now()
+	pal.SetCreatedDate(now)
+	pal.SetModifiedDate(now)
+	return pal
+}
+
+// Export writes a puzzle from the model in AcrossLite text format.
+func Export(puzzle *model.Puzzle, writer io.Writer) error {
+	return Write(NewAcrossLite(puzzle), writer)
+}
+
+// getClue returns the clue for a word in the puzzle, or "" if it has
+// none.
+func getClue(puzzle *model.Puzzle, seq int, dir model.Direction) string {
+	word := puzzle.LookupWordByNumber(seq, dir)
+	if word == nil {
+		return ""
+	}
+	clue, _ := puzzle.GetClue(word)
+	return clue
+}
@@ -6,13 +6,25 @@ import (
 	al "github.com/philhanna/cwcomp/acrosslite"
 )
 
-// WriteGrid writes the <GRID> entry
+// WriteGrid writes the <GRID> entry.  Rebus cells are written with the
+// key assigned to them in the <REBUS> section, and circled cells are
+// written in lower case.
 func WriteGrid(pal *al.AcrossLite) string {
 	const TAG = "<GRID>"
-	n := pal.GetSize()
-	parts := make([]string, n)
+	nRows := pal.GetRows()
+	keys, _ := rebusKeys(pal)
+	parts := make([]string, nRows)
 	for i, line := range pal.GetGrid() {
-		parts[i] = "    " + line
+		row := []byte(line)
+		for j := range row {
+			text := strings.ToUpper(pal.GetRebus(i+1, j+1))
+			if key, ok := keys[text]; ok {
+				row[j] = key
+			} else if pal.IsCircled(i+1, j+1) && row[j] >= 'A' && row[j] <= 'Z' {
+				row[j] += 'a' - 'A'
+			}
+		}
+		parts[i] = "    " + string(row)
 	}
 	section := strings.Join(parts, "\n")
 	result := TAG + "\n" + section
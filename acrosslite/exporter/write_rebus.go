@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	al "github.com/philhanna/cwcomp/acrosslite"
+)
+
+// Characters used as keys for rebus cells in the grid, in the order in
+// which they are assigned.
+const REBUS_KEYS = "1234567890@#$%&*+=?"
+
+// WriteRebus writes the optional <REBUS> section, or an empty string if
+// the puzzle has no rebus or circled cells.  If there are circled cells,
+// the section starts with "MARK;", which means that they are written in
+// lower case in the grid.
+func WriteRebus(pal *al.AcrossLite) string {
+	keys, texts := rebusKeys(pal)
+	if len(texts) == 0 && len(pal.Circles) == 0 {
+		return ""
+	}
+	const TAG = "<REBUS>"
+	parts := make([]string, 0)
+	if len(pal.Circles) > 0 {
+		parts = append(parts, "    MARK;")
+	}
+	for _, text := range texts {
+		parts = append(parts, fmt.Sprintf("    %c:%s:%c", keys[text], text, text[0]))
+	}
+	section := strings.Join(parts, "\n")
+	result := fmt.Sprintf("%s\n%s", TAG, section)
+	return result
+}
+
+// rebusKeys assigns a key character to each distinct rebus text, in
+// order of the first cell in which it appears.  It returns the map of
+// texts to keys and the list of texts in order.  If there are more
+// distinct texts than keys, the extra ones are left out, and those
+// cells are written with their first letter only.
+func rebusKeys(pal *al.AcrossLite) (map[string]byte, []string) {
+	indices := make([]int, 0, len(pal.Rebus))
+	for index := range pal.Rebus {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	keys := make(map[string]byte)
+	texts := make([]string, 0)
+	for _, index := range indices {
+		text := strings.ToUpper(pal.Rebus[index])
+		if _, found := keys[text]; found || len(texts) == len(REBUS_KEYS) {
+			continue
+		}
+		keys[text] = REBUS_KEYS[len(texts)]
+		texts = append(texts, text)
+	}
+	return keys, texts
+}
@@ -6,9 +6,9 @@ import (
 	al "github.com/philhanna/cwcomp/acrosslite"
 )
 
-// WriteSize writes the <SIZE> entry
+// WriteSize writes the <SIZE> entry, which is given as the number of
+// columns by the number of rows (width x height).
 func WriteSize(pal *al.AcrossLite) string {
 	const TAG = `<SIZE>`
-	n := pal.GetSize()
-	return fmt.Sprintf("%s\n    %dx%d", TAG, n, n)
+	return fmt.Sprintf("%s\n    %dx%d", TAG, pal.GetCols(), pal.GetRows())
 }
@@ -35,6 +35,7 @@ var (
 	errReadingSize      = errors.New("unexpected final state READING_SIZE")
 	errNoGrid           = errors.New("never found <GRID>")
 	errReadingGrid      = errors.New("unexpected final state READING_GRID")
+	errReadingRebus     = errors.New("unexpected final state READING_REBUS")
 	errReadingAcross    = errors.New("unexpected final state READING_ACROSS")
 )
 
@@ -51,6 +52,7 @@ var StateMap = map[ParsingState]Handler{
 	READING_SIZE:          HandleReadingSize,
 	LOOKING_FOR_GRID:      HandleLookingForGrid,
 	READING_GRID:          HandleReadingGrid,
+	READING_REBUS:         HandleReadingRebus,
 	READING_ACROSS:        HandleReadingAcross,
 	READING_DOWN:          HandleReadingDown,
 	READING_NOTEPAD:       HandleReadingNotepad,
@@ -69,6 +71,7 @@ var FinalStateMap = map[ParsingState]error{
 	READING_SIZE:          errReadingSize,
 	LOOKING_FOR_GRID:      errNoGrid,
 	READING_GRID:          errReadingGrid,
+	READING_REBUS:         errReadingRebus,
 	READING_ACROSS:        errReadingAcross,
 }
 
@@ -130,17 +133,17 @@ func Parse(reader io.Reader) (*al.AcrossLite, error) {
 
 func patchWordNumbers(pal *al.AcrossLite) {
 
-	// Create an n x n grid that we can renumber
-	n := pal.GetSize()
-	cells := make([][]byte, n)
-	for i := 0; i < n; i++ {
-		cells[i] = make([]byte, n)
+	// Create an nRows x nCols grid that we can renumber
+	nRows, nCols := pal.GetRows(), pal.GetCols()
+	cells := make([][]byte, nRows)
+	for i := 0; i < nRows; i++ {
+		cells[i] = make([]byte, nCols)
 	}
 
 	// Convert the grid strings into this simple byte matrix
 	// so that we can calculate the word numbers.
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
+	for i := 0; i < nRows; i++ {
+		for j := 0; j < nCols; j++ {
 			letter := pal.Grid[i][j]
 			if letter == '.' {
 				letter = cwcomp.BLACK_CELL
@@ -106,7 +106,8 @@ func TestParse(t *testing.T) {
 			assert.Equal(t, tt.wantErr, err)
 			if tt.wantErr == nil {
 				assert.Equal(t, tt.wantTitle, puzzle.Title)
-				assert.Equal(t, tt.wantSize, puzzle.Size)
+				assert.Equal(t, tt.wantSize, puzzle.Rows)
+				assert.Equal(t, tt.wantSize, puzzle.Cols)
 			}
 		})
 	}
@@ -20,32 +20,36 @@ func HandleLookingForGrid(pal *al.AcrossLite, line string) (ParsingState, error)
 
 // HandleReadingGrid stores grid lines in the AcrossLite structure.  It
 // verifies that each line is of the right length, and that the final
-// number of lines agrees with the declared size.
+// number of lines agrees with the declared size.  The grid may be
+// followed by an optional <REBUS> section.
 func HandleReadingGrid(pal *al.AcrossLite, line string) (ParsingState, error) {
-	if line == "<ACROSS>" {
+	if line == "<ACROSS>" || line == "<REBUS>" {
 
 		// Verify that the number of grid lines agrees with the declared
 		// size.
-		if len(pal.Grid) != pal.Size {
+		if len(pal.Grid) != pal.Rows {
 			return UNKNOWN, fmt.Errorf(
 				"found %d lines in <GRID> section, expected %d",
-				len(pal.Grid), pal.Size)
+				len(pal.Grid), pal.Rows)
+		}
+		if line == "<REBUS>" {
+			return READING_REBUS, nil
 		}
 		return READING_ACROSS, nil
 	}
 
 	// Because I allow partially completed puzzles to be imported, it is
 	// necessary to pad the line with spaces if it is not as long as the
-	// declared size.
-	for len(line) < pal.Size {
+	// declared width.
+	for len(line) < pal.Cols {
 		line += " "
 	}
 
 	// But if the line is too long, that will be a fatal error
-	if len(line) != pal.Size {
+	if len(line) != pal.Cols {
 		return UNKNOWN, fmt.Errorf(
 			"found %d characters in grid line, expected %d",
-			len(line), pal.Size)
+			len(line), pal.Cols)
 	}
 
 	// Append the grid line to the AcrossLite grid list
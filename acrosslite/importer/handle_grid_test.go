@@ -15,7 +15,8 @@ func TestHandleReadingGrid(t *testing.T) {
 		"CCCCC",
 		"DDDDD",
 	}
-	pal.Size = 5
+	pal.Rows = 5
+	pal.Cols = 5
 	_, err := HandleReadingGrid(pal, "<ACROSS>")
 	assert.NotNil(t, err)
 
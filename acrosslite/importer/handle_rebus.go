@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	al "github.com/philhanna/cwcomp/acrosslite"
+)
+
+// HandleReadingRebus parses the optional <REBUS> section, which follows
+// the grid.  Each line has the form
+//
+//	key:FULLTEXT:L
+//
+// where key is the character used in the grid for the rebus cells,
+// FULLTEXT is the full contents of those cells, and L is the single
+// letter that can be entered instead.  Each cell in the grid containing
+// the key is replaced by the single letter, and its full text is
+// recorded in the Rebus map.
+//
+// The line "MARK;" means that lower case letters in the grid are
+// circled cells.  These are converted to upper case and recorded in the
+// Circles map.
+func HandleReadingRebus(pal *al.AcrossLite, line string) (ParsingState, error) {
+	if line == "<ACROSS>" {
+		return READING_ACROSS, nil
+	}
+	if line == "" {
+		return READING_REBUS, nil
+	}
+	if strings.EqualFold(line, "MARK;") {
+		markCircles(pal)
+		return READING_REBUS, nil
+	}
+
+	parts := strings.Split(line, ":")
+	if len(parts) != 3 || len(parts[0]) != 1 || parts[1] == "" {
+		return UNKNOWN, fmt.Errorf("invalid line %q in <REBUS> section", line)
+	}
+	key := parts[0][0]
+	text := strings.ToUpper(parts[1])
+	letter := text[0]
+	if len(parts[2]) > 0 {
+		letter = strings.ToUpper(parts[2])[0]
+	}
+
+	for r := 1; r <= pal.GetRows(); r++ {
+		for c := 1; c <= pal.GetCols(); c++ {
+			if pal.Grid[r-1][c-1] != key {
+				continue
+			}
+			if err := pal.SetRebus(r, c, text); err != nil {
+				return UNKNOWN, err
+			}
+			if err := pal.SetCell(r, c, letter); err != nil {
+				return UNKNOWN, err
+			}
+		}
+	}
+	return READING_REBUS, nil
+}
+
+// markCircles converts the lower case letters in the grid to upper case
+// and marks those cells as circled.
+func markCircles(pal *al.AcrossLite) {
+	for r := 1; r <= pal.GetRows(); r++ {
+		for c := 1; c <= pal.GetCols(); c++ {
+			letter := pal.Grid[r-1][c-1]
+			if letter >= 'a' && letter <= 'z' {
+				pal.SetCircled(r, c, true)
+				pal.SetCell(r, c, letter-'a'+'A')
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"testing"
+
+	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleReadingRebus(t *testing.T) {
+	pal := new(al.AcrossLite)
+	pal.SetSize(3, 3)
+	pal.Grid = []string{
+		"1AT",
+		"O.2",
+		"WOE",
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    ParsingState
+		wantErr bool
+	}{
+		{"mark", "MARK;", READING_REBUS, false},
+		{"first key", "1:HEART:H", READING_REBUS, false},
+		{"default letter", "2:one:", READING_REBUS, false},
+		{"too few parts", "3:XYZ", UNKNOWN, true},
+		{"long key", "33:XYZ:X", UNKNOWN, true},
+		{"next section", "<ACROSS>", READING_ACROSS, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := HandleReadingRebus(pal, tt.line)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, state)
+		})
+	}
+
+	assert.Equal(t, []string{"HAT", "O.O", "WOE"}, pal.Grid)
+	assert.Equal(t, "HEART", pal.GetRebus(1, 1))
+	assert.Equal(t, "ONE", pal.GetRebus(2, 3))
+	assert.Equal(t, "", pal.GetRebus(1, 2))
+}
+
+func TestHandleReadingRebus_Mark(t *testing.T) {
+	pal := new(al.AcrossLite)
+	pal.SetSize(2, 3)
+	pal.Grid = []string{
+		"cAt",
+		".OX",
+	}
+	state, err := HandleReadingRebus(pal, "MARK;")
+	assert.Nil(t, err)
+	assert.Equal(t, READING_REBUS, state)
+	assert.Equal(t, []string{"CAT", ".OX"}, pal.Grid)
+	assert.True(t, pal.IsCircled(1, 1))
+	assert.False(t, pal.IsCircled(1, 2))
+	assert.True(t, pal.IsCircled(1, 3))
+}
@@ -20,18 +20,17 @@ func HandleLookingForSize(pal *al.AcrossLite, line string) (ParsingState, error)
 }
 
 // HandleReadingSize examines the current line, and verifies that it has
-// the form <digits>x<digits>.  Note that the only grids I handle are
-// square ones.
+// the form <digits>x<digits>, which is the number of columns by the
+// number of rows (width x height).  Grids need not be square.
 func HandleReadingSize(pal *al.AcrossLite, line string) (ParsingState, error) {
 	reSize := regexp.MustCompile(`(\d+)x(\d+)`)
 	tokens := reSize.FindStringSubmatch(line)
 	switch {
 	case tokens == nil:
 		return UNKNOWN, fmt.Errorf("no <digits>x<digits> size expression found")
-	case tokens[1] != tokens[2]:
-		return UNKNOWN, fmt.Errorf("only square grids allowed, not %sx%s", tokens[1], tokens[2])
 	default:
-		pal.Size, _ = strconv.Atoi(tokens[1])
+		pal.Cols, _ = strconv.Atoi(tokens[1])
+		pal.Rows, _ = strconv.Atoi(tokens[2])
 		return LOOKING_FOR_GRID, nil
 	}
 
@@ -9,10 +9,12 @@ import (
 
 func TestHandleReadingSize(t *testing.T) {
 	tests := []struct {
-		name    string
-		line    string
-		want    ParsingState
-		wantErr bool
+		name     string
+		line     string
+		want     ParsingState
+		wantErr  bool
+		wantRows int
+		wantCols int
 	}{
 		{
 			name: "Good",
@@ -25,9 +27,11 @@ func TestHandleReadingSize(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "Unsquare grid",
-			line:    "15x16",
-			wantErr: true,
+			name:     "Unsquare grid",
+			line:     "21x15",
+			want:     LOOKING_FOR_GRID,
+			wantRows: 15,
+			wantCols: 21,
 		},
 	}
 	for _, tt := range tests {
@@ -39,6 +43,10 @@ func TestHandleReadingSize(t *testing.T) {
 				return
 			}
 			assert.Equal(t, tt.want, have)
+			if tt.wantRows != 0 {
+				assert.Equal(t, tt.wantRows, pal.Rows)
+				assert.Equal(t, tt.wantCols, pal.Cols)
+			}
 		})
 	}
 }
@@ -22,6 +22,7 @@ const (
 	READING_SIZE
 	LOOKING_FOR_GRID
 	READING_GRID
+	READING_REBUS
 	READING_ACROSS
 	READING_DOWN
 	READING_NOTEPAD
@@ -0,0 +1,138 @@
+// Puz reads and writes puzzles in the binary Across Lite .puz format.
+//
+// The format is not officially documented, but it is described in
+// detail at https://code.google.com/archive/p/puz/wikis/FileFormat.wiki.
+// A .puz file consists of a fixed-size header, the solution and the
+// player's grid, a list of null-terminated strings (title, author,
+// copyright, clues, and notes), and optional extra sections such as
+// GRBS/RTBL (rebus) and GEXT (circles and other cell markings).
+package puz
+
+import (
+	"errors"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// puzStrings are the variable-length strings that follow the grids.
+type puzStrings struct {
+	title     string
+	author    string
+	copyright string
+	clues     []string
+	notes     string
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// Offsets of the fields in the header
+const (
+	OFFSET_CHECKSUM           = 0x00
+	OFFSET_MAGIC              = 0x02
+	OFFSET_CIB_CHECKSUM       = 0x0E
+	OFFSET_MASKED_LOW         = 0x10
+	OFFSET_MASKED_HIGH        = 0x14
+	OFFSET_VERSION            = 0x18
+	OFFSET_SCRAMBLED_CHECKSUM = 0x1E
+	OFFSET_WIDTH              = 0x2C
+	OFFSET_HEIGHT             = 0x2D
+	OFFSET_NCLUES             = 0x2E
+	OFFSET_PUZZLE_TYPE        = 0x30
+	OFFSET_SCRAMBLED_TAG      = 0x32
+	HEADER_SIZE               = 0x34
+)
+
+const (
+	MAGIC   = "ACROSS&DOWN\x00"
+	VERSION = "1.3\x00"
+
+	PUZZLE_TYPE_NORMAL = 0x0001
+	SCRAMBLED          = 0x0004
+
+	BLACK_SQUARE = '.'
+	EMPTY_SQUARE = '-'
+
+	GEXT_CIRCLED = 0x80
+)
+
+var (
+	ErrNotPuz      = errors.New("not an Across Lite .puz file")
+	ErrTruncated   = errors.New("unexpected end of .puz data")
+	ErrChecksum    = errors.New(".puz checksum does not match")
+	ErrScrambled   = errors.New(".puz solution is scrambled; a key is required")
+	ErrWrongKey    = errors.New("wrong key for scrambled .puz solution")
+	ErrIncomplete  = errors.New("only a complete solution can be scrambled")
+	ErrTooLarge    = errors.New("grid is too large for the .puz format")
+	ErrInvalidKey  = errors.New("scramble key must be a four digit number")
+	ErrBadRebusKey = errors.New("invalid entry in RTBL rebus table")
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Checksum computes the .puz checksum of a region of data, starting
+// from an initial checksum value. This is a 16-bit rotate-and-add.
+func Checksum(data []byte, cksum uint16) uint16 {
+	for _, b := range data {
+		if cksum&1 != 0 {
+			cksum = (cksum >> 1) | 0x8000
+		} else {
+			cksum = cksum >> 1
+		}
+		cksum += uint16(b)
+	}
+	return cksum
+}
+
+// isLetter returns true if the byte is an uppercase letter A-Z
+func isLetter(b byte) bool {
+	return strings.IndexByte("ABCDEFGHIJKLMNOPQRSTUVWXYZ", b) >= 0
+}
+
+// stringsChecksum adds the title, author, copyright, clues, and notes
+// to a checksum, in the way the .puz format specifies: the null
+// terminator is included for all but the clues, and empty strings are
+// skipped.
+func stringsChecksum(strs *puzStrings, cksum uint16) uint16 {
+	for _, s := range []string{strs.title, strs.author, strs.copyright} {
+		if s != "" {
+			cksum = Checksum(append(toLatin1(s), 0), cksum)
+		}
+	}
+	for _, clue := range strs.clues {
+		cksum = Checksum(toLatin1(clue), cksum)
+	}
+	if strs.notes != "" {
+		cksum = Checksum(append(toLatin1(strs.notes), 0), cksum)
+	}
+	return cksum
+}
+
+// fromLatin1 converts ISO-8859-1 bytes, the encoding used by .puz
+// files, to a Go string.
+func fromLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// toLatin1 converts a Go string to ISO-8859-1 bytes.  Characters that
+// cannot be represented are replaced with '?'.
+func toLatin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
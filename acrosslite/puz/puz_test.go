@@ -0,0 +1,262 @@
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestPuzzle() *al.AcrossLite {
+	pal := al.NewAcrossLite()
+	pal.SetSize(3, 4)
+	pal.Grid = []string{
+		"CAT.",
+		"ARES",
+		".BEE",
+	}
+	pal.SetTitle("Small")
+	pal.SetAuthor("Café Author")
+	pal.SetCopyright("Not copyrighted")
+	pal.AcrossClues = map[int]string{
+		1: "Feline",
+		4: "God of war",
+		6: "Buzzer",
+	}
+	pal.DownClues = map[int]string{
+		1: "Taxi",
+		2: "Letters before C",
+		3: "Beverage",
+		5: "Snake",
+	}
+	return pal
+}
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		start uint16
+		want  uint16
+	}{
+		{"empty", []byte{}, 0, 0},
+		{"one byte", []byte{'A'}, 0, 0x41},
+		{"odd rotates", []byte{'A', 'B'}, 0, 0x8020 + 0x42},
+		{"initial value", []byte{1}, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Checksum(tt.data, tt.start))
+		})
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	// The files in testdata were made from createTestPuzzle by a separate
+	// implementation of the format, so they check the checksums and the
+	// scrambling against something other than this package's own output.
+	tests := []struct {
+		file      string
+		key       int
+		checksum  uint16
+		cib       uint16
+		masked    []byte
+		scrambled uint16
+		solution  string
+	}{
+		{"small.puz", 0, 0x1D6D, 0x6C00,
+			[]byte{0x49, 0x64, 0xF2, 0x80, 0x2D, 0x07, 0x5E, 0x8B}, 0x0000, "CAT.ARES.BEE"},
+		{"small-scrambled.puz", 1234, 0x7D4D, 0x6C02,
+			[]byte{0x4B, 0x10, 0xF2, 0x80, 0x2D, 0x1B, 0x5E, 0x8B}, 0xDD11, "DGQ.IYOC.NPI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			want, err := os.ReadFile("testdata/" + tt.file)
+			assert.Nil(t, err)
+
+			// The file has the expected checksums and solution
+			assert.Equal(t, tt.checksum, binary.LittleEndian.Uint16(want[OFFSET_CHECKSUM:]))
+			assert.Equal(t, tt.cib, binary.LittleEndian.Uint16(want[OFFSET_CIB_CHECKSUM:]))
+			assert.Equal(t, tt.masked, want[OFFSET_MASKED_LOW:OFFSET_VERSION])
+			assert.Equal(t, tt.scrambled, binary.LittleEndian.Uint16(want[OFFSET_SCRAMBLED_CHECKSUM:]))
+			assert.Equal(t, tt.solution, string(want[HEADER_SIZE:HEADER_SIZE+12]))
+
+			// Writing the puzzle gives exactly the same bytes
+			buf := new(bytes.Buffer)
+			assert.Nil(t, WriteScrambled(createTestPuzzle(), buf, tt.key))
+			assert.Equal(t, want, buf.Bytes())
+
+			// And reading the file gives the puzzle
+			got, err := ReadScrambled(bytes.NewReader(want), tt.key)
+			assert.Nil(t, err)
+			pal := createTestPuzzle()
+			assert.Equal(t, pal.Grid, got.Grid)
+			assert.Equal(t, pal.Author, got.Author)
+			assert.Equal(t, pal.AcrossClues, got.AcrossClues)
+			assert.Equal(t, pal.DownClues, got.DownClues)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	pal := createTestPuzzle()
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Write(pal, buf))
+
+	got, err := Read(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, got.GetRows())
+	assert.Equal(t, 4, got.GetCols())
+	assert.Equal(t, pal.Grid, got.Grid)
+	assert.Equal(t, pal.Title, got.Title)
+	assert.Equal(t, pal.Author, got.Author)
+	assert.Equal(t, pal.Copyright, got.Copyright)
+	assert.Equal(t, pal.AcrossClues, got.AcrossClues)
+	assert.Equal(t, pal.DownClues, got.DownClues)
+}
+
+func TestRoundTrip_Incomplete(t *testing.T) {
+	pal := createTestPuzzle()
+	pal.Grid[1] = "A  S"
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Write(pal, buf))
+
+	got, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "A  S", got.Grid[1])
+
+	// Only a complete solution can be scrambled
+	assert.Equal(t, ErrIncomplete, WriteScrambled(pal, new(bytes.Buffer), 1234))
+}
+
+func TestRoundTrip_Extras(t *testing.T) {
+	pal := createTestPuzzle()
+	assert.Nil(t, pal.SetRebus(2, 2, "RED"))
+	assert.Nil(t, pal.SetRebus(3, 3, "EAST"))
+	assert.Nil(t, pal.SetRebus(3, 4, "RED"))
+	pal.SetCircled(1, 1, true)
+	pal.SetCircled(2, 4, true)
+	pal.SetNotes("Some notes")
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Write(pal, buf))
+
+	got, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "RED", got.GetRebus(2, 2))
+	assert.Equal(t, "EAST", got.GetRebus(3, 3))
+	assert.Equal(t, "RED", got.GetRebus(3, 4))
+	assert.Equal(t, "", got.GetRebus(1, 1))
+	assert.True(t, got.IsCircled(1, 1))
+	assert.True(t, got.IsCircled(2, 4))
+	assert.False(t, got.IsCircled(2, 2))
+	assert.Equal(t, "Some notes", got.GetNotes())
+}
+
+func TestExport(t *testing.T) {
+	puzzle, err := model.ImportPuzzle(createTestPuzzle())
+	assert.Nil(t, err)
+	assert.Nil(t, puzzle.SetCircled(model.NewPoint(1, 2), true))
+	assert.Nil(t, puzzle.SetShade(model.NewPoint(1, 3), "gray"))
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Export(puzzle, buf))
+	got, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, createTestPuzzle().Grid, got.Grid)
+	assert.Equal(t, "Feline", got.AcrossClues[1])
+	assert.Equal(t, "Snake", got.DownClues[5])
+	assert.True(t, got.IsCircled(1, 2))
+	assert.False(t, got.IsCircled(1, 3))
+
+	// Circles survive the import back into the model
+	other, err := model.ImportPuzzle(got)
+	assert.Nil(t, err)
+	assert.True(t, other.GetCellStyle(model.NewPoint(1, 2)).Circled)
+}
+
+func TestRead_LeadingJunk(t *testing.T) {
+	buf := new(bytes.Buffer)
+	buf.WriteString("junk")
+	assert.Nil(t, Write(createTestPuzzle(), buf))
+
+	got, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Small", got.GetTitle())
+}
+
+func TestRead_Errors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Write(createTestPuzzle(), buf))
+	good := buf.Bytes()
+
+	corrupt := func(offset int) []byte {
+		blob := make([]byte, len(good))
+		copy(blob, good)
+		blob[offset] ^= 0x01
+		return blob
+	}
+
+	tests := []struct {
+		name string
+		blob []byte
+		want error
+	}{
+		{"not puz", []byte("hello, world"), ErrNotPuz},
+		{"truncated header", good[:HEADER_SIZE-1], ErrTruncated},
+		{"truncated grid", good[:HEADER_SIZE+5], ErrTruncated},
+		{"bad solution", corrupt(HEADER_SIZE), ErrChecksum},
+		{"bad header", corrupt(OFFSET_PUZZLE_TYPE), ErrChecksum},
+		{"bad string", corrupt(len(good) - 3), ErrChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.blob))
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestScramble(t *testing.T) {
+	pal := createTestPuzzle()
+	buf := new(bytes.Buffer)
+	assert.Nil(t, WriteScrambled(pal, buf, 1234))
+	blob := buf.Bytes()
+
+	// The solution in the file is not the plain one
+	assert.NotEqual(t, []byte("CAT.ARES.BEE"), blob[HEADER_SIZE:HEADER_SIZE+12])
+	assert.Equal(t, byte('.'), blob[HEADER_SIZE+3])
+
+	_, err := Read(bytes.NewReader(blob))
+	assert.Equal(t, ErrScrambled, err)
+
+	_, err = ReadScrambled(bytes.NewReader(blob), 4321)
+	assert.Equal(t, ErrWrongKey, err)
+
+	got, err := ReadScrambled(bytes.NewReader(blob), 1234)
+	assert.Nil(t, err)
+	assert.Equal(t, pal.Grid, got.Grid)
+}
+
+func TestScramble_InverseOfUnscramble(t *testing.T) {
+	solution := []byte("ABCDE.FGHIJKLMNOPQRSTUVWXYZ")
+	for _, key := range []int{1000, 1234, 9999, 3141} {
+		scrambled, err := Scramble(solution, 9, 3, key)
+		assert.Nil(t, err)
+		got, err := Unscramble(scrambled, 9, 3, key)
+		assert.Nil(t, err)
+		assert.Equal(t, solution, got)
+	}
+	_, err := Scramble(solution, 9, 3, 123)
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+func TestWrite_TooLarge(t *testing.T) {
+	pal := al.NewAcrossLite()
+	pal.SetSize(1, 256)
+	assert.Equal(t, ErrTooLarge, Write(pal, new(bytes.Buffer)))
+}
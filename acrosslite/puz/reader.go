@@ -0,0 +1,251 @@
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/philhanna/cwcomp"
+	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Read parses a .puz file and creates an AcrossLite structure from it.
+// If the solution is scrambled, ErrScrambled is returned; use
+// ReadScrambled instead.
+func Read(reader io.Reader) (*al.AcrossLite, error) {
+	return ReadScrambled(reader, 0)
+}
+
+// ReadScrambled parses a .puz file whose solution may be scrambled,
+// using the specified four digit key to unscramble it.  If the solution
+// is not scrambled, the key is ignored.
+func ReadScrambled(reader io.Reader, key int) (*al.AcrossLite, error) {
+	blob, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some files have junk before the header, so look for the magic
+	// string rather than assuming it is at the start.
+	start := bytes.Index(blob, []byte(MAGIC)) - OFFSET_MAGIC
+	if start < 0 {
+		return nil, ErrNotPuz
+	}
+	blob = blob[start:]
+	if len(blob) < HEADER_SIZE {
+		return nil, ErrTruncated
+	}
+	header := blob[:HEADER_SIZE]
+
+	width := int(header[OFFSET_WIDTH])
+	height := int(header[OFFSET_HEIGHT])
+	nClues := int(binary.LittleEndian.Uint16(header[OFFSET_NCLUES:]))
+	scrambled := binary.LittleEndian.Uint16(header[OFFSET_SCRAMBLED_TAG:])&SCRAMBLED != 0
+
+	// Read the solution and the player's grid
+	size := width * height
+	offset := HEADER_SIZE
+	if len(blob) < offset+2*size {
+		return nil, ErrTruncated
+	}
+	solution := blob[offset : offset+size]
+	offset += size
+	grid := blob[offset : offset+size]
+	offset += size
+
+	// Read the strings
+	strs := new(puzStrings)
+	nextString := func() (string, bool) {
+		end := bytes.IndexByte(blob[offset:], 0)
+		if end < 0 {
+			return "", false
+		}
+		s := fromLatin1(blob[offset : offset+end])
+		offset += end + 1
+		return s, true
+	}
+	var ok bool
+	if strs.title, ok = nextString(); !ok {
+		return nil, ErrTruncated
+	}
+	if strs.author, ok = nextString(); !ok {
+		return nil, ErrTruncated
+	}
+	if strs.copyright, ok = nextString(); !ok {
+		return nil, ErrTruncated
+	}
+	strs.clues = make([]string, nClues)
+	for i := 0; i < nClues; i++ {
+		if strs.clues[i], ok = nextString(); !ok {
+			return nil, ErrTruncated
+		}
+	}
+	strs.notes, _ = nextString() // Notes are optional
+
+	// Read the extra sections
+	sections, err := readSections(blob[offset:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the checksums
+	if err := verifyChecksums(header, solution, grid, strs); err != nil {
+		return nil, err
+	}
+
+	// Unscramble the solution if necessary
+	if scrambled {
+		if key == 0 {
+			return nil, ErrScrambled
+		}
+		solution, err = Unscramble(solution, width, height, key)
+		if err != nil {
+			return nil, err
+		}
+		want := binary.LittleEndian.Uint16(header[OFFSET_SCRAMBLED_CHECKSUM:])
+		if ScrambledChecksum(solution, width, height) != want {
+			return nil, ErrWrongKey
+		}
+	}
+
+	return newAcrossLite(width, height, solution, strs, sections)
+}
+
+// newAcrossLite creates the AcrossLite structure from the parts of the
+// .puz file.
+func newAcrossLite(width, height int, solution []byte, strs *puzStrings, sections map[string][]byte) (*al.AcrossLite, error) {
+	pal := al.NewAcrossLite()
+	pal.SetSize(height, width)
+	pal.SetTitle(strs.title)
+	pal.SetAuthor(strs.author)
+	pal.SetCopyright(strs.copyright)
+	pal.SetNotes(strs.notes)
+
+	// Copy the solution into the grid
+	cells := make([][]byte, height)
+	for i := 0; i < height; i++ {
+		row := make([]byte, width)
+		cells[i] = make([]byte, width)
+		for j := 0; j < width; j++ {
+			b := solution[i*width+j]
+			switch b {
+			case BLACK_SQUARE:
+				cells[i][j] = cwcomp.BLACK_CELL
+			case EMPTY_SQUARE:
+				b = ' '
+				cells[i][j] = b
+			default:
+				cells[i][j] = b
+			}
+			row[j] = b
+		}
+		pal.Grid[i] = string(row)
+	}
+
+	// The clues are in order of word number, with the across clue
+	// before the down clue for the same number.
+	i := 0
+	for _, nc := range model.GetNumberedCells(cells) {
+		if nc.StartA && i < len(strs.clues) {
+			pal.AcrossClues[nc.Seq] = strs.clues[i]
+			i++
+		}
+		if nc.StartD && i < len(strs.clues) {
+			pal.DownClues[nc.Seq] = strs.clues[i]
+			i++
+		}
+	}
+
+	// Rebus cells
+	if grbs, ok := sections["GRBS"]; ok && len(grbs) == width*height {
+		table, err := parseRebusTable(sections["RTBL"])
+		if err != nil {
+			return nil, err
+		}
+		for index, value := range grbs {
+			if value == 0 {
+				continue
+			}
+			if text, ok := table[int(value)-1]; ok {
+				pal.Rebus[index] = text
+			}
+		}
+	}
+
+	// Circled cells
+	if gext, ok := sections["GEXT"]; ok && len(gext) == width*height {
+		for index, value := range gext {
+			if value&GEXT_CIRCLED != 0 {
+				pal.Circles[index] = true
+			}
+		}
+	}
+
+	return pal, nil
+}
+
+// parseRebusTable parses the RTBL section, which is a list of entries
+// like " 0:HEART; 1:LOVE;" mapping rebus keys to their full text.
+func parseRebusTable(data []byte) (map[int]string, error) {
+	table := make(map[int]string)
+	for _, entry := range strings.Split(fromLatin1(data), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		keyText, text, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, ErrBadRebusKey
+		}
+		key, err := strconv.Atoi(strings.TrimSpace(keyText))
+		if err != nil {
+			return nil, ErrBadRebusKey
+		}
+		table[key] = text
+	}
+	return table, nil
+}
+
+// readSections reads the extra sections following the strings. Each one
+// has a four character name, a two byte length, a two byte checksum,
+// the data, and a null terminator.
+func readSections(blob []byte) (map[string][]byte, error) {
+	sections := make(map[string][]byte)
+	for len(blob) >= 8 {
+		name := string(blob[0:4])
+		length := int(binary.LittleEndian.Uint16(blob[4:6]))
+		cksum := binary.LittleEndian.Uint16(blob[6:8])
+		if len(blob) < 8+length {
+			return nil, ErrTruncated
+		}
+		data := blob[8 : 8+length]
+		if Checksum(data, 0) != cksum {
+			return nil, ErrChecksum
+		}
+		sections[name] = data
+		blob = blob[8+length:]
+		if len(blob) > 0 && blob[0] == 0 {
+			blob = blob[1:]
+		}
+	}
+	return sections, nil
+}
+
+// verifyChecksums checks the CIB checksum, the overall file checksum,
+// and the masked checksums in the header.
+func verifyChecksums(header, solution, grid []byte, strs *puzStrings) error {
+	want := computeChecksums(header, solution, grid, strs)
+	if !bytes.Equal(header[OFFSET_CHECKSUM:OFFSET_MAGIC], want[OFFSET_CHECKSUM:OFFSET_MAGIC]) {
+		return ErrChecksum
+	}
+	if !bytes.Equal(header[OFFSET_CIB_CHECKSUM:OFFSET_VERSION], want[OFFSET_CIB_CHECKSUM:OFFSET_VERSION]) {
+		return ErrChecksum
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package puz
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Scramble applies the Across Lite scrambling algorithm to a solution
+// grid, given as the rows of the grid concatenated together, with '.'
+// for black squares.  Only the letters are scrambled; black squares are
+// left where they are.  The key is a four digit number.
+func Scramble(solution []byte, width, height int, key int) ([]byte, error) {
+	digits, err := keyDigits(key)
+	if err != nil {
+		return nil, err
+	}
+	letters := columnLetters(solution, width, height)
+	for _, b := range letters {
+		if !isLetter(b) {
+			return nil, ErrIncomplete
+		}
+	}
+	for _, k := range digits {
+		letters = shift(letters, digits, 1)
+		letters = rotate(letters, k)
+		letters = shuffle(letters)
+	}
+	return restoreColumnLetters(solution, width, height, letters), nil
+}
+
+// Unscramble reverses Scramble.
+func Unscramble(solution []byte, width, height int, key int) ([]byte, error) {
+	digits, err := keyDigits(key)
+	if err != nil {
+		return nil, err
+	}
+	letters := columnLetters(solution, width, height)
+	n := len(letters)
+	if n == 0 {
+		return solution, nil
+	}
+	for i := len(digits) - 1; i >= 0; i-- {
+		k := digits[i]
+		letters = unshuffle(letters)
+		letters = rotate(letters, n-k%n)
+		letters = shift(letters, digits, -1)
+	}
+	return restoreColumnLetters(solution, width, height, letters), nil
+}
+
+// ScrambledChecksum returns the checksum of the letters of an
+// unscrambled solution, taken in column-major order.  This is stored in
+// the header of a scrambled puzzle so that a key can be verified.
+func ScrambledChecksum(solution []byte, width, height int) uint16 {
+	return Checksum(columnLetters(solution, width, height), 0)
+}
+
+// columnLetters returns the letters of the solution in column-major
+// order, omitting the black squares.
+func columnLetters(solution []byte, width, height int) []byte {
+	letters := make([]byte, 0, len(solution))
+	for c := 0; c < width; c++ {
+		for r := 0; r < height; r++ {
+			b := solution[r*width+c]
+			if b != BLACK_SQUARE {
+				letters = append(letters, b)
+			}
+		}
+	}
+	return letters
+}
+
+// keyDigits splits a four digit key into its digits.
+func keyDigits(key int) ([]int, error) {
+	if key < 1000 || key > 9999 {
+		return nil, ErrInvalidKey
+	}
+	return []int{key / 1000, key / 100 % 10, key / 10 % 10, key % 10}, nil
+}
+
+// restoreColumnLetters is the inverse of columnLetters: it returns a
+// copy of the solution with its letters replaced, in column-major order,
+// by the specified letters.
+func restoreColumnLetters(solution []byte, width, height int, letters []byte) []byte {
+	result := make([]byte, len(solution))
+	copy(result, solution)
+	i := 0
+	for c := 0; c < width; c++ {
+		for r := 0; r < height; r++ {
+			if result[r*width+c] != BLACK_SQUARE {
+				result[r*width+c] = letters[i]
+				i++
+			}
+		}
+	}
+	return result
+}
+
+// rotate returns a copy of the letters cut at position k, so that the
+// letters from k on come first.
+func rotate(letters []byte, k int) []byte {
+	if len(letters) > 0 {
+		k %= len(letters)
+	}
+	result := make([]byte, 0, len(letters))
+	result = append(result, letters[k:]...)
+	result = append(result, letters[:k]...)
+	return result
+}
+
+// shift adds (or, if sign is -1, subtracts) the key digits, in
+// rotation, to the letters, wrapping around from Z to A.
+func shift(letters []byte, digits []int, sign int) []byte {
+	result := make([]byte, len(letters))
+	for i, b := range letters {
+		offset := int(b-'A') + sign*digits[i%len(digits)]
+		result[i] = byte('A' + (offset+26)%26)
+	}
+	return result
+}
+
+// shuffle interleaves the second half of the letters with the first
+// half, like a riffle shuffle of a deck of cards.
+func shuffle(letters []byte) []byte {
+	mid := len(letters) / 2
+	result := make([]byte, 0, len(letters))
+	for i := 0; i < mid; i++ {
+		result = append(result, letters[mid+i], letters[i])
+	}
+	if len(letters)%2 != 0 {
+		result = append(result, letters[len(letters)-1])
+	}
+	return result
+}
+
+// unshuffle reverses shuffle.
+func unshuffle(letters []byte) []byte {
+	result := make([]byte, 0, len(letters))
+	for i := 1; i < len(letters); i += 2 {
+		result = append(result, letters[i])
+	}
+	for i := 0; i < len(letters); i += 2 {
+		result = append(result, letters[i])
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package puz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/philhanna/cwcomp"
+	al "github.com/philhanna/cwcomp/acrosslite"
+	"github.com/philhanna/cwcomp/acrosslite/exporter"
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Write writes the puzzle in the AcrossLite structure as a .puz file.
+func Write(pal *al.AcrossLite, writer io.Writer) error {
+	return WriteScrambled(pal, writer, 0)
+}
+
+// WriteScrambled writes the puzzle as a .puz file with its solution
+// scrambled using the specified four digit key.  A key of zero means the
+// solution is not scrambled.  Only a complete solution can be scrambled.
+func WriteScrambled(pal *al.AcrossLite, writer io.Writer, key int) error {
+	blob, err := encode(pal, key)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(blob)
+	return err
+}
+
+// encode creates the bytes of a .puz file from an AcrossLite structure.
+func encode(pal *al.AcrossLite, key int) ([]byte, error) {
+	width, height := pal.GetCols(), pal.GetRows()
+	if width > 0xFF || height > 0xFF {
+		return nil, ErrTooLarge
+	}
+
+	// Create the solution and the player's grid
+	cells := make([][]byte, height)
+	solution := make([]byte, 0, width*height)
+	grid := make([]byte, 0, width*height)
+	for i := 0; i < height; i++ {
+		cells[i] = make([]byte, width)
+		for j := 0; j < width; j++ {
+			b := byte(' ')
+			if j < len(pal.Grid[i]) {
+				b = strings.ToUpper(pal.Grid[i][j : j+1])[0]
+			}
+			switch {
+			case b == BLACK_SQUARE:
+				cells[i][j] = cwcomp.BLACK_CELL
+				solution = append(solution, BLACK_SQUARE)
+				grid = append(grid, BLACK_SQUARE)
+			case isLetter(b):
+				cells[i][j] = b
+				solution = append(solution, b)
+				grid = append(grid, EMPTY_SQUARE)
+			default:
+				cells[i][j] = ' '
+				solution = append(solution, EMPTY_SQUARE)
+				grid = append(grid, EMPTY_SQUARE)
+			}
+		}
+	}
+
+	// Clues are listed in order of word number, across before down
+	strs := new(puzStrings)
+	strs.title = pal.GetTitle()
+	strs.author = pal.GetAuthor()
+	strs.copyright = pal.GetCopyright()
+	strs.notes = pal.GetNotes()
+	strs.clues = make([]string, 0)
+	for _, nc := range model.GetNumberedCells(cells) {
+		if nc.StartA {
+			strs.clues = append(strs.clues, pal.AcrossClues[nc.Seq])
+		}
+		if nc.StartD {
+			strs.clues = append(strs.clues, pal.DownClues[nc.Seq])
+		}
+	}
+
+	// Scramble the solution if requested
+	var scrambledChecksum uint16
+	if key != 0 {
+		scrambled, err := Scramble(solution, width, height, key)
+		if err != nil {
+			return nil, err
+		}
+		scrambledChecksum = ScrambledChecksum(solution, width, height)
+		solution = scrambled
+	}
+
+	// Create the header
+	header := make([]byte, HEADER_SIZE)
+	copy(header[OFFSET_MAGIC:], MAGIC)
+	copy(header[OFFSET_VERSION:], VERSION)
+	header[OFFSET_WIDTH] = byte(width)
+	header[OFFSET_HEIGHT] = byte(height)
+	binary.LittleEndian.PutUint16(header[OFFSET_NCLUES:], uint16(len(strs.clues)))
+	binary.LittleEndian.PutUint16(header[OFFSET_PUZZLE_TYPE:], PUZZLE_TYPE_NORMAL)
+	if key != 0 {
+		binary.LittleEndian.PutUint16(header[OFFSET_SCRAMBLED_CHECKSUM:], scrambledChecksum)
+		binary.LittleEndian.PutUint16(header[OFFSET_SCRAMBLED_TAG:], SCRAMBLED)
+	}
+	copy(header, computeChecksums(header, solution, grid, strs))
+
+	// Write everything out
+	buf := new(bytes.Buffer)
+	buf.Write(header)
+	buf.Write(solution)
+	buf.Write(grid)
+	for _, s := range []string{strs.title, strs.author, strs.copyright} {
+		buf.Write(toLatin1(s))
+		buf.WriteByte(0)
+	}
+	for _, clue := range strs.clues {
+		buf.Write(toLatin1(clue))
+		buf.WriteByte(0)
+	}
+	buf.Write(toLatin1(strs.notes))
+	buf.WriteByte(0)
+
+	if len(pal.Rebus) > 0 {
+		grbs, rtbl := encodeRebus(pal.Rebus, width*height)
+		writeSection(buf, "GRBS", grbs)
+		writeSection(buf, "RTBL", rtbl)
+	}
+	if len(pal.Circles) > 0 {
+		gext := make([]byte, width*height)
+		for index, circled := range pal.Circles {
+			if circled && index >= 0 && index < len(gext) {
+				gext[index] |= GEXT_CIRCLED
+			}
+		}
+		writeSection(buf, "GEXT", gext)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeRebus creates the GRBS and RTBL sections.  Each distinct rebus
+// text gets a key, assigned in order of the first cell that uses it.
+// GRBS has one byte per cell, which is zero for an ordinary cell and
+// one more than the key for a rebus cell.
+func encodeRebus(rebus map[int]string, size int) ([]byte, []byte) {
+	indices := make([]int, 0, len(rebus))
+	for index := range rebus {
+		if index >= 0 && index < size {
+			indices = append(indices, index)
+		}
+	}
+	sort.Ints(indices)
+
+	grbs := make([]byte, size)
+	keys := make(map[string]int)
+	sb := strings.Builder{}
+	for _, index := range indices {
+		text := strings.ToUpper(rebus[index])
+		key, ok := keys[text]
+		if !ok {
+			key = len(keys)
+			keys[text] = key
+			sb.WriteString(fmt.Sprintf("%2d:%s;", key, text))
+		}
+		grbs[index] = byte(key + 1)
+	}
+	return grbs, toLatin1(sb.String())
+}
+
+// writeSection writes one of the extra sections: its name, length,
+// checksum, data, and a null terminator.
+func writeSection(buf *bytes.Buffer, name string, data []byte) {
+	prefix := make([]byte, 8)
+	copy(prefix, name)
+	binary.LittleEndian.PutUint16(prefix[4:], uint16(len(data)))
+	binary.LittleEndian.PutUint16(prefix[6:], Checksum(data, 0))
+	buf.Write(prefix)
+	buf.Write(data)
+	buf.WriteByte(0)
+}
+
+// computeChecksums returns a copy of the header with the overall
+// checksum, the CIB checksum, and the masked checksums filled in.
+func computeChecksums(header, solution, grid []byte, strs *puzStrings) []byte {
+	result := make([]byte, HEADER_SIZE)
+	copy(result, header)
+
+	// The CIB checksum covers the header from the width to the end
+	cib := Checksum(header[OFFSET_WIDTH:HEADER_SIZE], 0)
+
+	// The overall checksum covers the CIB, both grids, and the strings
+	cksum := Checksum(solution, cib)
+	cksum = Checksum(grid, cksum)
+	cksum = stringsChecksum(strs, cksum)
+
+	// The masked checksums are the individual checksums XORed with the
+	// letters of "ICHEATED"
+	sums := []uint16{
+		cib,
+		Checksum(solution, 0),
+		Checksum(grid, 0),
+		stringsChecksum(strs, 0),
+	}
+	const mask = "ICHEATED"
+	for i, sum := range sums {
+		result[OFFSET_MASKED_LOW+i] = mask[i] ^ byte(sum)
+		result[OFFSET_MASKED_HIGH+i] = mask[i+4] ^ byte(sum>>8)
+	}
+
+	binary.LittleEndian.PutUint16(result[OFFSET_CHECKSUM:], cksum)
+	binary.LittleEndian.PutUint16(result[OFFSET_CIB_CHECKSUM:], cib)
+	return result
+}
+
+// Export writes a puzzle from the model as a .puz file.  Circled cells
+// are kept, but other cell styles cannot be represented in this format.
+func Export(puzzle *model.Puzzle, writer io.Writer) error {
+	return Write(exporter.NewAcrossLite(puzzle), writer)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+var (
+	OPTION_SUB    bool
+	OPTION_PAIRS  bool
+	OPTION_MIN    int
+	OPTION_SCORES bool
+)
+
+// This program finds the anagrams of a set of letters in the dictionary:
+// the words that use all the letters, the words that use some of them,
+// or the pairs of words that together use all of them.
+func main() {
+
+	const (
+		usage = `usage: anagram [OPTIONS] LETTERS...
+
+Finds the dictionary words that are anagrams of the letters. If more than
+one argument is given, they are joined together, so "anagram new york" is
+the same as "anagram newyork".
+
+positional arguments:
+  letters                  letters to anagram
+
+options:
+  -h, --help               display this help text and exit
+  -s, --sub                find words that use some of the letters
+  -p, --pairs              find pairs of words that use all the letters
+  -m, --min LENGTH         minimum word length with --sub or --pairs (default 3)
+  -c, --scores             show the score of each word
+`
+	)
+
+	// Parse the command line arguments
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.BoolVar(&OPTION_SUB, "s", false, "sub-anagrams")
+	flag.BoolVar(&OPTION_SUB, "sub", false, "sub-anagrams")
+	flag.BoolVar(&OPTION_PAIRS, "p", false, "two-word anagrams")
+	flag.BoolVar(&OPTION_PAIRS, "pairs", false, "two-word anagrams")
+	flag.IntVar(&OPTION_MIN, "m", 3, "minimum length")
+	flag.IntVar(&OPTION_MIN, "min", 3, "minimum length")
+	flag.BoolVar(&OPTION_SCORES, "c", false, "show scores")
+	flag.BoolVar(&OPTION_SCORES, "scores", false, "show scores")
+	flag.Parse()
+
+	if flag.NArg() == 0 || (OPTION_SUB && OPTION_PAIRS) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	letters := strings.Join(flag.Args(), "")
+	if err := model.LoadDictionary(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Find and list the anagrams
+	switch {
+	case OPTION_PAIRS:
+		for _, pair := range model.FindTwoWordAnagrams(letters, OPTION_MIN, 0) {
+			if OPTION_SCORES {
+				fmt.Printf("%s (%d)\n", pair.String(), pair.Score())
+			} else {
+				fmt.Println(pair.String())
+			}
+		}
+	case OPTION_SUB:
+		printWords(model.FindSubAnagrams(letters, OPTION_MIN))
+	default:
+		printWords(model.FindAnagrams(letters))
+	}
+}
+
+// printWords lists the words, one per line
+func printWords(words []model.ScoredWord) {
+	for _, sw := range words {
+		if OPTION_SCORES {
+			fmt.Printf("%s (%d)\n", sw.Word, sw.Score)
+		} else {
+			fmt.Println(sw.Word)
+		}
+	}
+}
@@ -27,7 +27,9 @@ func main() {
 	os.Remove(filename)
 
 	// Create new
-	model.CreateDatabase()
+	if err := model.CreateDatabase(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // CopyFile copies src into dst (Note the order of the arguments!)
@@ -43,6 +43,15 @@ func main() {
 	filename := filepath.Join(os.TempDir(), "across_lite.svg")
 	cells := model.PuzzleToSimpleMatrix(puzzle)
 	svgObj := svg.NewSVG(cells)
+	for i, row := range model.PuzzleToTextMatrix(puzzle) {
+		for j, text := range row {
+			svgObj.SetRebus(i+1, j+1, text)
+			style := puzzle.GetCellStyle(model.NewPoint(i+1, j+1))
+			svgObj.SetCircled(i+1, j+1, style.Circled)
+			svgObj.SetShade(i+1, j+1, style.Shade)
+			svgObj.SetBars(i+1, j+1, string(style.Bars))
+		}
+	}
 	svgString := svgObj.GenerateSVG()
 	svgBytes := []byte(svgString)
 	os.WriteFile(filename, svgBytes, 0644)
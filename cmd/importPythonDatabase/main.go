@@ -115,6 +115,15 @@ options:
 			log.Printf("Creating SVG in %s\n", filename)
 			cells := model.PuzzleToSimpleMatrix(puzzle)
 			image := svg.NewSVG(cells)
+			for i, row := range model.PuzzleToTextMatrix(puzzle) {
+				for j, text := range row {
+					image.SetRebus(i+1, j+1, text)
+					style := puzzle.GetCellStyle(model.NewPoint(i+1, j+1))
+					image.SetCircled(i+1, j+1, style.Circled)
+					image.SetShade(i+1, j+1, style.Shade)
+					image.SetBars(i+1, j+1, string(style.Bars))
+				}
+			}
 			svgString := image.GenerateSVG()
 			svgBytes := []byte(svgString)
 			os.WriteFile(filename, svgBytes, 0644)
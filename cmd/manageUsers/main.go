@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+var (
+	OPTION_PASSWORD string
+	OPTION_EMAIL    string
+	OPTION_AUTHOR   string
+	OPTION_ADDRESS1 string
+	OPTION_ADDRESS2 string
+	OPTION_CITY     string
+	OPTION_STATE    string
+	OPTION_ZIP      string
+)
+
+// This program adds users to the database, sets their passwords, and
+// shows or updates their author name and address.
+func main() {
+
+	const (
+		usage = `usage: manageUsers [OPTIONS] COMMAND USERNAME
+
+Manages the users in the database named in the configuration.
+
+commands:
+  add                      add a new user
+  passwd                   set the user's password
+  show                     show the user's profile as JSON
+  profile                  update the user's profile from the options below
+
+options:
+  -h, --help               display this help text and exit
+  -p, --password PASSWORD  password for add or passwd (default: read a
+                           line from standard input)
+  --email EMAIL            email address
+  --author NAME            name as author
+  --address1 LINE          address line 1
+  --address2 LINE          address line 2
+  --city CITY              city
+  --state STATE            state code
+  --zip ZIP                zip code
+`
+	)
+
+	// Parse the command line arguments
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.StringVar(&OPTION_PASSWORD, "p", "", "password")
+	flag.StringVar(&OPTION_PASSWORD, "password", "", "password")
+	flag.StringVar(&OPTION_EMAIL, "email", "", "email address")
+	flag.StringVar(&OPTION_AUTHOR, "author", "", "author name")
+	flag.StringVar(&OPTION_ADDRESS1, "address1", "", "address line 1")
+	flag.StringVar(&OPTION_ADDRESS2, "address2", "", "address line 2")
+	flag.StringVar(&OPTION_CITY, "city", "", "city")
+	flag.StringVar(&OPTION_STATE, "state", "", "state code")
+	flag.StringVar(&OPTION_ZIP, "zip", "", "zip code")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, username := flag.Arg(0), flag.Arg(1)
+
+	switch command {
+	case "add":
+		user, err := model.CreateUser(username, getPassword())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Added user %q with id %d\n", user.Username, user.ID)
+	case "passwd":
+		if err := model.SetPassword(username, getPassword()); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Changed password of user %q\n", username)
+	case "show":
+		printUser(getUser(username))
+	case "profile":
+		user := getUser(username)
+		setIfGiven := func(field *string, name string) {
+			flag.Visit(func(f *flag.Flag) {
+				if f.Name == name {
+					*field = f.Value.String()
+				}
+			})
+		}
+		setIfGiven(&user.Email, "email")
+		setIfGiven(&user.AuthorName, "author")
+		setIfGiven(&user.AddressLine1, "address1")
+		setIfGiven(&user.AddressLine2, "address2")
+		setIfGiven(&user.AddressCity, "city")
+		setIfGiven(&user.AddressState, "state")
+		setIfGiven(&user.AddressZip, "zip")
+		if err := model.UpdateProfile(user); err != nil {
+			log.Fatal(err)
+		}
+		printUser(getUser(username))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// getPassword returns the password given with -p, or else reads it
+// from the first line of standard input.
+func getPassword() string {
+	if OPTION_PASSWORD != "" {
+		return OPTION_PASSWORD
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Could not read password: %v\n", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// getUser returns the user with the specified name, or exits if there
+// is none.
+func getUser(username string) *model.User {
+	user, err := model.GetUserByName(username)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return user
+}
+
+// printUser prints the user's profile as JSON.
+func printUser(user *model.User) {
+	jsonBlob, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(jsonBlob))
+}
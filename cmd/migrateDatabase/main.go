@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/philhanna/cwcomp"
+	"github.com/philhanna/cwcomp/model"
+)
+
+var (
+	OPTION_STATUS bool
+)
+
+// This program upgrades the database schema in place by applying the
+// migrations that have not yet been applied to it, or shows which ones
+// have been.
+func main() {
+
+	const (
+		usage = `usage: migrateDatabase [OPTIONS]
+
+Brings the schema of the database named in the configuration up to date,
+keeping the data that is already there. The server does this when it
+starts, so this is only needed to upgrade a database beforehand.
+
+options:
+  -h, --help               display this help text and exit
+  -s, --status             list the migrations and whether they have been
+                           applied, without applying any
+`
+	)
+
+	// Parse the command line arguments
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.BoolVar(&OPTION_STATUS, "s", false, "show status")
+	flag.BoolVar(&OPTION_STATUS, "status", false, "show status")
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	filename := cwcomp.GetConfiguration().DATABASE.NAME
+	log.Printf("Database file name = %v\n", filename)
+
+	if OPTION_STATUS {
+		statuses, err := model.GetMigrationStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			applied := status.Applied
+			if applied == "" {
+				applied = "pending"
+			}
+			fmt.Printf("%4d  %-25s  %s\n", status.Version, status.Description, applied)
+		}
+		return
+	}
+
+	applied, err := model.Migrate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(applied) == 0 {
+		fmt.Printf("Database is up to date at version %d\n", model.LatestSchemaVersion())
+		return
+	}
+	fmt.Printf("Applied %d migration(s), now at version %d\n",
+		len(applied), model.LatestSchemaVersion())
+}
@@ -1,7 +1,15 @@
 package main
 
-import "github.com/philhanna/cwcomp/rest"
+import (
+	"log"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/philhanna/cwcomp/rest"
+)
 
 func main() {
+	if err := model.LoadDictionary(); err != nil {
+		log.Fatalf("Could not load the dictionary: %v\n", err)
+	}
 	rest.HandleRequests()
 }
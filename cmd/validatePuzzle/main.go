@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/philhanna/cwcomp/acrosslite/importer"
+	"github.com/philhanna/cwcomp/acrosslite/puz"
+	"github.com/philhanna/cwcomp/ipuz"
+	"github.com/philhanna/cwcomp/model"
+)
+
+var (
+	OPTION_JSON   bool
+	OPTION_USERID int
+)
+
+// This program checks a puzzle for problems that should be fixed before
+// it is submitted, such as short words, unchecked cells, and missing
+// clues, and lists them.  The exit code is 1 if any are found.
+func main() {
+
+	const (
+		usage = `usage: validatePuzzle [OPTIONS] PUZZLE
+
+Checks a puzzle for problems and lists them. The puzzle is either the name
+of a puzzle in the database or a file in AcrossLite text (.txt), AcrossLite
+binary (.puz), or ipuz (.ipuz) format.  The exit code is 1 if any problems
+are found.
+
+positional arguments:
+  puzzle                   puzzle name or file name
+
+options:
+  -h, --help               display this help text and exit
+  -u, --userid USERID      user who owns the puzzle (default 1)
+  -j, --json               list the problems as JSON
+`
+	)
+
+	// Parse the command line arguments
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.IntVar(&OPTION_USERID, "u", 1, "user ID")
+	flag.IntVar(&OPTION_USERID, "userid", 1, "user ID")
+	flag.BoolVar(&OPTION_JSON, "j", false, "JSON output")
+	flag.BoolVar(&OPTION_JSON, "json", false, "JSON output")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load the puzzle and validate it
+	puzzle, err := loadPuzzle(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	diagnostics := puzzle.Validate()
+
+	// List the problems
+	if OPTION_JSON {
+		jsonBlob, _ := json.MarshalIndent(diagnostics, "", "  ")
+		fmt.Println(string(jsonBlob))
+	} else {
+		for _, d := range diagnostics {
+			fmt.Println(d.String())
+		}
+		fmt.Printf("%d problems found\n", len(diagnostics))
+	}
+	if len(diagnostics) > 0 {
+		os.Exit(1)
+	}
+}
+
+// loadPuzzle reads the puzzle from a file, if one exists with this name,
+// or else from the database.
+func loadPuzzle(name string) (*model.Puzzle, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return model.LoadPuzzle(OPTION_USERID, name)
+	}
+	defer file.Close()
+
+	var source model.Importer
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".puz":
+		source, err = puz.Read(file)
+	case ".ipuz":
+		source, err = ipuz.Read(file)
+	default:
+		source, err = importer.Parse(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return model.ImportPuzzle(source)
+}
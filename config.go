@@ -13,17 +13,28 @@ import (
 // ---------------------------------------------------------------------
 
 type Configuration struct {
-	DATABASE database `json:"database"`
-	SERVER   server   `json:"server"`
+	DATABASE   database   `json:"database"`
+	SERVER     server     `json:"server"`
+	DICTIONARY dictionary `json:"dictionary"`
 }
 
 type database struct {
 	NAME string `json:"name"`
 }
 
+// dictionary is optional.  If FILE is empty, the embedded word list is
+// used, which has no scores, so its matches are in alphabetical order.
+type dictionary struct {
+	FILE     string `json:"file"`     // Word list in WORD;score format
+	MINSCORE int    `json:"minscore"` // Minimum score of words to match
+}
+
+// SESSIONS is where login sessions are kept: "memory", or "database"
+// (the default) so that they outlast a restart of the server.
 type server struct {
-	HOST string `json:"host"`
-	PORT int    `json:"port"`
+	HOST     string `json:"host"`
+	PORT     int    `json:"port"`
+	SESSIONS string `json:"sessions"`
 }
 
 // ---------------------------------------------------------------------
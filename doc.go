@@ -3,15 +3,16 @@
 // Contains types and functions that support the internal workings of
 // the application.
 //
-// A puzzle consists of an nxn matrix of cells, which are of two types:
+// A puzzle consists of an nRows x nCols matrix of cells (often, but not
+// necessarily, square), which are of two types:
 //
 //   - Black cells: Blocks in the grid
 //
 //   - Letter cells: Ordinary cells where letters of words can be
 //     placed.
 //
-// The puzzle also supports undo/redo for black cells and words in the
-// grid.
+// The puzzle also supports undo/redo for every change to the grid,
+// clues, and name, with a history that can be saved with the puzzle.
 //
 // The word list is derived from git@github.com:elasticdog/yawl.git,
 // with some editing by me.
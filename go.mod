@@ -5,9 +5,10 @@ go 1.22.0
 require (
 	github.com/ghodss/yaml v1.0.0
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/mattn/go-sqlite3 v1.14.22
-	github.com/philhanna/collections v1.4.0
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/crypto v0.33.0
 )
 
 require (
@@ -0,0 +1,341 @@
+// Ipuz reads and writes puzzles in the open ipuz JSON format described
+// at http://www.ipuz.org/.
+//
+// Only the crossword kind of ipuz puzzle is supported.  An IPuz
+// structure implements model.Importer, so a puzzle that has been read
+// can be loaded with model.ImportPuzzle, with multi-letter answers
+// becoming rebus cells, and with circles, background colors, and bars
+// becoming cell styles.  Going the other way, NewIPuz creates an IPuz
+// structure from a model.Puzzle.
+package ipuz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// IPuz is the representation of an ipuz crossword.  The field names and
+// JSON keys follow the ipuz specification; fields that this application
+// does not use are ignored when reading.
+type IPuz struct {
+	Name       string            `json:"-"`
+	Version    string            `json:"version"`
+	Kind       []string          `json:"kind"`
+	Dimensions Dimensions        `json:"dimensions"`
+	Title      string            `json:"title,omitempty"`
+	Author     string            `json:"author,omitempty"`
+	Copyright  string            `json:"copyright,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+	Block      string            `json:"block,omitempty"`
+	Empty      json.RawMessage   `json:"empty,omitempty"`
+	Puzzle     [][]PuzzleCell    `json:"puzzle"`
+	Solution   [][]SolutionCell  `json:"solution,omitempty"`
+	Clues      map[string][]Clue `json:"clues,omitempty"`
+}
+
+// Dimensions is the width and height of the grid
+type Dimensions struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// PuzzleCell is one cell of the puzzle grid.  In JSON, this is a word
+// number (0 for none), the block string, null for an omitted cell, or an
+// object with "cell" and "style" keys.
+type PuzzleCell struct {
+	Number int    // Word number, or 0 if the cell is not numbered
+	Block  bool   // True if this is a black cell
+	Null   bool   // True if the cell is omitted from the grid
+	Style  *Style // Optional style for the cell
+	text   string // Original string value, if not a number
+}
+
+// Style is the subset of the ipuz cell style used by this application:
+// a circle, the background color (six hex digits, without a '#'), and
+// the sides of the cell with bars (a string of T, R, B, and L).
+type Style struct {
+	Shapebg string `json:"shapebg,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Barred  string `json:"barred,omitempty"`
+}
+
+// SolutionCell is one cell of the solution grid.  In JSON, this is a
+// string of one or more letters, the block string, null, or an object
+// with a "value" key.  An empty value means the answer is unknown.
+type SolutionCell string
+
+// Clue is a word number and the text of its clue.  In JSON, this is
+// written as a [number, "text"] pair, but the object form
+// {"number": n, "clue": "text"} is also accepted when reading.
+type Clue struct {
+	Number int
+	Text   string
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+const (
+	VERSION        = "http://ipuz.org/v2"
+	KIND_CROSSWORD = "http://ipuz.org/crossword#1"
+	DEFAULT_BLOCK  = "#"
+	CIRCLE         = "circle"
+	ACROSS         = "Across"
+	DOWN           = "Down"
+)
+
+var (
+	ErrNotCrossword = errors.New("ipuz file is not a crossword")
+	ErrDimensions   = errors.New("ipuz grid does not match its dimensions")
+)
+
+// ---------------------------------------------------------------------
+// Constructor
+// ---------------------------------------------------------------------
+
+// NewIPuz creates an ipuz crossword from a puzzle in the model.
+func NewIPuz(puzzle *model.Puzzle) *IPuz {
+	nRows, nCols := puzzle.GetRows(), puzzle.GetCols()
+
+	p := new(IPuz)
+	p.Name = puzzle.GetPuzzleName()
+	p.Version = VERSION
+	p.Kind = []string{KIND_CROSSWORD}
+	p.Dimensions = Dimensions{Width: nCols, Height: nRows}
+	p.Title = puzzle.GetPuzzleName()
+	p.Block = DEFAULT_BLOCK
+
+	// Create the puzzle and solution grids
+	cells := model.PuzzleToSimpleMatrix(puzzle)
+	texts := model.PuzzleToTextMatrix(puzzle)
+	p.Puzzle = make([][]PuzzleCell, nRows)
+	p.Solution = make([][]SolutionCell, nRows)
+	for i := 0; i < nRows; i++ {
+		p.Puzzle[i] = make([]PuzzleCell, nCols)
+		p.Solution[i] = make([]SolutionCell, nCols)
+		for j := 0; j < nCols; j++ {
+			switch letter := cells[i][j]; letter {
+			case model.BLACK_CELL:
+				p.Puzzle[i][j].Block = true
+				p.Solution[i][j] = SolutionCell(p.Block)
+			case ' ':
+				p.Solution[i][j] = ""
+			default:
+				p.Solution[i][j] = SolutionCell(texts[i][j])
+			}
+			p.Puzzle[i][j].Style = newStyle(puzzle.GetCellStyle(model.NewPoint(i+1, j+1)))
+		}
+	}
+
+	// Number the cells and collect the clues
+	across := make([]Clue, 0)
+	down := make([]Clue, 0)
+	for _, nc := range model.GetNumberedCells(cells) {
+		p.Puzzle[nc.Row-1][nc.Col-1].Number = nc.Seq
+		if nc.StartA {
+			across = append(across, newClue(puzzle, nc.Seq, model.ACROSS))
+		}
+		if nc.StartD {
+			down = append(down, newClue(puzzle, nc.Seq, model.DOWN))
+		}
+	}
+	p.Clues = map[string][]Clue{
+		ACROSS: across,
+		DOWN:   down,
+	}
+
+	return p
+}
+
+// newStyle creates the ipuz style of a cell, or nil if it has none
+func newStyle(cellStyle model.CellStyle) *Style {
+	if cellStyle.IsPlain() {
+		return nil
+	}
+	style := new(Style)
+	if cellStyle.Circled {
+		style.Shapebg = CIRCLE
+	}
+	style.Color = strings.TrimPrefix(cellStyle.Shade, "#")
+	style.Barred = string(cellStyle.Bars)
+	return style
+}
+
+// newClue creates the clue for a word in the puzzle
+func newClue(puzzle *model.Puzzle, seq int, dir model.Direction) Clue {
+	clue := Clue{Number: seq}
+	if word := puzzle.LookupWordByNumber(seq, dir); word != nil {
+		clue.Text, _ = puzzle.GetClue(word)
+	}
+	return clue
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetAcrossClues returns a map of across word numbers to their clues.
+func (p *IPuz) GetAcrossClues() map[int]string {
+	return p.getClues(ACROSS)
+}
+
+// GetAuthor returns the author
+func (p *IPuz) GetAuthor() string {
+	return p.Author
+}
+
+// GetCell returns the letter at a given point in the grid.  These are
+// relative to 1, not 0.  A black cell is returned as '\x00' and a cell
+// with no known answer as ' '.  If the answer has more than one letter
+// (a rebus), only the first letter is returned.
+func (p *IPuz) GetCell(r, c int) (byte, error) {
+	if r < 1 || r > p.GetRows() || c < 1 || c > p.GetCols() {
+		return 0, fmt.Errorf("invalid index: r=%d,c=%d", r, c)
+	}
+	i, j := r-1, c-1
+	if cell := p.Puzzle[i][j]; cell.Block || cell.Null {
+		return model.BLACK_CELL, nil
+	}
+	if i >= len(p.Solution) || j >= len(p.Solution[i]) {
+		return ' ', nil
+	}
+	value := string(p.Solution[i][j])
+	if value == p.block() {
+		return model.BLACK_CELL, nil
+	}
+	if value == "" {
+		return ' ', nil
+	}
+	return strings.ToUpper(value)[0], nil
+}
+
+// GetCols returns the number of columns in the grid
+func (p *IPuz) GetCols() int {
+	return p.Dimensions.Width
+}
+
+// GetCopyright returns the copyright
+func (p *IPuz) GetCopyright() string {
+	return p.Copyright
+}
+
+// GetDownClues returns a map of down word numbers to their clues.
+func (p *IPuz) GetDownClues() map[int]string {
+	return p.getClues(DOWN)
+}
+
+// GetName returns the puzzle name
+func (p *IPuz) GetName() string {
+	return p.Name
+}
+
+// GetNotes returns the notes
+func (p *IPuz) GetNotes() string {
+	return p.Notes
+}
+
+// GetRebus returns the full text of the cell at the given point
+// (relative to 1) if its answer has more than one letter, or "" if not.
+func (p *IPuz) GetRebus(r, c int) string {
+	if r < 1 || r > len(p.Solution) || c < 1 || c > len(p.Solution[r-1]) {
+		return ""
+	}
+	value := string(p.Solution[r-1][c-1])
+	if len(value) < 2 || value == p.block() {
+		return ""
+	}
+	return strings.ToUpper(value)
+}
+
+// GetRows returns the number of rows in the grid
+func (p *IPuz) GetRows() int {
+	return p.Dimensions.Height
+}
+
+// GetTitle returns the puzzle title
+func (p *IPuz) GetTitle() string {
+	return p.Title
+}
+
+// GetCellStyle returns the style of the cell at the given point
+// (relative to 1).  A background color given as hex digits is returned
+// with a leading '#'.
+func (p *IPuz) GetCellStyle(r, c int) model.CellStyle {
+	cellStyle := model.CellStyle{Circled: p.IsCircled(r, c)}
+	if r < 1 || r > p.GetRows() || c < 1 || c > p.GetCols() {
+		return cellStyle
+	}
+	style := p.Puzzle[r-1][c-1].Style
+	if style == nil {
+		return cellStyle
+	}
+	cellStyle.Shade = style.Color
+	if isHexColor(style.Color) {
+		cellStyle.Shade = "#" + style.Color
+	}
+	cellStyle.Bars, _ = model.ParseBars(style.Barred)
+	return cellStyle
+}
+
+// IsCircled returns true if the cell at the given point (relative to 1)
+// has a circle drawn in it.
+func (p *IPuz) IsCircled(r, c int) bool {
+	if r < 1 || r > p.GetRows() || c < 1 || c > p.GetCols() {
+		return false
+	}
+	style := p.Puzzle[r-1][c-1].Style
+	return style != nil && style.Shapebg == CIRCLE
+}
+
+// SetName sets the puzzle name
+func (p *IPuz) SetName(name string) {
+	p.Name = name
+}
+
+// block returns the string used for black cells in this puzzle
+func (p *IPuz) block() string {
+	if p.Block == "" {
+		return DEFAULT_BLOCK
+	}
+	return p.Block
+}
+
+// getClues returns the clues for a direction.  The ipuz direction may
+// have a label attached, as in "Across:Horizontal", so only the part
+// before the colon is compared.
+func (p *IPuz) getClues(direction string) map[int]string {
+	clueMap := make(map[int]string)
+	for key, clues := range p.Clues {
+		name, _, _ := strings.Cut(key, ":")
+		if !strings.EqualFold(name, direction) {
+			continue
+		}
+		for _, clue := range clues {
+			clueMap[clue.Number] = clue.Text
+		}
+	}
+	return clueMap
+}
+
+// isHexColor returns true if the string is an RGB color of six hex
+// digits
+func isHexColor(s string) bool {
+	if len(s) != 6 {
+		return false
+	}
+	for _, ch := range s {
+		if !strings.ContainsRune("0123456789ABCDEFabcdef", ch) {
+			return false
+		}
+	}
+	return true
+}
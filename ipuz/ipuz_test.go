@@ -0,0 +1,168 @@
+package ipuz
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func readSample(t *testing.T) *IPuz {
+	fp, err := os.Open(filepath.Join("testdata", "sample.ipuz"))
+	assert.Nil(t, err)
+	defer fp.Close()
+	p, err := Read(fp)
+	assert.Nil(t, err)
+	p.SetName("sample")
+	return p
+}
+
+func TestRead(t *testing.T) {
+	p := readSample(t)
+	assert.Equal(t, 3, p.GetRows())
+	assert.Equal(t, 4, p.GetCols())
+	assert.Equal(t, "Small", p.GetTitle())
+	assert.Equal(t, "Jack London", p.GetAuthor())
+
+	tests := []struct {
+		r, c int
+		want byte
+	}{
+		{1, 1, 'C'},
+		{1, 4, model.BLACK_CELL},
+		{3, 1, model.BLACK_CELL},
+		{3, 3, 'E'},
+		{3, 4, ' '},
+	}
+	for _, tt := range tests {
+		got, err := p.GetCell(tt.r, tt.c)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+	_, err := p.GetCell(4, 1)
+	assert.NotNil(t, err)
+
+	assert.True(t, p.IsCircled(1, 2))
+	assert.False(t, p.IsCircled(1, 1))
+
+	assert.Equal(t, map[int]string{1: "Feline", 4: "God of war", 6: "Buzzer"}, p.GetAcrossClues())
+	assert.Equal(t, map[int]string{1: "Taxi", 2: "Letters before C", 3: "Beverage", 5: "Snake"}, p.GetDownClues())
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"not a crossword", `{"kind": ["http://ipuz.org/sudoku#1"], "dimensions": {"width": 1, "height": 1}, "puzzle": [[0]]}`, ErrNotCrossword},
+		{"too few rows", `{"kind": ["http://ipuz.org/crossword#1"], "dimensions": {"width": 1, "height": 2}, "puzzle": [[0]]}`, ErrDimensions},
+		{"too few columns", `{"kind": ["http://ipuz.org/crossword#1"], "dimensions": {"width": 2, "height": 1}, "puzzle": [[0]]}`, ErrDimensions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.data))
+			assert.Equal(t, tt.want, err)
+		})
+	}
+	_, err := Read(strings.NewReader("not json"))
+	assert.NotNil(t, err)
+}
+
+func TestRead_Wrapped(t *testing.T) {
+	data := `ipuz({"kind": ["http://ipuz.org/crossword#1"], "dimensions": {"width": 2, "height": 1},
+		"block": "X", "puzzle": [[1, "X"]], "solution": [["A", "X"]]})`
+	p, err := Read(strings.NewReader(data))
+	assert.Nil(t, err)
+	letter, _ := p.GetCell(1, 2)
+	assert.Equal(t, byte(model.BLACK_CELL), letter)
+	assert.Equal(t, DEFAULT_BLOCK, p.Block)
+}
+
+func TestImportPuzzle(t *testing.T) {
+	puzzle, err := model.ImportPuzzle(readSample(t))
+	assert.Nil(t, err)
+	assert.Equal(t, "sample", puzzle.GetPuzzleName())
+	assert.True(t, puzzle.IsBlackCell(model.NewPoint(1, 4)))
+
+	word := puzzle.LookupWordByNumber(4, model.ACROSS)
+	assert.Equal(t, "ARES", puzzle.GetText(word))
+	clue, _ := puzzle.GetClue(word)
+	assert.Equal(t, "God of war", clue)
+
+	word = puzzle.LookupWordByNumber(2, model.DOWN)
+	clue, _ = puzzle.GetClue(word)
+	assert.Equal(t, "Letters before C", clue)
+}
+
+func TestExport(t *testing.T) {
+	puzzle, err := model.ImportPuzzle(readSample(t))
+	assert.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Export(puzzle, buf))
+	assert.Contains(t, buf.String(), `"http://ipuz.org/crossword#1"`)
+
+	p, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "sample", p.GetTitle())
+	assert.Equal(t, 1, p.Puzzle[0][0].Number)
+	assert.Equal(t, 6, p.Puzzle[2][1].Number)
+	assert.True(t, p.Puzzle[0][3].Block)
+	assert.Equal(t, SolutionCell(""), p.Solution[2][3])
+
+	other, err := model.ImportPuzzle(p)
+	assert.Nil(t, err)
+	for point := range puzzle.PointIterator() {
+		assert.Equal(t, puzzle.GetLetter(point), other.GetLetter(point))
+	}
+	assert.Equal(t, readSample(t).GetAcrossClues(), p.GetAcrossClues())
+	assert.Equal(t, readSample(t).GetDownClues(), p.GetDownClues())
+}
+
+func TestExport_Rebus(t *testing.T) {
+	puzzle, err := model.ImportPuzzle(readSample(t))
+	assert.Nil(t, err)
+	word := puzzle.LookupWordByNumber(1, model.ACROSS)
+	assert.Nil(t, puzzle.SetText(word, "[CAT]AT"))
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Export(puzzle, buf))
+	p, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, SolutionCell("CAT"), p.Solution[0][0])
+	assert.Equal(t, "CAT", p.GetRebus(1, 1))
+	assert.Equal(t, "", p.GetRebus(1, 2))
+
+	other, err := model.ImportPuzzle(p)
+	assert.Nil(t, err)
+	assert.Equal(t, "[CAT]AT", other.GetText(other.LookupWordByNumber(1, model.ACROSS)))
+}
+
+func TestExport_Styles(t *testing.T) {
+	puzzle, err := model.ImportPuzzle(readSample(t))
+	assert.Nil(t, err)
+	style := model.CellStyle{Circled: true, Shade: "#C0C0C0", Bars: "RB"}
+	assert.Nil(t, puzzle.SetCellStyle(model.NewPoint(2, 2), style))
+	assert.Nil(t, puzzle.SetShade(model.NewPoint(1, 1), "gray"))
+
+	buf := new(bytes.Buffer)
+	assert.Nil(t, Export(puzzle, buf))
+	assert.Contains(t, buf.String(), `"color": "C0C0C0"`)
+	p, err := Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, &Style{Shapebg: CIRCLE, Color: "C0C0C0", Barred: "RB"}, p.Puzzle[1][1].Style)
+	assert.Equal(t, &Style{Shapebg: CIRCLE}, p.Puzzle[0][1].Style)
+	assert.Nil(t, p.Puzzle[0][2].Style)
+
+	other, err := model.ImportPuzzle(p)
+	assert.Nil(t, err)
+	assert.Equal(t, style, other.GetCellStyle(model.NewPoint(2, 2)))
+	assert.Equal(t, "gray", other.GetCellStyle(model.NewPoint(1, 1)).Shade)
+	assert.Equal(t, model.CellStyle{Circled: true}, other.GetCellStyle(model.NewPoint(1, 2)))
+	assert.True(t, other.GetCellStyle(model.NewPoint(1, 3)).IsPlain())
+}
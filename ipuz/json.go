@@ -0,0 +1,178 @@
+package ipuz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// MarshalJSON writes a puzzle cell as a number, the block string, null,
+// or, if it has a style, an object.
+func (cell PuzzleCell) MarshalJSON() ([]byte, error) {
+	switch {
+	case cell.Null:
+		return []byte("null"), nil
+	case cell.Block:
+		return json.Marshal(DEFAULT_BLOCK)
+	case cell.Style != nil:
+		return json.Marshal(struct {
+			Cell  int    `json:"cell"`
+			Style *Style `json:"style"`
+		}{cell.Number, cell.Style})
+	default:
+		return json.Marshal(cell.Number)
+	}
+}
+
+// UnmarshalJSON reads a puzzle cell in any of the forms allowed by the
+// ipuz specification.  Whether a string value is the block string is
+// decided later, when the whole puzzle has been read.
+func (cell *PuzzleCell) UnmarshalJSON(data []byte) error {
+	*cell = PuzzleCell{}
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		cell.Null = true
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var obj struct {
+			Cell  json.RawMessage `json:"cell"`
+			Style *Style          `json:"style"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if len(obj.Cell) > 0 {
+			if err := cell.UnmarshalJSON(obj.Cell); err != nil {
+				return err
+			}
+		}
+		cell.Style = obj.Style
+		return nil
+	}
+
+	value, err := scalarString(data)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		cell.Number = n
+	} else {
+		cell.text = value
+	}
+	return nil
+}
+
+// MarshalJSON writes a solution cell as a string, or null if the answer
+// is not known.
+func (cell SolutionCell) MarshalJSON() ([]byte, error) {
+	if cell == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(string(cell))
+}
+
+// UnmarshalJSON reads a solution cell, which may be a string, a number
+// (meaning an empty cell), null, or an object with a "value" key.
+func (cell *SolutionCell) UnmarshalJSON(data []byte) error {
+	*cell = ""
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var obj struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if len(obj.Value) == 0 {
+			return nil
+		}
+		return cell.UnmarshalJSON(obj.Value)
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*cell = SolutionCell(s)
+		return nil
+	}
+
+	// Anything else (such as the number 0) is an empty cell
+	return nil
+}
+
+// MarshalJSON writes a clue as a [number, "text"] pair.
+func (clue Clue) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{clue.Number, clue.Text})
+}
+
+// UnmarshalJSON reads a clue as a [number, "text"] pair or as an object
+// with "number" and "clue" keys.  The number may be a string.
+func (clue *Clue) UnmarshalJSON(data []byte) error {
+	*clue = Clue{}
+	data = bytes.TrimSpace(data)
+	var number json.RawMessage
+	switch {
+	case len(data) > 0 && data[0] == '[':
+		var pair []json.RawMessage
+		if err := json.Unmarshal(data, &pair); err != nil {
+			return err
+		}
+		if len(pair) < 2 {
+			return fmt.Errorf("invalid ipuz clue: %s", data)
+		}
+		number = pair[0]
+		if err := json.Unmarshal(pair[1], &clue.Text); err != nil {
+			return err
+		}
+	case len(data) > 0 && data[0] == '{':
+		var obj struct {
+			Number json.RawMessage `json:"number"`
+			Clue   string          `json:"clue"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		number = obj.Number
+		clue.Text = obj.Clue
+	default:
+		return fmt.Errorf("invalid ipuz clue: %s", data)
+	}
+
+	value, err := scalarString(number)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid ipuz clue number: %s", number)
+	}
+	clue.Number = n
+	return nil
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// scalarString returns the value of a JSON string or number as a string.
+func scalarString(data []byte) (string, error) {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
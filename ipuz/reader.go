@@ -0,0 +1,86 @@
+package ipuz
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Read parses an ipuz crossword.  Some ipuz files are wrapped in
+// "ipuz(...)" so that they can be loaded as JSONP; the wrapper is
+// removed if present.
+//
+// The block string is normalized to DEFAULT_BLOCK, so that the puzzle
+// can be written back out unchanged.
+func Read(reader io.Reader) (*IPuz, error) {
+	blob, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	blob = bytes.TrimSpace(blob)
+	if bytes.HasPrefix(blob, []byte("ipuz(")) && bytes.HasSuffix(blob, []byte(")")) {
+		blob = blob[len("ipuz(") : len(blob)-1]
+	}
+
+	p := new(IPuz)
+	if err := json.Unmarshal(blob, p); err != nil {
+		return nil, err
+	}
+
+	// Make sure this is a crossword of the expected size
+	crossword := false
+	for _, kind := range p.Kind {
+		if strings.Contains(kind, "/crossword") {
+			crossword = true
+			break
+		}
+	}
+	if !crossword {
+		return nil, ErrNotCrossword
+	}
+	nRows, nCols := p.GetRows(), p.GetCols()
+	if nRows < 1 || nCols < 1 || len(p.Puzzle) != nRows {
+		return nil, ErrDimensions
+	}
+	for _, row := range p.Puzzle {
+		if len(row) != nCols {
+			return nil, ErrDimensions
+		}
+	}
+	if len(p.Solution) > 0 {
+		if len(p.Solution) != nRows {
+			return nil, ErrDimensions
+		}
+		for _, row := range p.Solution {
+			if len(row) != nCols {
+				return nil, ErrDimensions
+			}
+		}
+	}
+
+	// Normalize the block string
+	block := p.block()
+	for i, row := range p.Puzzle {
+		for j, cell := range row {
+			if cell.text == block {
+				p.Puzzle[i][j].Block = true
+			}
+			p.Puzzle[i][j].text = ""
+		}
+	}
+	for i, row := range p.Solution {
+		for j, cell := range row {
+			if string(cell) == block {
+				p.Solution[i][j] = DEFAULT_BLOCK
+			}
+		}
+	}
+	p.Block = DEFAULT_BLOCK
+
+	return p, nil
+}
@@ -0,0 +1,24 @@
+package ipuz
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Write writes the ipuz crossword as JSON to the specified writer.
+func Write(p *IPuz, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// Export writes a puzzle from the model in ipuz format.
+func Export(puzzle *model.Puzzle, writer io.Writer) error {
+	return Write(NewIPuz(puzzle), writer)
+}
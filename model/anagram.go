@@ -0,0 +1,235 @@
+package model
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// AnagramPair is a two-word anagram of a set of letters
+type AnagramPair struct {
+	First  ScoredWord `json:"first"`
+	Second ScoredWord `json:"second"`
+}
+
+// pairHeap is a heap of anagram pairs with the worst one on top, so
+// that the best pairs can be kept without keeping them all.
+type pairHeap []AnagramPair
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// The anagram index maps the sorted letters of each dictionary word at
+// or above the minimum score to the words with those letters, best
+// first.  It is rebuilt only when the dictionary or minimum score
+// changes.
+var (
+	anagramIndexMutex   sync.Mutex
+	anagramIndexVersion = -1
+	anagramIndex        map[string][]ScoredWord
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// FindAnagrams returns the dictionary words that use exactly the
+// specified letters, best first.  Anything other than the letters A-Z
+// is ignored, so "Heart!" and "HEART" are the same.
+func FindAnagrams(letters string) []ScoredWord {
+	key := anagramKey(normalizeWord(letters))
+	words := getAnagramIndex()[key]
+	anagrams := make([]ScoredWord, len(words))
+	copy(anagrams, words)
+	return anagrams
+}
+
+// FindSubAnagrams returns the dictionary words of at least minLength
+// letters that can be made from some of the specified letters (a letter
+// can be used only as many times as it appears).  The longest words
+// come first, and words of the same length are best first.
+func FindSubAnagrams(letters string, minLength int) []ScoredWord {
+	letters = normalizeWord(letters)
+	counts := letterCounts(letters)
+	subAnagrams := make([]ScoredWord, 0)
+	for key, words := range getAnagramIndex() {
+		if len(key) < minLength || len(key) > len(letters) {
+			continue
+		}
+		if _, ok := subtractLetters(counts, key); ok {
+			subAnagrams = append(subAnagrams, words...)
+		}
+	}
+	sort.Slice(subAnagrams, func(i, j int) bool {
+		a, b := subAnagrams[i], subAnagrams[j]
+		if len(a.Word) != len(b.Word) {
+			return len(a.Word) > len(b.Word)
+		}
+		return a.ranksBefore(b)
+	})
+	return subAnagrams
+}
+
+// FindTwoWordAnagrams returns the pairs of dictionary words, each of at
+// least minLength letters, that together use exactly the specified
+// letters.  The pairs with the highest total score come first.  Each
+// pair is listed once, with the alphabetically first set of letters in
+// the first word.  If limit is greater than zero, only that many of the
+// best pairs are returned, and only that many are kept while searching.
+func FindTwoWordAnagrams(letters string, minLength, limit int) []AnagramPair {
+	if minLength < 1 {
+		minLength = 1
+	}
+	letters = normalizeWord(letters)
+	counts := letterCounts(letters)
+	index := getAnagramIndex()
+	pairs := make(pairHeap, 0)
+	add := func(pair AnagramPair) {
+		switch {
+		case limit <= 0:
+			pairs = append(pairs, pair)
+		case len(pairs) < limit:
+			heap.Push(&pairs, pair)
+		case pair.ranksBefore(pairs[0]):
+			pairs[0] = pair
+			heap.Fix(&pairs, 0)
+		}
+	}
+	for key, firstWords := range index {
+		if len(key) < minLength || len(letters)-len(key) < minLength {
+			continue
+		}
+		rest, ok := subtractLetters(counts, key)
+		if !ok {
+			continue
+		}
+		otherKey := countsToKey(rest)
+		if key > otherKey {
+			continue
+		}
+		secondWords, ok := index[otherKey]
+		if !ok {
+			continue
+		}
+		for i, first := range firstWords {
+			for j, second := range secondWords {
+				if key == otherKey && j < i {
+					continue
+				}
+				add(AnagramPair{first, second})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].ranksBefore(pairs[j])
+	})
+	return pairs
+}
+
+// getAnagramIndex returns the anagram index, building it if necessary.
+func getAnagramIndex() map[string][]ScoredWord {
+	anagramIndexMutex.Lock()
+	defer anagramIndexMutex.Unlock()
+
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	if anagramIndexVersion != dictionaryVersion {
+		anagramIndex = make(map[string][]ScoredWord)
+		for _, sw := range dictionary {
+			if sw.Score < dictionaryMinimum {
+				break
+			}
+			key := anagramKey(sw.Word)
+			anagramIndex[key] = append(anagramIndex[key], sw)
+		}
+		anagramIndexVersion = dictionaryVersion
+	}
+	return anagramIndex
+}
+
+// anagramKey returns the letters of a word in alphabetical order, which
+// is the same for all its anagrams.
+func anagramKey(word string) string {
+	return countsToKey(letterCounts(word))
+}
+
+// letterCounts returns the number of times each letter A-Z appears in
+// a word.
+func letterCounts(word string) [26]int {
+	var counts [26]int
+	for i := 0; i < len(word); i++ {
+		if ch := word[i]; ch >= 'A' && ch <= 'Z' {
+			counts[ch-'A']++
+		}
+	}
+	return counts
+}
+
+// countsToKey returns the letters with these counts in alphabetical
+// order.
+func countsToKey(counts [26]int) string {
+	sb := strings.Builder{}
+	for i, n := range counts {
+		for ; n > 0; n-- {
+			sb.WriteByte(byte('A' + i))
+		}
+	}
+	return sb.String()
+}
+
+// subtractLetters removes the letters of a key from a set of counts,
+// returning the counts that remain and whether all the letters were
+// there to remove.
+func subtractLetters(counts [26]int, key string) ([26]int, bool) {
+	for i := 0; i < len(key); i++ {
+		ch := key[i] - 'A'
+		if counts[ch] == 0 {
+			return counts, false
+		}
+		counts[ch]--
+	}
+	return counts, true
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Score returns the total score of the two words
+func (pair AnagramPair) Score() int {
+	return pair.First.Score + pair.Second.Score
+}
+
+// String returns the two words separated by a space
+func (pair AnagramPair) String() string {
+	return pair.First.Word + " " + pair.Second.Word
+}
+
+// ranksBefore returns true if this pair comes before another in the
+// list of two-word anagrams, i.e., it has a higher total score, or the
+// same score and comes first alphabetically.
+func (pair AnagramPair) ranksBefore(other AnagramPair) bool {
+	if pair.Score() != other.Score() {
+		return pair.Score() > other.Score()
+	}
+	return pair.String() < other.String()
+}
+
+// Len, Less, Swap, Push, and Pop implement heap.Interface, with the
+// worst pair first.
+func (h pairHeap) Len() int           { return len(h) }
+func (h pairHeap) Less(i, j int) bool { return h[j].ranksBefore(h[i]) }
+func (h pairHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *pairHeap) Push(x any)        { *h = append(*h, x.(AnagramPair)) }
+func (h *pairHeap) Pop() any {
+	old := *h
+	pair := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return pair
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// anagramWordList is a small word list for the anagram tests
+const anagramWordList = `HEART;60
+EARTH;70
+HATER;40
+RATHE;20
+HEAT;50
+TEAR;55
+RATE;45
+HAT;30
+EAR;35
+ART;25
+THE;65
+A;10`
+
+// wordsOf returns the words in a list of scored words
+func wordsOf(list []ScoredWord) []string {
+	words := make([]string, len(list))
+	for i, sw := range list {
+		words[i] = sw.Word
+	}
+	return words
+}
+
+func TestFindAnagrams(t *testing.T) {
+	defer useWordList(t, anagramWordList)()
+	tests := []struct {
+		letters string
+		want    []string
+	}{
+		{"HEART", []string{"EARTH", "HEART", "HATER", "RATHE"}},
+		{"t-h-e-r-a", []string{"EARTH", "HEART", "HATER", "RATHE"}},
+		{"TARE", []string{"TEAR", "RATE"}},
+		{"XYZ", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.letters, func(t *testing.T) {
+			assert.Equal(t, tt.want, wordsOf(FindAnagrams(tt.letters)))
+		})
+	}
+}
+
+func TestFindAnagrams_MinScore(t *testing.T) {
+	defer useWordList(t, anagramWordList)()
+	SetMinScore(50)
+	assert.Equal(t, []string{"EARTH", "HEART"}, wordsOf(FindAnagrams("HEART")))
+}
+
+func TestFindSubAnagrams(t *testing.T) {
+	defer useWordList(t, anagramWordList)()
+	tests := []struct {
+		name      string
+		letters   string
+		minLength int
+		want      []string
+	}{
+		{"all", "HEAT", 0, []string{"HEAT", "THE", "HAT", "A"}},
+		{"min length", "HEART", 4, []string{"EARTH", "HEART", "HATER", "RATHE", "TEAR", "HEAT", "RATE"}},
+		{"repeated letters", "AA", 1, []string{"A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, wordsOf(FindSubAnagrams(tt.letters, tt.minLength)))
+		})
+	}
+}
+
+func TestFindTwoWordAnagrams(t *testing.T) {
+	defer useWordList(t, anagramWordList)()
+	tests := []struct {
+		name      string
+		letters   string
+		minLength int
+		limit     int
+		want      []string
+	}{
+		{"pairs", "HEARTHAT", 3, 0, []string{"EARTH HAT", "HEART HAT", "HATER HAT", "RATHE HAT"}},
+		{"same letters", "HATHAT", 3, 0, []string{"HAT HAT"}},
+		{"min length", "HEARTA", 2, 0, []string{"EAR HAT"}},
+		{"too short", "HEARTA", 4, 0, []string{}},
+		{"short words", "HEARTA", 1, 0, []string{"A EARTH", "A HEART", "EAR HAT", "A HATER", "A RATHE"}},
+		{"limit", "HEARTA", 1, 3, []string{"A EARTH", "A HEART", "EAR HAT"}},
+		{"limit above count", "HEARTHAT", 3, 10, []string{"EARTH HAT", "HEART HAT", "HATER HAT", "RATHE HAT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have := make([]string, 0)
+			for _, pair := range FindTwoWordAnagrams(tt.letters, tt.minLength, tt.limit) {
+				have = append(have, pair.String())
+			}
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// AutofillOptions controls how Autofill searches for a fill.
+type AutofillOptions struct {
+	//
+	// If not empty, only the words that pass through at least one of
+	// these points are filled.  Otherwise, the whole grid is filled.
+	//
+	Region []Point
+	//
+	// Maximum time to spend searching. Zero means no limit.
+	//
+	TimeLimit time.Duration
+	//
+	// Closing this channel cancels the search. May be nil.
+	//
+	Stop <-chan struct{}
+}
+
+// fillSlot is the internal representation of a word in the grid while
+// the autofill search is running.
+type fillSlot struct {
+	word     *Word   // The word in the puzzle
+	points   []Point // The points of the word, in order
+	fillable bool    // True if the search may write into this word
+	checked  bool    // True if the word must remain completable
+}
+
+// autofiller holds the state of a single autofill search.  It works on
+// its own copy of the letters so that the puzzle is only changed if a
+// complete fill is found.
+type autofiller struct {
+	grid     map[Point]byte   // Letters in the grid (' ' if empty)
+	slots    []*fillSlot      // All the words in the grid
+	crossers map[Point][]int  // Indices of slots passing through a point
+	used     map[string]bool  // Answers already in the grid
+	byLength map[int][]string // Dictionary words grouped by length
+	inDict   map[string]bool  // Dictionary membership
+	stop     <-chan struct{}  // Cancellation channel
+	deadline time.Time        // Zero if no time limit
+	steps    int              // Number of nodes visited
+	err      error            // Reason the search was interrupted
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+var (
+	ErrAutofillCanceled   = errors.New("autofill was canceled")
+	ErrAutofillTimeout    = errors.New("autofill time limit exceeded")
+	ErrAutofillNoSolution = errors.New("no fill found for the grid")
+)
+
+// How often (in search nodes) to check for cancellation and timeout
+const autofillCheckInterval = 64
+
+var (
+	autofillIndexMutex   sync.Mutex
+	autofillIndexVersion = -1
+	autofillByLength     map[int][]string
+	autofillInDict       map[string]bool
+)
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Autofill completes the empty cells of the grid with words from the
+// dictionary, merged with the user's words if they have been set with
+// SetUserWords, using a backtracking search that fills the most
+// constrained word first.
+//
+// Letters already in the grid are never changed, every word that is
+// completed must be in the dictionary with at least the minimum score
+// (see SetMinScore), and no answer may appear in the grid twice.
+// Higher-scoring words are tried first.  Words containing rebus cells
+// are ignored.  If a fill is found, each word that was changed is set
+// with SetText, in one group so that the whole fill can be undone at
+// once, and the list of those words is returned.  Otherwise, the puzzle
+// is left unchanged and one of ErrAutofillCanceled, ErrAutofillTimeout,
+// or ErrAutofillNoSolution is returned.
+func (puzzle *Puzzle) Autofill(options AutofillOptions) ([]*Word, error) {
+	af := newAutofiller(puzzle, options)
+
+	// Words already complete in the grid count as used answers
+	for _, slot := range af.slots {
+		if text := af.text(slot); !strings.Contains(text, " ") {
+			af.used[text] = true
+		}
+	}
+
+	if !af.solve() {
+		if af.err != nil {
+			return nil, af.err
+		}
+		return nil, ErrAutofillNoSolution
+	}
+
+	// Find the words that were changed, then copy them back into the
+	// puzzle
+	filled := make([]*Word, 0)
+	for _, slot := range af.slots {
+		if af.text(slot) != puzzle.GetText(slot.word) {
+			filled = append(filled, slot.word)
+		}
+	}
+	puzzle.BeginGroup("autofill")
+	defer puzzle.EndGroup()
+	for _, word := range filled {
+		if err := puzzle.SetText(word, af.text(af.slotFor(word))); err != nil {
+			return nil, err
+		}
+	}
+	return filled, nil
+}
+
+// ---------------------------------------------------------------------
+// Internal functions and methods
+// ---------------------------------------------------------------------
+
+// newAutofiller creates the search state for this puzzle.
+func newAutofiller(puzzle *Puzzle, options AutofillOptions) *autofiller {
+	af := new(autofiller)
+	af.grid = make(map[Point]byte)
+	af.crossers = make(map[Point][]int)
+	af.used = make(map[string]bool)
+	af.byLength, af.inDict = puzzle.userWords.getAutofillIndex()
+	af.stop = options.Stop
+	if options.TimeLimit > 0 {
+		af.deadline = time.Now().Add(options.TimeLimit)
+	}
+
+	for point := range puzzle.PointIterator() {
+		if puzzle.IsBlackCell(point) {
+			continue
+		}
+		letter := strings.ToUpper(puzzle.GetLetter(point))
+		af.grid[point] = letter[0]
+	}
+
+	region := make(map[Point]bool)
+	for _, point := range options.Region {
+		region[point] = true
+	}
+
+	for _, word := range puzzle.words {
+
+		// A single cell between black cells is not a real entry, and
+		// words with rebus cells are left as they are.
+		if word.length < 2 || puzzle.hasRebus(word) {
+			continue
+		}
+
+		slot := new(fillSlot)
+		slot.word = word
+		slot.fillable = len(region) == 0
+		for point := range puzzle.WordIterator(word.point, word.direction) {
+			slot.points = append(slot.points, point)
+			if region[point] {
+				slot.fillable = true
+			}
+		}
+
+		// Words outside the region that cannot be completed even now
+		// are not allowed to block the search.
+		slot.checked = slot.fillable || af.hasCandidate(slot)
+
+		index := len(af.slots)
+		af.slots = append(af.slots, slot)
+		for _, point := range slot.points {
+			af.crossers[point] = append(af.crossers[point], index)
+		}
+	}
+
+	return af
+}
+
+// getAutofillIndex returns the dictionary words at or above the
+// minimum score, grouped by length (best first), and as a set.  These
+// are rebuilt only when the dictionary or minimum score changes.
+func getAutofillIndex() (map[int][]string, map[string]bool) {
+	autofillIndexMutex.Lock()
+	defer autofillIndexMutex.Unlock()
+
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	if autofillIndexVersion != dictionaryVersion {
+		autofillByLength = make(map[int][]string)
+		autofillInDict = make(map[string]bool)
+		for _, sw := range dictionary {
+			if sw.Score < dictionaryMinimum {
+				break
+			}
+			word := sw.Word
+			autofillByLength[len(word)] = append(autofillByLength[len(word)], word)
+			autofillInDict[word] = true
+		}
+		autofillIndexVersion = dictionaryVersion
+	}
+	return autofillByLength, autofillInDict
+}
+
+// candidates returns the dictionary words that fit the current letters
+// of the slot and are not already used in the grid.
+func (af *autofiller) candidates(slot *fillSlot) []string {
+	pattern := af.text(slot)
+	matches := make([]string, 0)
+	for _, word := range af.byLength[len(pattern)] {
+		if fitsPattern(word, pattern) && !af.used[word] {
+			matches = append(matches, word)
+		}
+	}
+	return matches
+}
+
+// hasCandidate returns true if at least one unused dictionary word
+// fits the current letters of the slot.
+func (af *autofiller) hasCandidate(slot *fillSlot) bool {
+	pattern := af.text(slot)
+	for _, word := range af.byLength[len(pattern)] {
+		if fitsPattern(word, pattern) && !af.used[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// interrupted returns true if the search has been canceled or has run
+// out of time, and records the reason in af.err.
+func (af *autofiller) interrupted() bool {
+	if af.err != nil {
+		return true
+	}
+	af.steps++
+	if af.steps%autofillCheckInterval != 1 {
+		return false
+	}
+	select {
+	case <-af.stop:
+		af.err = ErrAutofillCanceled
+		return true
+	default:
+	}
+	if !af.deadline.IsZero() && time.Now().After(af.deadline) {
+		af.err = ErrAutofillTimeout
+		return true
+	}
+	return false
+}
+
+// place writes the word into the slot and checks that every crossing
+// word can still be completed. Crossing words that become complete as a
+// result must be unused dictionary words.  Returns the points that were
+// changed and the answers that were marked as used, so that the
+// placement can be undone, along with whether the placement is viable.
+func (af *autofiller) place(slot *fillSlot, word string) ([]Point, []string, bool) {
+	changed := make([]Point, 0)
+	added := []string{word}
+	af.used[word] = true
+
+	for i, point := range slot.points {
+		if af.grid[point] == ' ' {
+			af.grid[point] = word[i]
+			changed = append(changed, point)
+		}
+	}
+
+	viable := true
+	for _, point := range changed {
+		for _, index := range af.crossers[point] {
+			crosser := af.slots[index]
+			if crosser == slot || !crosser.checked {
+				continue
+			}
+			text := af.text(crosser)
+			if !strings.Contains(text, " ") {
+				if !af.inDict[text] || af.used[text] {
+					viable = false
+					break
+				}
+				af.used[text] = true
+				added = append(added, text)
+			} else if !af.hasCandidate(crosser) {
+				viable = false
+				break
+			}
+		}
+		if !viable {
+			break
+		}
+	}
+
+	return changed, added, viable
+}
+
+// solve runs the backtracking search, returning true if every fillable
+// slot has been completed.
+func (af *autofiller) solve() bool {
+	if af.interrupted() {
+		return false
+	}
+
+	// Choose the incomplete slot with the fewest candidates
+	var best *fillSlot
+	var bestCandidates []string
+	for _, slot := range af.slots {
+		if !slot.fillable || !strings.Contains(af.text(slot), " ") {
+			continue
+		}
+		candidates := af.candidates(slot)
+		if len(candidates) == 0 {
+			return false
+		}
+		if best == nil || len(candidates) < len(bestCandidates) {
+			best = slot
+			bestCandidates = candidates
+		}
+	}
+	if best == nil {
+		return true
+	}
+
+	for _, word := range bestCandidates {
+		if af.used[word] {
+			continue
+		}
+		changed, added, viable := af.place(best, word)
+		if viable && af.solve() {
+			return true
+		}
+		for _, point := range changed {
+			af.grid[point] = ' '
+		}
+		for _, answer := range added {
+			delete(af.used, answer)
+		}
+		if af.err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// hasRebus returns true if any cell of the word is a rebus cell.
+func (puzzle *Puzzle) hasRebus(word *Word) bool {
+	for _, text := range puzzle.GetCellTexts(word) {
+		if len(text) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// slotFor returns the slot for the specified word.
+func (af *autofiller) slotFor(word *Word) *fillSlot {
+	for _, slot := range af.slots {
+		if slot.word == word {
+			return slot
+		}
+	}
+	return nil
+}
+
+// text returns the current letters of the slot, with ' ' for empty
+// cells.
+func (af *autofiller) text(slot *fillSlot) string {
+	sb := strings.Builder{}
+	for _, point := range slot.points {
+		sb.WriteByte(af.grid[point])
+	}
+	return sb.String()
+}
+
+// fitsPattern returns true if the word has the same length as the
+// pattern and agrees with it in every non-blank position.
+func fitsPattern(word, pattern string) bool {
+	if len(word) != len(pattern) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ' ' && pattern[i] != word[i] {
+			return false
+		}
+	}
+	return true
+}
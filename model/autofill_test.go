@@ -0,0 +1,157 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertValidFill checks that every word in the grid is complete, in
+// the dictionary, and not repeated.
+func assertValidFill(t *testing.T, puzzle *Puzzle) {
+	seen := make(map[string]bool)
+	for _, word := range puzzle.words {
+		if word.length < 2 {
+			continue
+		}
+		text := puzzle.GetText(word)
+		assert.False(t, strings.Contains(text, " "), "incomplete word %q", text)
+		assert.True(t, autofillInDict[text], "%q is not in the dictionary", text)
+		assert.False(t, seen[text], "%q is used twice", text)
+		seen[text] = true
+	}
+}
+
+func TestPuzzle_Autofill(t *testing.T) {
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+
+	filled, err := puzzle.Autofill(AutofillOptions{TimeLimit: 30 * time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(filled))
+	assertValidFill(t, puzzle)
+
+	// The whole fill is undone at once
+	undo, _ := puzzle.GetHistory()
+	assert.Equal(t, 1, len(undo))
+	assert.Equal(t, GROUP_COMMAND, undo[0].Kind)
+	puzzle.Undo()
+	for _, word := range puzzle.words {
+		assert.Equal(t, "   ", puzzle.GetText(word))
+	}
+}
+
+func TestPuzzle_Autofill_KeepsLetters(t *testing.T) {
+	puzzle := NewPuzzle(4)
+	puzzle.Toggle(NewPoint(1, 1))
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+	puzzle.SetText(word, "CAT")
+
+	_, err := puzzle.Autofill(AutofillOptions{TimeLimit: 30 * time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, "CAT", puzzle.GetText(word))
+	assertValidFill(t, puzzle)
+}
+
+func TestPuzzle_Autofill_Region(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	for _, point := range []Point{{1, 4}, {2, 4}} {
+		puzzle.Toggle(point)
+	}
+	puzzle.RenumberCells()
+
+	// Only fill the words through the top left corner
+	_, err := puzzle.Autofill(AutofillOptions{
+		Region:    []Point{NewPoint(1, 1)},
+		TimeLimit: 30 * time.Second,
+	})
+	assert.Nil(t, err)
+
+	across := puzzle.LookupWord(NewPoint(1, 1), ACROSS)
+	down := puzzle.LookupWord(NewPoint(1, 1), DOWN)
+	assert.False(t, strings.Contains(puzzle.GetText(across), " "))
+	assert.False(t, strings.Contains(puzzle.GetText(down), " "))
+
+	// A word far from the region is untouched
+	other := puzzle.LookupWord(NewPoint(5, 5), DOWN)
+	assert.Equal(t, "     ", puzzle.GetText(other))
+}
+
+func TestPuzzle_Autofill_NoSolution(t *testing.T) {
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+	puzzle.SetText(word, "QX ")
+	before := puzzle.String()
+
+	_, err := puzzle.Autofill(AutofillOptions{})
+	assert.Equal(t, ErrAutofillNoSolution, err)
+	assert.Equal(t, before, puzzle.String())
+}
+
+func TestPuzzle_Autofill_NoDuplicates(t *testing.T) {
+	puzzle := NewPuzzle(3)
+	puzzle.Toggle(NewPoint(2, 2))
+	puzzle.RenumberCells()
+
+	// 1 across is CAT, and 1 down starts with CA, so it must not also
+	// be CAT
+	puzzle.SetText(puzzle.LookupWordByNumber(1, ACROSS), "CAT")
+	puzzle.SetText(puzzle.LookupWordByNumber(1, DOWN), "CA ")
+	puzzle.SetText(puzzle.LookupWordByNumber(4, ACROSS), "  T")
+
+	_, err := puzzle.Autofill(AutofillOptions{TimeLimit: 30 * time.Second})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "CAT", puzzle.GetText(puzzle.LookupWordByNumber(1, DOWN)))
+	assertValidFill(t, puzzle)
+}
+
+func TestPuzzle_Autofill_UserWords(t *testing.T) {
+	defer useWordList(t, "AB;90\nCD;90\nAC;90\nBD;90")()
+	runtest(func(t *testing.T) {
+		BanUserWord(TEST_USERID, "AB")
+		AddUserWord(TEST_USERID, "EB", 60)
+		AddUserWord(TEST_USERID, "EC", 60)
+		uw, _ := LoadUserWords(TEST_USERID)
+
+		tests := []struct {
+			name string
+			uw   *UserWords
+			want string
+		}{
+			{"base only", nil, "AB"},
+			{"user words", uw, "EB"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				puzzle := NewPuzzle(2)
+				puzzle.RenumberCells()
+				puzzle.SetUserWords(tt.uw)
+				_, err := puzzle.Autofill(AutofillOptions{TimeLimit: 30 * time.Second})
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, puzzle.GetText(puzzle.LookupWordByNumber(1, ACROSS)))
+			})
+		}
+	})(t)
+}
+
+func TestPuzzle_Autofill_Canceled(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.RenumberCells()
+	stop := make(chan struct{})
+	close(stop)
+
+	_, err := puzzle.Autofill(AutofillOptions{Stop: stop})
+	assert.Equal(t, ErrAutofillCanceled, err)
+}
+
+func TestPuzzle_Autofill_Timeout(t *testing.T) {
+	puzzle := NewPuzzle(15)
+	puzzle.RenumberCells()
+
+	_, err := puzzle.Autofill(AutofillOptions{TimeLimit: time.Nanosecond})
+	assert.Equal(t, ErrAutofillTimeout, err)
+}
@@ -1,5 +1,7 @@
 package model
 
+import "strings"
+
 // ---------------------------------------------------------------------
 // Type definitions
 // ---------------------------------------------------------------------
@@ -64,24 +66,6 @@ func (puzzle *Puzzle) IsBlackCell(point Point) bool {
 	}
 }
 
-// RedoBlackCell pops a point from the redo stack and toggles the black
-// cell at that point.
-func (puzzle *Puzzle) RedoBlackCell() {
-	if puzzle.redoPointStack.IsEmpty() {
-		// Nothing to redo
-		return
-	}
-
-	// Pop the point at the top of the redo stack
-	point, _ := puzzle.redoPointStack.Pop()
-
-	// Push that point onto the undo stack
-	puzzle.undoPointStack.Push(point)
-
-	// Toggle that point and its symmetric twin.
-	puzzle.togglePoint(point)
-}
-
 // String returns a string representation of this black cell.
 func (bc BlackCell) String() string {
 	sb := bc.point.String()
@@ -89,56 +73,80 @@ func (bc BlackCell) String() string {
 }
 
 // Toggle switches a point between black cell and letter cell.
-// Does so also to the symmetric point.
+// Does so also to the symmetric point(s), according to the puzzle's
+// symmetry mode, which all become the opposite of what the point was.
+// The change is recorded in the history as a group, with the state of
+// each cell that changes and any letters and styles that are lost, so
+// that undoing it restores them.
 func (puzzle *Puzzle) Toggle(point Point) {
 	if err := puzzle.ValidIndex(point); err != nil {
 		panic(err)
 	}
-	puzzle.undoPointStack.Push(point)
-	puzzle.togglePoint(point)
+	after := BLACK_CELL_STATE
+	if puzzle.IsBlackCell(point) {
+		after = LETTER_CELL_STATE
+	}
+	puzzle.BeginGroup("toggle")
+	defer puzzle.EndGroup()
+	for _, p := range append([]Point{point}, puzzle.SymmetricPoints(point)...) {
+		before := puzzle.getCellState(p)
+		if before == after {
+			continue
+		}
+		if before == LETTER_CELL_STATE {
+			if strings.TrimSpace(puzzle.GetLetter(p)) != "" {
+				puzzle.SetLetter(p, "")
+			}
+			if style := puzzle.GetCellStyle(p); !style.IsPlain() {
+				puzzle.record(&Command{Kind: STYLE_COMMAND, Point: p, Before: style.String()})
+			}
+		}
+		puzzle.record(&Command{Kind: BLACK_CELL_COMMAND, Point: p, Before: before, After: after})
+		puzzle.setCellState(p, after)
+	}
+}
+
+// getCellState returns BLACK_CELL_STATE or LETTER_CELL_STATE for the
+// cell at the specified point.
+func (puzzle *Puzzle) getCellState(point Point) string {
+	if puzzle.IsBlackCell(point) {
+		return BLACK_CELL_STATE
+	}
+	return LETTER_CELL_STATE
+}
+
+// setCellState is an internal method that makes the cell at the
+// specified point a black cell or an empty letter cell, without
+// recording the change in the history.
+func (puzzle *Puzzle) setCellState(point Point, state string) {
+	if puzzle.getCellState(point) == state {
+		return
+	}
+	if state == BLACK_CELL_STATE {
+		puzzle.SetCell(point, NewBlackCell(point))
+	} else {
+		puzzle.SetCell(point, NewLetterCell(point))
+	}
 }
 
 // togglePoint is an internal method that changes the specified cell
-// from a letter cell to a black cell or vice versa.  Separated here
-// because we need to handle the undo/redo stacks differently in
-// different cases.
+// and its symmetric twins from a letter cell to a black cell or vice
+// versa.  It is only used to undo and redo black cell commands in
+// histories saved before the state of each cell was recorded.
 func (puzzle *Puzzle) togglePoint(point Point) {
 	cell := puzzle.GetCell(point)
+	points := append([]Point{point}, puzzle.SymmetricPoints(point)...)
 
 	switch cell.(type) {
 
 	case BlackCell:
-		cell = NewLetterCell(point)
-		puzzle.SetCell(point, cell)
-		symPoint := puzzle.SymmetricPoint(point)
-		symCell := NewLetterCell(symPoint)
-		puzzle.SetCell(symPoint, symCell)
+		for _, p := range points {
+			puzzle.SetCell(p, NewLetterCell(p))
+		}
 
 	case LetterCell:
-		cell = NewBlackCell(point)
-		puzzle.SetCell(point, cell)
-		symPoint := puzzle.SymmetricPoint(point)
-		symCell := NewBlackCell(symPoint)
-		puzzle.SetCell(symPoint, symCell)
-	}
-}
-
-// UndoBlackCell pops a point from the undo stack and toggles the black
-// cell at that point.
-func (puzzle *Puzzle) UndoBlackCell() {
-	if puzzle.undoPointStack.IsEmpty() {
-		// Nothing to undo
-		return
+		for _, p := range points {
+			puzzle.SetCell(p, NewBlackCell(p))
+		}
 	}
-
-	// Pop the point at the top of the undo stack
-	point, _ := puzzle.undoPointStack.Pop()
-
-	// Push that point onto the redo stack
-	puzzle.redoPointStack.Push(point)
-
-	// Toggle that point and its symmetric twin.  Note that this is the
-	// same as the Toggle() method except that it doesn't push the
-	// action onto the undo stack.
-	puzzle.togglePoint(point)
 }
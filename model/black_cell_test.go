@@ -63,25 +63,23 @@ func TestBlackCell_GetPoint(t *testing.T) {
 func TestPuzzle_RedoBlackCell(t *testing.T) {
 	puzzle := NewPuzzle(9)
 
-	// Redo should be a nop if the stack is empty
+	// Redo should be a nop if the history is empty
 	assert.Equal(t, 0, puzzle.CountBlackCells())
-	assert.Equal(t, 0, puzzle.undoPointStack.Len())
-	assert.Equal(t, 0, puzzle.redoPointStack.Len())
-	puzzle.RedoBlackCell()
+	assert.False(t, puzzle.CanRedo())
+	assert.Nil(t, puzzle.Redo())
 	assert.Equal(t, 0, puzzle.CountBlackCells())
-	assert.Equal(t, 0, puzzle.undoPointStack.Len())
-	assert.Equal(t, 0, puzzle.redoPointStack.Len())
+	assert.False(t, puzzle.CanUndo())
 
 	// Add a black cell and then undo it
 	puzzle.Toggle(NewPoint(1, 1))
-	puzzle.UndoBlackCell()
+	puzzle.Undo()
 
 	// Should be zero cells
 	beforeCount := puzzle.CountBlackCells()
 	assert.Equal(t, 0, beforeCount)
 
 	// Now redo the add black cell
-	puzzle.RedoBlackCell()
+	puzzle.Redo()
 
 	// Should be two black cells (symmetric twin, too)
 	afterCount := puzzle.CountBlackCells()
@@ -138,19 +136,71 @@ func TestPuzzle_Toggle_Bad(t *testing.T) {
 func TestPuzzle_UndoBlackCell(t *testing.T) {
 	puzzle := NewPuzzle(9)
 
-	// Undo should be a nop if the stack is empty
+	// Undo should be a nop if the history is empty
 	assert.Equal(t, 0, puzzle.CountBlackCells())
-	assert.Equal(t, 0, puzzle.undoPointStack.Len())
-	assert.Equal(t, 0, puzzle.redoPointStack.Len())
-	puzzle.UndoBlackCell()
+	assert.False(t, puzzle.CanUndo())
+	assert.Nil(t, puzzle.Undo())
 	assert.Equal(t, 0, puzzle.CountBlackCells())
-	assert.Equal(t, 0, puzzle.undoPointStack.Len())
-	assert.Equal(t, 0, puzzle.redoPointStack.Len())
+	assert.False(t, puzzle.CanRedo())
 
 	puzzle.Toggle(NewPoint(1, 1))
 	beforeCount := puzzle.CountBlackCells()
 	assert.Equal(t, 2, beforeCount)
-	puzzle.UndoBlackCell()
+	puzzle.Undo()
 	afterCount := puzzle.CountBlackCells()
 	assert.Equal(t, 0, afterCount)
 }
+
+func TestPuzzle_UndoBlackCell_MixedTwins(t *testing.T) {
+	puzzle := NewPuzzle(5)
+
+	// Toggling a letter cell whose twin is already black leaves the twin
+	// black, and undoing it leaves the twin black too
+	puzzle.SetSymmetry(NO_SYMMETRY)
+	puzzle.Toggle(NewPoint(1, 1))
+	puzzle.SetSymmetry(ROTATIONAL)
+	puzzle.Toggle(NewPoint(5, 5))
+	assert.True(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.True(t, puzzle.IsBlackCell(NewPoint(5, 5)))
+
+	puzzle.Undo()
+	assert.True(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.False(t, puzzle.IsBlackCell(NewPoint(5, 5)))
+
+	puzzle.Redo()
+	assert.True(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.True(t, puzzle.IsBlackCell(NewPoint(5, 5)))
+
+	// Toggling it back makes both letter cells, and undoing that makes
+	// both black again
+	puzzle.Toggle(NewPoint(1, 1))
+	assert.Equal(t, 0, puzzle.CountBlackCells())
+	puzzle.Undo()
+	assert.True(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.True(t, puzzle.IsBlackCell(NewPoint(5, 5)))
+}
+
+func TestPuzzle_UndoBlackCell_Style(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	point := NewPoint(1, 1)
+	twin := NewPoint(5, 5)
+	style := CellStyle{Circled: true, Shade: "gray", Bars: "RB"}
+	assert.Nil(t, puzzle.SetCellStyle(point, style))
+	puzzle.SetLetter(point, "A")
+	puzzle.SetLetter(twin, "Z")
+
+	// The letters and style lost to the black cells come back with them
+	puzzle.Toggle(point)
+	assert.True(t, puzzle.GetCellStyle(point).IsPlain())
+	puzzle.Undo()
+	assert.Equal(t, style, puzzle.GetCellStyle(point))
+	assert.Equal(t, "A", puzzle.GetLetter(point))
+	assert.Equal(t, "Z", puzzle.GetLetter(twin))
+
+	// And are lost again when it is redone
+	puzzle.Redo()
+	assert.True(t, puzzle.IsBlackCell(point))
+	puzzle.Toggle(point)
+	assert.True(t, puzzle.GetCellStyle(point).IsPlain())
+	assert.Equal(t, " ", puzzle.GetLetter(point))
+}
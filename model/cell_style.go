@@ -0,0 +1,179 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// CellStyle contains the optional style attributes of a letter cell,
+// which are used to mark theme entries:
+//   - Whether the cell is circled
+//   - The color with which the cell is shaded, e.g., "gray" or
+//     "#C0C0C0", or "" if it is not shaded
+//   - The sides of the cell on which a heavy bar is drawn, as in a
+//     barred grid
+type CellStyle struct {
+	Circled bool   `json:"circled,omitempty"`
+	Shade   string `json:"shade,omitempty"`
+	Bars    Bars   `json:"bars,omitempty"`
+}
+
+// Bars is a set of sides of a cell, written as a string of the letters
+// T, R, B, and L (for top, right, bottom, and left) in that order.
+type Bars string
+
+// StyleImporter is an optional extension of the Importer interface for
+// sources that support cell styles.
+type StyleImporter interface {
+	Importer
+
+	// Returns the style of the cell at a given point in the grid
+	// (relative to 1, not 0).
+	GetCellStyle(r, c int) CellStyle
+}
+
+// CircleImporter is an optional extension of the Importer interface for
+// sources that support circled cells, but not other styles.
+type CircleImporter interface {
+	Importer
+
+	// Returns true if the cell at a given point in the grid (relative to
+	// 1, not 0) is circled.
+	IsCircled(r, c int) bool
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// The sides of a cell on which a bar can be drawn
+const (
+	BAR_TOP    = 'T'
+	BAR_RIGHT  = 'R'
+	BAR_BOTTOM = 'B'
+	BAR_LEFT   = 'L'
+)
+
+// All the sides, in canonical order
+const ALL_BARS = Bars("TRBL")
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// ParseBars creates a set of bars from a string of side letters in any
+// order and case.  An error is returned if the string contains anything
+// other than T, R, B, and L.
+func ParseBars(s string) (Bars, error) {
+	s = strings.ToUpper(s)
+	for _, side := range s {
+		if !strings.ContainsRune(string(ALL_BARS), side) {
+			return "", fmt.Errorf("invalid bar side %q in %q", side, s)
+		}
+	}
+	sb := strings.Builder{}
+	for _, side := range ALL_BARS {
+		if strings.ContainsRune(s, side) {
+			sb.WriteRune(side)
+		}
+	}
+	return Bars(sb.String()), nil
+}
+
+// parseCellStyle creates a style from its string form (see
+// CellStyle.String).  A string that cannot be parsed is a plain style.
+func parseCellStyle(s string) CellStyle {
+	style := CellStyle{}
+	if s != "" {
+		json.Unmarshal([]byte(s), &style)
+	}
+	return style
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Has returns true if there is a bar on the specified side.
+func (bars Bars) Has(side rune) bool {
+	return strings.ContainsRune(string(bars), side)
+}
+
+// String returns the style as JSON, e.g., {"circled":true}, or "" if
+// it is plain.  This is how styles are recorded in the history.
+func (style CellStyle) String() string {
+	if style.IsPlain() {
+		return ""
+	}
+	jsonBlob, _ := json.Marshal(style)
+	return string(jsonBlob)
+}
+
+// IsPlain returns true if the style has no attributes set.
+func (style CellStyle) IsPlain() bool {
+	return style == CellStyle{}
+}
+
+// GetCellStyle returns the style of the letter cell at this point.  A
+// black cell (or a point not in the grid) has no style.
+func (puzzle *Puzzle) GetCellStyle(point Point) CellStyle {
+	if puzzle.ValidIndex(point) != nil {
+		return CellStyle{}
+	}
+	cell, ok := puzzle.GetCell(point).(LetterCell)
+	if !ok {
+		return CellStyle{}
+	}
+	return cell.style
+}
+
+// SetCellStyle sets the style of the letter cell at this point.  An
+// error is returned if the point is not in the grid, if it is a black
+// cell, or if the bars are invalid.
+func (puzzle *Puzzle) SetCellStyle(point Point, style CellStyle) error {
+	if err := puzzle.ValidIndex(point); err != nil {
+		return err
+	}
+	cell, ok := puzzle.GetCell(point).(LetterCell)
+	if !ok {
+		return fmt.Errorf("cannot set the style of black cell %s", point.String())
+	}
+	bars, err := ParseBars(string(style.Bars))
+	if err != nil {
+		return err
+	}
+	style.Bars = bars
+	style.Shade = strings.TrimSpace(style.Shade)
+	cell.style = style
+	puzzle.SetCell(point, cell)
+	return nil
+}
+
+// SetCircled marks or unmarks the letter cell at this point as circled,
+// leaving its other style attributes unchanged.
+func (puzzle *Puzzle) SetCircled(point Point, circled bool) error {
+	style := puzzle.GetCellStyle(point)
+	style.Circled = circled
+	return puzzle.SetCellStyle(point, style)
+}
+
+// SetShade sets the shading color of the letter cell at this point, or
+// removes the shading if the color is "".
+func (puzzle *Puzzle) SetShade(point Point, color string) error {
+	style := puzzle.GetCellStyle(point)
+	style.Shade = color
+	return puzzle.SetCellStyle(point, style)
+}
+
+// SetBars sets the sides of the letter cell at this point on which a
+// bar is drawn, e.g., "RB" for the right and bottom sides.
+func (puzzle *Puzzle) SetBars(point Point, bars string) error {
+	style := puzzle.GetCellStyle(point)
+	style.Bars = Bars(bars)
+	return puzzle.SetCellStyle(point, style)
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBars(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Bars
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"one", "b", "B", false},
+		{"reordered", "LRT", "TRL", false},
+		{"duplicates", "RR", "R", false},
+		{"all", "lbrt", ALL_BARS, false},
+		{"invalid", "TX", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseBars(tt.s)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+	assert.True(t, Bars("RB").Has(BAR_BOTTOM))
+	assert.False(t, Bars("RB").Has(BAR_TOP))
+}
+
+func TestPuzzle_SetCellStyle(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	point := NewPoint(1, 2)
+
+	assert.True(t, puzzle.GetCellStyle(point).IsPlain())
+	assert.Nil(t, puzzle.SetCircled(point, true))
+	assert.Nil(t, puzzle.SetShade(point, " gray "))
+	assert.Nil(t, puzzle.SetBars(point, "br"))
+	want := CellStyle{Circled: true, Shade: "gray", Bars: "RB"}
+	assert.Equal(t, want, puzzle.GetCellStyle(point))
+
+	// Setting a letter keeps the style
+	puzzle.SetLetter(point, "N")
+	assert.Equal(t, want, puzzle.GetCellStyle(point))
+
+	// Black cells, invalid points, and invalid bars are errors
+	assert.NotNil(t, puzzle.SetCircled(NewPoint(1, 1), true))
+	assert.NotNil(t, puzzle.SetCircled(NewPoint(10, 1), true))
+	assert.NotNil(t, puzzle.SetBars(point, "X"))
+	assert.True(t, puzzle.GetCellStyle(NewPoint(1, 1)).IsPlain())
+	assert.True(t, puzzle.GetCellStyle(NewPoint(10, 1)).IsPlain())
+
+	// Clearing the attributes leaves a plain cell
+	assert.Nil(t, puzzle.SetCellStyle(point, CellStyle{}))
+	assert.True(t, puzzle.GetCellStyle(point).IsPlain())
+}
+
+func TestPuzzle_SavePuzzle_CellStyle(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "style-test"
+		puzzle := getGoodPuzzle()
+		puzzle.SetPuzzleName(puzzleName)
+		style := CellStyle{Circled: true, Shade: "#C0C0C0", Bars: "TL"}
+		assert.Nil(t, puzzle.SetCellStyle(NewPoint(2, 2), style))
+		assert.Nil(t, puzzle.SetCircled(NewPoint(9, 8), true))
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+		loaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, style, loaded.GetCellStyle(NewPoint(2, 2)))
+		assert.True(t, loaded.GetCellStyle(NewPoint(9, 8)).Circled)
+		assert.True(t, loaded.GetCellStyle(NewPoint(3, 3)).IsPlain())
+		assert.True(t, puzzle.Equal(loaded))
+	})(t)
+}
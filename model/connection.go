@@ -23,22 +23,24 @@ var ddl string
 func Connect() (*sql.DB, error) {
 	dbName := cwcomp.GetConfiguration().DATABASE.NAME
 	dataSourceName := fmt.Sprintf("file:%s?_foreign_keys=on", dbName)
-	con, _ := sql.Open("sqlite3", dataSourceName)
+	con, err := sql.Open("sqlite3", dataSourceName)
+	if err != nil {
+		return nil, err
+	}
 	return con, nil
 }
 
 // CreateDatabase creates the database, either the production one
-// or the test one, depending on Configuration.DATABASE.NAME
-func CreateDatabase() {
-
-	// Connect to the database
-	con, _ := Connect()
-
-	// Run the DDL
-	ddl := GetDDL()
-	con.Exec(ddl)
-	log.Printf("Created %v\n", cwcomp.GetConfiguration().DATABASE.NAME)
-
+// or the test one, depending on Configuration.DATABASE.NAME.  The
+// database is stamped with the latest schema version, so that no
+// migrations are applied to it (see Migrate).
+func CreateDatabase() error {
+	dbName := cwcomp.GetConfiguration().DATABASE.NAME
+	if _, err := Migrate(); err != nil {
+		return fmt.Errorf("could not create %v: %v", dbName, err)
+	}
+	log.Printf("Created %v\n", dbName)
+	return nil
 }
 
 // GetDDL returns a string containing the contents of the tables.sql file.
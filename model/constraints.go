@@ -44,6 +44,15 @@ type Constraint struct {
 	// Number of words that match that pattern
 	//
 	NChoices int `json:"nChoices"`
+	//
+	// Score of the crossing word, if it is complete and in the
+	// dictionary, or 0 otherwise
+	//
+	Score int `json:"score"`
+	//
+	// Highest score of the words that match that pattern
+	//
+	BestScore int `json:"bestScore"`
 }
 
 // ---------------------------------------------------------------------
@@ -51,7 +60,8 @@ type Constraint struct {
 // ---------------------------------------------------------------------
 
 // GetConstraints finds the constraints imposed on this word by its
-// crossing words.
+// crossing words.  The matching words come from the dictionary merged
+// with the user's words, if they have been set with SetUserWords.
 func (puzzle *Puzzle) GetConstraints(word *Word) []*Constraint {
 
 	// Create a slice to return the constraints we find for each
@@ -82,13 +92,18 @@ func (puzzle *Puzzle) GetConstraints(word *Word) []*Constraint {
 		cst.Pos = index + 1
 
 		// Set the index (1, 2, ..., ) within the crossing word at which
-		// this crossing occurs.
+		// this crossing occurs.  Because the cells before it may be
+		// rebus cells, also find the offset of its letters within the
+		// crossing word's answer.
 		crossIndex := 0
+		offset, crossOffset := 0, 0
 		for crossPoint := range puzzle.WordIterator(crosserWordNumber.point, crosser.direction) {
 			crossIndex++
 			if crossPoint == point {
 				cst.Index = crossIndex
+				crossOffset = offset
 			}
+			offset += len(puzzle.GetLetter(crossPoint))
 		}
 
 		// Get the letter at that point
@@ -105,7 +120,7 @@ func (puzzle *Puzzle) GetConstraints(word *Word) []*Constraint {
 
 		// Get the pattern for a regular expression for the possible
 		// choices of the crossing word.
-		pattern := cst.Text
+		pattern := puzzle.GetAnswer(crosser)
 		re := regexp.MustCompile(` `)
 		pattern = re.ReplaceAllLiteralString(pattern, ".")
 
@@ -114,21 +129,33 @@ func (puzzle *Puzzle) GetConstraints(word *Word) []*Constraint {
 		// matching words at the crossing point in a set (from which we
 		// will figure out a regular expression)
 		cst.NChoices = 0
-		letterSet := make(map[byte]bool)
-		for matcher := range GetMatchingWords(pattern, make(chan struct{})) {
+		letterSet := make(map[string]bool)
+		for matcher := range puzzle.userWords.GetScoredMatches(pattern, make(chan struct{})) {
+			if cst.NChoices == 0 {
+				// Matches come best first
+				cst.BestScore = matcher.Score
+			}
 			cst.NChoices++
-			letter := matcher[cst.Index-1]
+			letter := matcher.Word[crossOffset : crossOffset+len(cst.Letter)]
 			letterSet[letter] = true
 		}
+		cst.Score, _ = puzzle.userWords.GetScore(puzzle.GetAnswer(crosser))
 
 		// Now take all the letters in the set and make a regular expression
-		// that describes every one.
-		letterList := make([]byte, 0)
-		for letter := range letterSet {
-			letterList = append(letterList, letter)
+		// that describes every one.  A rebus cell is necessarily already
+		// filled in, so its pattern is just its own letters.
+		if len(cst.Letter) > 1 {
+			if len(letterSet) > 0 {
+				cst.Pattern = cst.Letter
+			}
+		} else {
+			letterList := make([]byte, 0)
+			for letter := range letterSet {
+				letterList = append(letterList, letter[0])
+			}
+			letterString := string(letterList)
+			cst.Pattern = Regexp(letterString)
 		}
-		letterString := string(letterList)
-		cst.Pattern = Regexp(letterString)
 
 		// Special case - crossing word is not in the dictionary
 		if cst.Pattern == "" {
@@ -36,7 +36,9 @@ func createTestDatabase() {
 	}
 
 	// Create the test database
-	CreateDatabase()
+	if err := CreateDatabase(); err != nil {
+		log.Fatal(err)
+	}
 
 	// Connect to the test database
 	con, _ := Connect()
@@ -125,11 +127,32 @@ func tearDown() {
 func TestPuzzle_GetPuzzleList(t *testing.T) {
 	runtest(func(*testing.T) {
 		puzzle := getGoodPuzzle()
-		puzzleNames := puzzle.GetPuzzleList(TEST_USERID)
+		puzzleNames, err := puzzle.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
 		assert.Equal(t, 0, len(puzzleNames))
 	})(t)
 }
 
+// Tests that database errors are returned rather than ignored.
+func TestPuzzle_DatabaseErrors(t *testing.T) {
+	runtest(func(t *testing.T) {
+		config := cwcomp.GetConfiguration()
+		saved := config.DATABASE.NAME
+		defer func() { config.DATABASE.NAME = saved }()
+		config.DATABASE.NAME = filepath.Join(t.TempDir(), "missing", "cwcomp.db")
+
+		puzzle := getGoodPuzzle()
+		_, err := puzzle.GetPuzzleList(TEST_USERID)
+		assert.NotNil(t, err)
+		_, err = puzzle.PuzzleNameUsed(TEST_USERID, "good9")
+		assert.NotNil(t, err)
+		_, err = GetUserWords(TEST_USERID)
+		assert.NotNil(t, err)
+		assert.NotNil(t, AddUserWord(TEST_USERID, "HEART", 10))
+		assert.NotNil(t, CreateDatabase())
+	})(t)
+}
+
 // Tests whether the specified puzzle name is already used.
 func TestPuzzle_PuzzleNameUsed(t *testing.T) {
 	runtest(func(*testing.T) {
@@ -140,21 +163,25 @@ func TestPuzzle_PuzzleNameUsed(t *testing.T) {
 		)
 		puzzle := getGoodPuzzle()
 
-		puzzleNames = puzzle.GetPuzzleList(TEST_USERID)
+		puzzleNames, err = puzzle.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
 		assert.Equal(t, 0, len(puzzleNames))
 
 		err = puzzle.SavePuzzle(TEST_USERID)
 		assert.NotNilf(t, err, "save puzzle")
 
-		used = puzzle.PuzzleNameUsed(TEST_USERID, "good9")
+		used, err = puzzle.PuzzleNameUsed(TEST_USERID, "good9")
+		assert.Nil(t, err)
 		assert.False(t, used)
 
 		puzzle.SetPuzzleName("good9")
 		puzzle.SavePuzzle(TEST_USERID)
-		puzzleNames = puzzle.GetPuzzleList(TEST_USERID)
+		puzzleNames, err = puzzle.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
 		assert.Equal(t, 1, len(puzzleNames))
 
-		used = puzzle.PuzzleNameUsed(TEST_USERID, "good9")
+		used, err = puzzle.PuzzleNameUsed(TEST_USERID, "good9")
+		assert.Nil(t, err)
 		assert.True(t, used)
 
 	})(t)
@@ -188,6 +215,45 @@ func TestPuzzle_LoadPuzzle(t *testing.T) {
 	})(t)
 }
 
+// Tests whether a puzzle with a different number of rows and columns
+// survives a round trip through the database.
+func TestPuzzle_LoadPuzzle_Rectangular(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Sunday"
+
+		puzzle := NewRectangularPuzzle(5, 7)
+		puzzle.Toggle(NewPoint(1, 1))
+		puzzle.RenumberCells()
+		puzzle.SetPuzzleName(puzzleName)
+		err := puzzle.SavePuzzle(TEST_USERID)
+		assert.Nil(t, err)
+
+		reloadedPuzzle, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, reloadedPuzzle.GetRows())
+		assert.Equal(t, 7, reloadedPuzzle.GetCols())
+		assert.True(t, reloadedPuzzle.IsBlackCell(NewPoint(5, 7)))
+		assert.True(t, puzzle.Equal(reloadedPuzzle))
+	})(t)
+}
+
+// Tests whether the symmetry mode is saved with the puzzle.
+func TestPuzzle_LoadPuzzle_Symmetry(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Mirror"
+
+		puzzle := getGoodPuzzle()
+		puzzle.SetSymmetry(LEFT_RIGHT)
+		puzzle.SetPuzzleName(puzzleName)
+		err := puzzle.SavePuzzle(TEST_USERID)
+		assert.Nil(t, err)
+
+		reloadedPuzzle, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, LEFT_RIGHT, reloadedPuzzle.GetSymmetry())
+	})(t)
+}
+
 func TestPuzzle_RenamePuzzle(t *testing.T) {
 	runtest(func(*testing.T) {
 		puzzle := getGoodPuzzle()
@@ -1,5 +1,10 @@
 PRAGMA foreign_keys=OFF;
 BEGIN TRANSACTION;
+CREATE TABLE schema_version (
+    version         INTEGER PRIMARY KEY,    -- Migration version
+    description     TEXT,                   -- What the migration does
+    applied         TEXT                    -- Datetime the migration was applied
+);
 CREATE TABLE users (
     userid          INTEGER PRIMARY KEY,    -- User ID
     username        TEXT NOT NULL UNIQUE,   -- User name
@@ -29,30 +34,102 @@ INSERT INTO users VALUES(
     '27615'
     );
 
+CREATE TABLE sessions (
+    id              TEXT PRIMARY KEY,       -- Session ID
+    userid          INTEGER NOT NULL,       -- User who is logged in
+    username        TEXT,                   -- User name
+    expires         TEXT,                   -- Datetime the session expires (UTC)
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
+);
+
+CREATE TABLE user_words (
+    userid          INTEGER NOT NULL,       -- User who owns the entry
+    word            TEXT NOT NULL,          -- Word (upper case, A-Z only)
+    score           INTEGER,                -- Score, replacing any in the base list
+    banned          INTEGER DEFAULT 0,      -- 1 if the word must not be used
+    PRIMARY KEY (userid, word),
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
+);
+
 CREATE TABLE puzzles (
     id              INTEGER PRIMARY KEY,    -- Puzzle ID
     userid          INTEGER NOT NULL,       -- User who owns the puzzle
-    puzzlename      TEXT NOT NULL UNIQUE,   -- Puzzle name
+    puzzlename      TEXT NOT NULL,          -- Puzzle name (unique for each user)
     created         TEXT,                   -- Datetime when created
     modified        TEXT,                   -- Datetime last modified
-    n               INTEGER                 -- Puzzle size (width and height)
+    nrows           INTEGER,                -- Number of rows (height)
+    ncols           INTEGER,                -- Number of columns (width)
+    symmetry        TEXT,                   -- Black cell symmetry mode
+    UNIQUE (userid, puzzlename)
+);
+CREATE TABLE puzzle_shares (
+    id              INTEGER NOT NULL,       -- Puzzle ID
+    userid          INTEGER NOT NULL,       -- User the puzzle is shared with
+    role            TEXT NOT NULL,          -- What the user can do ("view" or "edit")
+    PRIMARY KEY (id, userid),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE,
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
 );
 CREATE TABLE cells (
     id              INTEGER,                -- Puzzle ID
-    r               INTEGER,                -- Row number (1, 2, ..., n)
-    c               INTEGER,                -- Column number (1, 2, ..., n)
+    r               INTEGER,                -- Row number (1, 2, ..., nrows)
+    c               INTEGER,                -- Column number (1, 2, ..., ncols)
     letter          TEXT,                   -- Cell value character
+    circled         INTEGER DEFAULT 0,      -- 1 if the cell is circled
+    shade           TEXT DEFAULT '',        -- Shading color, if any
+    bars            TEXT DEFAULT '',        -- Sides with bars (T, R, B, L)
     PRIMARY KEY (id, r, c),
     FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
 );
 CREATE TABLE words (
     id              INTEGER,                -- Puzzle ID
-    r               INTEGER,                -- Row number (1, 2, ..., n)
-    c               INTEGER,                -- Column number (1, 2, ..., n)
+    r               INTEGER,                -- Row number (1, 2, ..., nrows)
+    c               INTEGER,                -- Column number (1, 2, ..., ncols)
     dir             TEXT,                   -- Direction (Across="A",Down="D")
     length          INTEGER,                -- Length of word
     clue            TEXT,                   -- Text of clue
     PRIMARY KEY (id, r, c, dir),
     FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
 );
+CREATE TABLE revisions (
+    id              INTEGER,                -- Puzzle ID
+    revision        INTEGER,                -- Revision number (1, 2, ...)
+    saved           TEXT,                   -- Datetime this revision was saved
+    nrows           INTEGER,                -- Number of rows (height)
+    ncols           INTEGER,                -- Number of columns (width)
+    symmetry        TEXT,                   -- Black cell symmetry mode
+    PRIMARY KEY (id, revision),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+);
+CREATE TABLE revision_cells (
+    id              INTEGER,                -- Puzzle ID
+    revision        INTEGER,                -- Revision number
+    r               INTEGER,                -- Row number (1, 2, ..., nrows)
+    c               INTEGER,                -- Column number (1, 2, ..., ncols)
+    letter          TEXT,                   -- Cell value character
+    circled         INTEGER DEFAULT 0,      -- 1 if the cell is circled
+    shade           TEXT DEFAULT '',        -- Shading color, if any
+    bars            TEXT DEFAULT '',        -- Sides with bars (T, R, B, L)
+    PRIMARY KEY (id, revision, r, c),
+    FOREIGN KEY (id, revision) REFERENCES revisions (id, revision) ON DELETE CASCADE
+);
+CREATE TABLE revision_words (
+    id              INTEGER,                -- Puzzle ID
+    revision        INTEGER,                -- Revision number
+    r               INTEGER,                -- Row number of starting point
+    c               INTEGER,                -- Column number of starting point
+    dir             TEXT,                   -- Direction (A or D)
+    length          INTEGER,                -- Length of the word
+    clue            TEXT,                   -- Text of the clue
+    PRIMARY KEY (id, revision, r, c, dir),
+    FOREIGN KEY (id, revision) REFERENCES revisions (id, revision) ON DELETE CASCADE
+);
+CREATE TABLE history (
+    id              INTEGER,                -- Puzzle ID
+    seq             INTEGER,                -- Position in the history (0, 1, ...)
+    command         TEXT,                   -- Change, as JSON
+    undone          INTEGER DEFAULT 0,      -- 1 if the change was undone (can be redone)
+    PRIMARY KEY (id, seq),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+);
 COMMIT;
\ No newline at end of file
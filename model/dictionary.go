@@ -3,27 +3,58 @@ package model
 import (
 	"bufio"
 	_ "embed"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/philhanna/cwcomp"
 )
 
 // ---------------------------------------------------------------------
 // Type definitions
 // ---------------------------------------------------------------------
 
-// Dictionary is the in-memory word list.  There is an entry in this map
-// for each word encountered in the words.txt file.
-type Dictionary []string
+// ScoredWord is a word in the dictionary with its quality score.  A
+// higher score means better fill.
+type ScoredWord struct {
+	Word  string `json:"word"`
+	Score int    `json:"score"`
+}
+
+// Dictionary is the in-memory word list, sorted by descending score and
+// then alphabetically, so that the best fill comes first.
+type Dictionary []ScoredWord
 
 // ---------------------------------------------------------------------
-// Global variables
+// Constants and variables
 // ---------------------------------------------------------------------
 
-var dictionary = make(Dictionary, 0)
+// Words in a word list without a score are given this one.  Scores are
+// usually in the range 0 to 100.
+const DEFAULT_SCORE = 50
+
+// In a word list, a score is separated from its word by this
+// character, as in "HEART;60"
+const SCORE_SEPARATOR = ";"
 
-// words is the embedded contents of the raw words.txt file
+var (
+	dictionary        = make(Dictionary, 0)
+	dictionaryScores  = make(map[string]int)
+	dictionaryMinimum = 0
+	dictionaryIndex   = NewWordIndex(dictionary)
+	dictionaryVersion = 0
+	dictionaryMutex   sync.RWMutex
+)
+
+// words is the embedded contents of the raw words.txt file, which lists
+// one word per line without scores
 //
 //go:embed words.txt
 var words string
@@ -32,42 +63,238 @@ var words string
 // Functions
 // ---------------------------------------------------------------------
 
-// LoadDictionary loads the dictionary at startup.  I was going to get fancy and
-// use a goroutine to do this, but it takes only less than a second on
-// my Linux machine (as opposed to 5-6 seconds in the Python version!)
-func LoadDictionary() {
+// LoadDictionary loads the word list named in the configuration, if
+// there is one, in place of the embedded words.txt that is loaded when
+// the package is initialized, and sets the minimum score from the
+// configuration.  It takes less than a second on my Linux machine (as
+// opposed to 5-6 seconds in the Python version!)
+//
+// The embedded words.txt has no scores, so every word in it has
+// DEFAULT_SCORE, and "best first" is just alphabetical order.  To rank
+// the matches, configure a word list in WORD;score format.
+func LoadDictionary() error {
+	config := cwcomp.GetConfiguration()
+	if config == nil {
+		return nil
+	}
+	if config.DICTIONARY.FILE != "" {
+		stime := time.Now()
+		fp, err := os.Open(config.DICTIONARY.FILE)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		if err := LoadWordList(fp); err != nil {
+			return fmt.Errorf("%s: %v", config.DICTIONARY.FILE, err)
+		}
+		log.Printf("Dictionary loaded from %s with %d words in %v\n",
+			config.DICTIONARY.FILE, len(dictionary), time.Since(stime))
+	}
+	SetMinScore(config.DICTIONARY.MINSCORE)
+	return nil
+}
+
+// loadEmbeddedDictionary loads the embedded words.txt, which has no
+// scores.
+func loadEmbeddedDictionary() {
 	stime := time.Now()
-	count := 0
-	reader := strings.NewReader(words)
+	if err := LoadWordList(strings.NewReader(words)); err != nil {
+		// Cannot happen unless words.txt is changed to have a bad score
+		log.Printf("Could not load the embedded dictionary: %v\n", err)
+		return
+	}
+	log.Printf("Dictionary loaded with %d words in %v\n", len(dictionary), time.Since(stime))
+}
+
+// LoadWordList replaces the dictionary with the words read from a word
+// list.  Each line has the form WORD;score, or just WORD for the
+// default score.  Words are converted to upper case and anything other
+// than the letters A-Z is removed, so "New York;55" becomes NEWYORK.
+// Blank lines and lines starting with "#" are ignored.  If a word is
+// listed more than once, its highest score is used.
+func LoadWordList(reader io.Reader) error {
+	scores, err := ParseWordList(reader)
+	if err != nil {
+		return err
+	}
+	newDictionary := make(Dictionary, 0, len(scores))
+	for word, score := range scores {
+		newDictionary = append(newDictionary, ScoredWord{word, score})
+	}
+	sort.Slice(newDictionary, func(i, j int) bool {
+		return newDictionary[i].ranksBefore(newDictionary[j])
+	})
+	newIndex := NewWordIndex(newDictionary)
+
+	dictionaryMutex.Lock()
+	defer dictionaryMutex.Unlock()
+	dictionary = newDictionary
+	dictionaryIndex = newIndex
+	dictionaryScores = scores
+	dictionaryVersion++
+	return nil
+}
+
+// ParseWordList reads a word list in the format described in
+// LoadWordList and returns a map of words to scores.  An error is
+// returned if a score is not an integer.
+func ParseWordList(reader io.Reader) (map[string]int, error) {
+	scores := make(map[string]int)
 	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
 	for scanner.Scan() {
-		line := scanner.Text()
-		dictionary = append(dictionary, line)
-		count++
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		text, scoreString, found := strings.Cut(line, SCORE_SEPARATOR)
+		score := DEFAULT_SCORE
+		if found {
+			var err error
+			score, err = strconv.Atoi(strings.TrimSpace(scoreString))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid score %q", lineNumber, scoreString)
+			}
+		}
+		word := normalizeWord(text)
+		if word == "" {
+			continue
+		}
+		if oldScore, ok := scores[word]; !ok || score > oldScore {
+			scores[word] = score
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
-	duration := time.Since(stime)
-	log.Printf("Dictionary loaded with %d words in %v\n", count, duration)
+	return scores, nil
 }
 
-// GetMatchingWords returns a slice of words that match the regular
-// expression pattern.
-func GetMatchingWords(pattern string, stop <-chan struct{}) <-chan string {
-	pattern = "^" + pattern + "$"
-	re, _ := regexp.Compile(pattern)
+// GetMinScore returns the minimum score of words that are matched.
+func GetMinScore() int {
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	return dictionaryMinimum
+}
+
+// SetMinScore sets the minimum score of words that are matched by
+// GetMatchingWords and used by Autofill.  Words with lower scores are
+// still in the dictionary, but are ignored.
+func SetMinScore(minScore int) {
+	dictionaryMutex.Lock()
+	defer dictionaryMutex.Unlock()
+	if minScore != dictionaryMinimum {
+		dictionaryMinimum = minScore
+		dictionaryVersion++
+	}
+}
+
+// GetScore returns the score of a word, and whether it is in the
+// dictionary at all (regardless of the minimum score).
+func GetScore(word string) (int, bool) {
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	score, ok := dictionaryScores[word]
+	return score, ok
+}
 
-	// Main loop - get all matching words in the dictionary
+// GetMatchingWords returns a channel of the words that match the regular
+// expression pattern, best first.  Words below the minimum score are
+// skipped.
+func GetMatchingWords(pattern string, stop <-chan struct{}) <-chan string {
 	ch := make(chan string)
 	go func() {
 		defer close(ch)
-		for _, word := range dictionary {
-			if re.MatchString(word) {
-				select {
-				case <-stop:
+		for sw := range GetScoredMatches(pattern, stop) {
+			select {
+			case <-stop:
+				return
+			case ch <- sw.Word:
+			}
+		}
+	}()
+	return ch
+}
+
+// GetScoredMatches is like GetMatchingWords, but returns each word with
+// its score.  Simple patterns of letters, "." and character classes are
+// answered from the dictionary index; anything else is matched with a
+// regular expression against every word.
+func GetScoredMatches(pattern string, stop <-chan struct{}) <-chan ScoredWord {
+	if masks, ok := parseIndexPattern(pattern); ok {
+		return matchDictionary(masks, nil, stop)
+	}
+	return matchDictionary(nil, regexpMatcher(pattern), stop)
+}
+
+// SearchWords returns a channel of the words that match a query, best
+// first.  Words below the minimum score are skipped.
+func SearchWords(query *WordQuery, stop <-chan struct{}) <-chan ScoredWord {
+	masks, _ := query.masks()
+	return matchDictionary(masks, query.Matches, stop)
+}
+
+// matchDictionary returns a channel of the dictionary words that match,
+// best first, stopping at the minimum score.  If masks is not nil, the
+// words are looked up in the index by the letters allowed at each
+// position, and otherwise every word is examined.  If match is not nil,
+// the words must also satisfy it.
+func matchDictionary(masks []letterMask, match func(string) bool, stop <-chan struct{}) <-chan ScoredWord {
+	dictionaryMutex.RLock()
+	words, index, minScore := dictionary, dictionaryIndex, dictionaryMinimum
+	dictionaryMutex.RUnlock()
+
+	ch := make(chan ScoredWord)
+	go func() {
+		defer close(ch)
+		send := func(sw ScoredWord) bool {
+			if sw.Score < minScore {
+				// The rest have lower scores, too
+				return false
+			}
+			if match != nil && !match(sw.Word) {
+				return true
+			}
+			select {
+			case <-stop:
+				return false
+			case ch <- sw:
+				return true
+			}
+		}
+		if masks != nil {
+			index.each(masks, send)
+		} else {
+			for _, sw := range words {
+				if !send(sw) {
 					return
-				case ch <- word:
 				}
 			}
 		}
 	}()
 	return ch
 }
+
+// regexpMatcher returns a function that matches words against the
+// regular expression pattern.  If the pattern is invalid, it matches
+// nothing.
+func regexpMatcher(pattern string) func(string) bool {
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return func(string) bool { return false }
+	}
+	return re.MatchString
+}
+
+// normalizeWord converts a word to upper case and removes anything
+// other than the letters A-Z.
+func normalizeWord(text string) string {
+	sb := strings.Builder{}
+	for _, ch := range strings.ToUpper(text) {
+		if ch >= 'A' && ch <= 'Z' {
+			sb.WriteRune(ch)
+		}
+	}
+	return sb.String()
+}
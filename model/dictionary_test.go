@@ -5,10 +5,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/philhanna/cwcomp"
+	"github.com/stretchr/testify/assert"
 )
 
+// useWordList replaces the dictionary with a test word list, returning
+// a function that restores the embedded one.
+func useWordList(t *testing.T, list string) func() {
+	assert.Nil(t, LoadWordList(strings.NewReader(list)))
+	return func() {
+		LoadWordList(strings.NewReader(words))
+		SetMinScore(0)
+	}
+}
+
 func TestDictionary_GetMatchingWords(t *testing.T) {
 	pattern := `[^AEIOU][AEIOU].E`
 	stime := time.Now()
@@ -35,3 +49,112 @@ func TestDictionary_GetMatchingWords(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWordList(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"scored", "HEART;60\nAAHED;10", map[string]int{"HEART": 60, "AAHED": 10}, false},
+		{"unscored", "heart", map[string]int{"HEART": DEFAULT_SCORE}, false},
+		{"normalized", "New York; 55\n\n# comment", map[string]int{"NEWYORK": 55}, false},
+		{"duplicate", "HEART;20\nheart;40\nHEART;30", map[string]int{"HEART": 40}, false},
+		{"bad score", "HEART;great", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := ParseWordList(strings.NewReader(tt.list))
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	saved := cwcomp.GetConfiguration
+	defer func() { cwcomp.GetConfiguration = saved }()
+	defer useWordList(t, words)()
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	os.WriteFile(good, []byte("HEART;60\nAAHED;10"), 0644)
+	bad := filepath.Join(dir, "bad.txt")
+	os.WriteFile(bad, []byte("HEART;great"), 0644)
+
+	tests := []struct {
+		name     string
+		file     string
+		minScore int
+		wantErr  bool
+	}{
+		{"missing", filepath.Join(dir, "bogus.txt"), 0, true},
+		{"bad score", bad, 0, true},
+		{"good", good, 20, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := *saved()
+			config.DICTIONARY.FILE = tt.file
+			config.DICTIONARY.MINSCORE = tt.minScore
+			cwcomp.GetConfiguration = func() *cwcomp.Configuration {
+				return &config
+			}
+			err := LoadDictionary()
+			assert.Equal(t, tt.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tt.minScore, GetMinScore())
+				score, ok := GetScore("HEART")
+				assert.True(t, ok)
+				assert.Equal(t, 60, score)
+			}
+		})
+	}
+}
+
+func TestDictionary_Ranking(t *testing.T) {
+	defer useWordList(t, "AAHED;10\nBORED;50\nCARED;60\nDARED;60\nEARED")()
+
+	matches := func() []string {
+		have := make([]string, 0)
+		for word := range GetMatchingWords(`.A.ED`, make(chan struct{})) {
+			have = append(have, word)
+		}
+		return have
+	}
+	assert.Equal(t, []string{"CARED", "DARED", "EARED", "AAHED"}, matches())
+
+	SetMinScore(50)
+	assert.Equal(t, 50, GetMinScore())
+	assert.Equal(t, []string{"CARED", "DARED", "EARED"}, matches())
+
+	// Words below the minimum are still in the dictionary
+	score, ok := GetScore("AAHED")
+	assert.True(t, ok)
+	assert.Equal(t, 10, score)
+	_, ok = GetScore("FARED")
+	assert.False(t, ok)
+
+	// And the autofill index is rebuilt
+	byLength, inDict := getAutofillIndex()
+	assert.Equal(t, []string{"CARED", "DARED", "BORED", "EARED"}, byLength[5])
+	assert.False(t, inDict["AAHED"])
+}
+
+func TestPuzzle_GetConstraints_Scores(t *testing.T) {
+	defer useWordList(t, "CAT;70\nCOT;40\nACT;60\nTAX;80")()
+
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+	puzzle.SetText(puzzle.LookupWordByNumber(1, DOWN), "CAT")
+	puzzle.SetText(puzzle.LookupWordByNumber(2, DOWN), "O  ")
+
+	constraints := puzzle.GetConstraints(puzzle.LookupWordByNumber(1, ACROSS))
+	assert.Equal(t, 70, constraints[0].Score)
+	assert.Equal(t, 70, constraints[0].BestScore)
+	assert.Equal(t, 0, constraints[1].Score)
+	assert.Equal(t, 0, constraints[1].BestScore)
+	assert.Equal(t, 0, constraints[2].Score)
+	assert.Equal(t, 80, constraints[2].BestScore)
+}
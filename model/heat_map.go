@@ -0,0 +1,201 @@
+package model
+
+import (
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// HeatMap shows how hard each part of the puzzle will be to fill, given
+// the letters already in the grid.  It is found by an arc consistency
+// pass over the whole puzzle: each cell starts with the letters that
+// could go in it, and each word removes from its cells the letters that
+// no dictionary word fitting the other cells would put there, until
+// nothing more changes.
+//
+// A word that no dictionary word fits is dead.  It is not used to
+// remove letters from its cells, so that one dead word does not make
+// the whole grid dead, but all its cells are marked dead.
+type HeatMap struct {
+	Cells []CellHeat `json:"cells"`
+	Words []WordHeat `json:"words"`
+}
+
+// CellHeat is the heat of a letter cell
+type CellHeat struct {
+	Point   Point  `json:"point"`
+	Letters string `json:"letters"` // Letters that can still go in the cell
+	Choices int    `json:"choices"` // Fewest choices of the cell's words
+	Dead    bool   `json:"dead"`    // True if the cell is in a dead word
+}
+
+// WordHeat is the heat of a word
+type WordHeat struct {
+	Word    WordRef `json:"word"`
+	Choices int     `json:"choices"` // Number of dictionary words that fit
+	Dead    bool    `json:"dead"`    // True if no dictionary word fits
+}
+
+// heatSlot is a word being examined by GetHeatMap
+type heatSlot struct {
+	word   *Word
+	points []Point
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// A word with this many choices or fewer is hard to fill
+const FEW_CHOICES = 5
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetHeatMap finds the heat map of the puzzle using the dictionary words
+// at or above the minimum score, merged with the user's words if they
+// have been set with SetUserWords.  Words of one letter, and words that
+// contain rebus cells, are not examined.
+func (puzzle *Puzzle) GetHeatMap() *HeatMap {
+	index, minScore := puzzle.userWords.getWordIndex()
+
+	// Start with the letters already in the cells
+	masks := make(map[Point]letterMask)
+	for point := range puzzle.PointIterator() {
+		if puzzle.IsBlackCell(point) {
+			continue
+		}
+		letter := puzzle.GetLetter(point)
+		switch {
+		case len(letter) == 1 && letter[0] >= 'A' && letter[0] <= 'Z':
+			masks[point] = 1 << (letter[0] - 'A')
+		case strings.TrimSpace(letter) == "":
+			masks[point] = ALL_LETTERS
+		}
+	}
+
+	// Find the words to examine, and which of them contain each cell
+	slots := make([]heatSlot, 0)
+	cellSlots := make(map[Point][]int)
+	for _, word := range puzzle.sortedWords() {
+		if word.length < 2 {
+			continue
+		}
+		slot := heatSlot{word: word, points: puzzle.wordPoints(word)}
+		rebus := false
+		for _, point := range slot.points {
+			if _, ok := masks[point]; !ok {
+				rebus = true
+			}
+		}
+		if rebus {
+			continue
+		}
+		for _, point := range slot.points {
+			cellSlots[point] = append(cellSlots[point], len(slots))
+		}
+		slots = append(slots, slot)
+	}
+
+	// Examine each word until no cell changes
+	choices := make([]int, len(slots))
+	dead := make([]bool, len(slots))
+	queue := make([]int, len(slots))
+	queued := make([]bool, len(slots))
+	for i := range slots {
+		queue[i] = i
+		queued[i] = true
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		queued[s] = false
+
+		slot := slots[s]
+		wordMasks := make([]letterMask, len(slot.points))
+		for i, point := range slot.points {
+			wordMasks[i] = masks[point]
+		}
+		count, letters := index.viable(wordMasks, minScore)
+		choices[s] = count
+		if count == 0 {
+			dead[s] = true
+			continue
+		}
+		for i, point := range slot.points {
+			if letters[i] == masks[point] {
+				continue
+			}
+			masks[point] = letters[i]
+			for _, other := range cellSlots[point] {
+				if other != s && !dead[other] && !queued[other] {
+					queue = append(queue, other)
+					queued[other] = true
+				}
+			}
+		}
+	}
+
+	// Report the results
+	heatMap := new(HeatMap)
+	heatMap.Words = make([]WordHeat, len(slots))
+	for s, slot := range slots {
+		heatMap.Words[s] = WordHeat{
+			Word:    puzzle.wordRef(slot.word),
+			Choices: choices[s],
+			Dead:    dead[s],
+		}
+	}
+	heatMap.Cells = make([]CellHeat, 0)
+	for point := range puzzle.PointIterator() {
+		if len(cellSlots[point]) == 0 {
+			continue
+		}
+		cell := CellHeat{Point: point, Letters: masks[point].String(), Choices: -1}
+		for _, s := range cellSlots[point] {
+			if cell.Choices < 0 || choices[s] < cell.Choices {
+				cell.Choices = choices[s]
+			}
+			cell.Dead = cell.Dead || dead[s]
+		}
+		heatMap.Cells = append(heatMap.Cells, cell)
+	}
+	return heatMap
+}
+
+// DeadCells returns the cells that are in words that cannot be filled.
+func (heatMap *HeatMap) DeadCells() []Point {
+	points := make([]Point, 0)
+	for _, cell := range heatMap.Cells {
+		if cell.Dead {
+			points = append(points, cell.Point)
+		}
+	}
+	return points
+}
+
+// HardWords returns the words that have no more than the specified
+// number of choices, including the dead ones.
+func (heatMap *HeatMap) HardWords(maxChoices int) []WordHeat {
+	words := make([]WordHeat, 0)
+	for _, word := range heatMap.Words {
+		if word.Choices <= maxChoices {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// String returns the letters in the set in alphabetical order.
+func (mask letterMask) String() string {
+	sb := strings.Builder{}
+	for letter := 0; letter < 26; letter++ {
+		if mask&(1<<letter) != 0 {
+			sb.WriteByte(byte('A' + letter))
+		}
+	}
+	return sb.String()
+}
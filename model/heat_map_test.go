@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getWordHeat returns the heat of the word with this reference
+func getWordHeat(heatMap *HeatMap, ref WordRef) WordHeat {
+	for _, wh := range heatMap.Words {
+		if wh.Word == ref {
+			return wh
+		}
+	}
+	return WordHeat{Choices: -1}
+}
+
+// getCellHeat returns the heat of the cell at this point
+func getCellHeat(heatMap *HeatMap, point Point) CellHeat {
+	for _, ch := range heatMap.Cells {
+		if ch.Point == point {
+			return ch
+		}
+	}
+	return CellHeat{Choices: -1}
+}
+
+func TestPuzzle_GetHeatMap(t *testing.T) {
+	defer useWordList(t, "BAT\nARE\nTEN\nBAR\nTEA\nXYZ")()
+
+	tests := []struct {
+		name     string
+		text     string // Text of 1 across
+		ref      WordRef
+		choices  int
+		dead     bool
+		point    Point
+		letters  string
+		deadCell bool
+	}{
+		{"empty", "   ", WordRef{1, DOWN}, 1, false, NewPoint(1, 1), "B", false},
+		{"propagated", "B  ", WordRef{1, DOWN}, 1, false, NewPoint(3, 1), "T", false},
+		{"filled", "BAT", WordRef{3, DOWN}, 2, false, NewPoint(3, 3), "AN", false},
+		{"dead", "BAX", WordRef{1, ACROSS}, 0, true, NewPoint(1, 3), "X", true},
+		{"crosser of dead", "BAX", WordRef{1, DOWN}, 2, false, NewPoint(2, 1), "A", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzle := NewPuzzle(3)
+			puzzle.RenumberCells()
+			puzzle.SetText(puzzle.LookupWordByNumber(1, ACROSS), tt.text)
+
+			heatMap := puzzle.GetHeatMap()
+			assert.Equal(t, 6, len(heatMap.Words))
+			assert.Equal(t, 9, len(heatMap.Cells))
+
+			wh := getWordHeat(heatMap, tt.ref)
+			assert.Equal(t, tt.choices, wh.Choices)
+			assert.Equal(t, tt.dead, wh.Dead)
+
+			ch := getCellHeat(heatMap, tt.point)
+			assert.Equal(t, tt.letters, ch.Letters)
+			assert.Equal(t, tt.deadCell, ch.Dead)
+		})
+	}
+}
+
+func TestHeatMap_DeadCells(t *testing.T) {
+	defer useWordList(t, "BAT\nARE\nTEN\nBAR\nTEA\nXYZ")()
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+	puzzle.SetText(puzzle.LookupWordByNumber(1, ACROSS), "BAX")
+
+	heatMap := puzzle.GetHeatMap()
+	dead := heatMap.DeadCells()
+	assert.Contains(t, dead, NewPoint(1, 1))
+	assert.Contains(t, dead, NewPoint(1, 3))
+
+	hard := heatMap.HardWords(0)
+	refs := make([]string, len(hard))
+	for i, wh := range hard {
+		refs[i] = wh.Word.String()
+	}
+	assert.Contains(t, refs, "1A")
+}
+
+func TestPuzzle_GetHeatMap_MinScore(t *testing.T) {
+	defer useWordList(t, "BAT;90\nARE;90\nTEN;90\nBAR;10\nTEA;10")()
+	SetMinScore(50)
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+
+	heatMap := puzzle.GetHeatMap()
+	assert.Equal(t, "B", getCellHeat(heatMap, NewPoint(1, 1)).Letters)
+	assert.Equal(t, 1, getWordHeat(heatMap, WordRef{4, ACROSS}).Choices)
+}
+
+func TestPuzzle_GetHeatMap_UserWords(t *testing.T) {
+	defer useWordList(t, "BAT\nARE\nTEN\nBAR\nTEA\nXYZ")()
+	runtest(func(t *testing.T) {
+		AddUserWord(TEST_USERID, "BAX", 60)
+		BanUserWord(TEST_USERID, "BAT")
+		uw, _ := LoadUserWords(TEST_USERID)
+
+		tests := []struct {
+			name    string
+			uw      *UserWords
+			text    string // Text of 1 across
+			choices int
+			dead    bool
+		}{
+			{"base only, user word", nil, "BAX", 0, true},
+			{"base only, banned word", nil, "B T", 1, false},
+			{"user word", uw, "BAX", 1, false},
+			{"banned word", uw, "B T", 0, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				puzzle := NewPuzzle(3)
+				puzzle.RenumberCells()
+				puzzle.SetUserWords(tt.uw)
+				puzzle.SetText(puzzle.LookupWordByNumber(1, ACROSS), tt.text)
+
+				wh := getWordHeat(puzzle.GetHeatMap(), WordRef{1, ACROSS})
+				assert.Equal(t, tt.choices, wh.Choices)
+				assert.Equal(t, tt.dead, wh.Dead)
+			})
+		}
+	})(t)
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"fmt"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// CommandKind identifies the type of change recorded in a Command
+type CommandKind string
+
+// Command is one change to the puzzle in its undo/redo history.  It
+// records the state before and after the change, so that it can be
+// undone and redone in any session, not just the one that made it:
+//   - BLACK_CELL_COMMAND: the cell at Point changed from Before to
+//     After, each BLACK_CELL_STATE or LETTER_CELL_STATE (in histories
+//     saved before these were recorded, both are empty, and the cell
+//     and its symmetric twins were toggled)
+//   - STYLE_COMMAND: the style of the letter cell at Point changed from
+//     Before to After, each a CellStyle as a string
+//   - SYMMETRY_COMMAND: the symmetry changed from Before to After
+//   - LETTER_COMMAND: the letter at Point changed from Before to After
+//   - WORD_COMMAND: the text of the word at Point in direction Dir
+//     changed from Before to After
+//   - CLUE_COMMAND: the clue of the word at Point in direction Dir
+//     changed from Before to After
+//   - RENAME_COMMAND: the puzzle name changed from Before to After
+//   - GROUP_COMMAND: the Commands were made together, as one change
+//     described by Label
+type Command struct {
+	Kind     CommandKind `json:"kind"`
+	Label    string      `json:"label,omitempty"`
+	Point    Point       `json:"point"`
+	Dir      Direction   `json:"dir,omitempty"`
+	Before   string      `json:"before"`
+	After    string      `json:"after"`
+	Commands []*Command  `json:"commands,omitempty"`
+}
+
+// History is the ordered list of changes made to a puzzle.  The commands
+// before the cursor can be undone (the last one first), and the commands
+// after it can be redone.
+type History struct {
+	commands []*Command // All the commands, oldest first
+	cursor   int        // Number of commands that have not been undone
+	group    *Command   // Group being recorded, if any
+	depth    int        // Number of nested groups being recorded
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+const (
+	BLACK_CELL_COMMAND CommandKind = "blackcell"
+	SYMMETRY_COMMAND   CommandKind = "symmetry"
+	LETTER_COMMAND     CommandKind = "letter"
+	WORD_COMMAND       CommandKind = "word"
+	CLUE_COMMAND       CommandKind = "clue"
+	RENAME_COMMAND     CommandKind = "rename"
+	GROUP_COMMAND      CommandKind = "group"
+	STYLE_COMMAND      CommandKind = "style"
+)
+
+// The states of a cell in a black cell command
+const (
+	BLACK_CELL_STATE  = "black"
+	LETTER_CELL_STATE = "letter"
+)
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// BeginGroup starts recording a compound change, so that all the
+// changes made until the matching EndGroup are undone and redone
+// together.  Groups can be nested, in which case the inner ones are
+// part of the outermost one.
+func (puzzle *Puzzle) BeginGroup(label string) {
+	h := &puzzle.history
+	if h.depth == 0 {
+		h.group = &Command{Kind: GROUP_COMMAND, Label: label, Commands: make([]*Command, 0)}
+	}
+	h.depth++
+}
+
+// EndGroup finishes recording a compound change started by BeginGroup.
+// A group with no changes is not added to the history.
+func (puzzle *Puzzle) EndGroup() {
+	h := &puzzle.history
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth > 0 {
+		return
+	}
+	group := h.group
+	h.group = nil
+	if len(group.Commands) > 0 {
+		puzzle.record(group)
+	}
+}
+
+// CanRedo returns true if there is a change that can be redone.
+func (puzzle *Puzzle) CanRedo() bool {
+	return puzzle.history.cursor < len(puzzle.history.commands)
+}
+
+// CanUndo returns true if there is a change that can be undone.
+func (puzzle *Puzzle) CanUndo() bool {
+	return puzzle.history.cursor > 0
+}
+
+// ClearHistory removes all the changes from the history.
+func (puzzle *Puzzle) ClearHistory() {
+	puzzle.history = History{}
+}
+
+// GetHistory returns the changes that can be undone, oldest first, and
+// the changes that can be redone, next first.
+func (puzzle *Puzzle) GetHistory() (undo []*Command, redo []*Command) {
+	h := &puzzle.history
+	undo = append(make([]*Command, 0), h.commands[:h.cursor]...)
+	redo = append(make([]*Command, 0), h.commands[h.cursor:]...)
+	return undo, redo
+}
+
+// Redo re-applies the last change that was undone and returns it, or nil
+// if there is nothing to redo.
+func (puzzle *Puzzle) Redo() *Command {
+	if !puzzle.CanRedo() {
+		return nil
+	}
+	h := &puzzle.history
+	cmd := h.commands[h.cursor]
+	h.cursor++
+	puzzle.applyCommand(cmd, false)
+	return cmd
+}
+
+// Undo reverses the last change and returns it, or nil if there is
+// nothing to undo.  If the change included black cells, the puzzle is
+// renumbered.
+func (puzzle *Puzzle) Undo() *Command {
+	if !puzzle.CanUndo() {
+		return nil
+	}
+	h := &puzzle.history
+	h.cursor--
+	cmd := h.commands[h.cursor]
+	puzzle.applyCommand(cmd, true)
+	return cmd
+}
+
+// applyCommand undoes or redoes a command, renumbering the puzzle if
+// black cells changed.
+func (puzzle *Puzzle) applyCommand(cmd *Command, undo bool) {
+	if puzzle.applyChange(cmd, undo) {
+		puzzle.RenumberCells()
+	}
+}
+
+// applyChange undoes or redoes a command, returning true if any black
+// cells changed.
+func (puzzle *Puzzle) applyChange(cmd *Command, undo bool) bool {
+	value := cmd.After
+	if undo {
+		value = cmd.Before
+	}
+	switch cmd.Kind {
+	case BLACK_CELL_COMMAND:
+		if value == "" {
+			puzzle.togglePoint(cmd.Point)
+		} else {
+			puzzle.setCellState(cmd.Point, value)
+		}
+		return true
+	case STYLE_COMMAND:
+		puzzle.SetCellStyle(cmd.Point, parseCellStyle(value))
+	case SYMMETRY_COMMAND:
+		puzzle.symmetry = Symmetry(value)
+	case LETTER_COMMAND:
+		puzzle.setLetter(cmd.Point, value)
+	case WORD_COMMAND:
+		puzzle.SetTextWithoutPush(NewWord(cmd.Point, cmd.Dir, 0, ""), value)
+	case CLUE_COMMAND:
+		if word := puzzle.lookupStartingWord(cmd.Point, cmd.Dir); word != nil {
+			word.clue = value
+		}
+	case RENAME_COMMAND:
+		puzzle.puzzleName = value
+	case GROUP_COMMAND:
+		changed := false
+		n := len(cmd.Commands)
+		for i := 0; i < n; i++ {
+			child := cmd.Commands[i]
+			if undo {
+				child = cmd.Commands[n-1-i]
+			}
+			if puzzle.applyChange(child, undo) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+// lookupStartingWord returns the word that starts at this point in this
+// direction, or nil if there is none.
+func (puzzle *Puzzle) lookupStartingWord(point Point, dir Direction) *Word {
+	for _, word := range puzzle.words {
+		if word.point == point && word.direction == dir {
+			return word
+		}
+	}
+	return nil
+}
+
+// record adds a change to the history, or to the group being recorded.
+// Any changes that were undone can no longer be redone.
+func (puzzle *Puzzle) record(cmd *Command) {
+	h := &puzzle.history
+	if h.group != nil && cmd != h.group {
+		h.group.Commands = append(h.group.Commands, cmd)
+		return
+	}
+	h.commands = append(h.commands[:h.cursor], cmd)
+	h.cursor = len(h.commands)
+}
+
+// String returns a string representation of this command.
+func (cmd *Command) String() string {
+	switch cmd.Kind {
+	case BLACK_CELL_COMMAND:
+		if cmd.Before == "" && cmd.After == "" {
+			return fmt.Sprintf("%s %s", cmd.Kind, cmd.Point.String())
+		}
+		return fmt.Sprintf("%s %s %q -> %q", cmd.Kind, cmd.Point.String(), cmd.Before, cmd.After)
+	case RENAME_COMMAND, SYMMETRY_COMMAND:
+		return fmt.Sprintf("%s %q -> %q", cmd.Kind, cmd.Before, cmd.After)
+	case LETTER_COMMAND, STYLE_COMMAND:
+		return fmt.Sprintf("%s %s %q -> %q", cmd.Kind, cmd.Point.String(), cmd.Before, cmd.After)
+	case GROUP_COMMAND:
+		return fmt.Sprintf("%s %q (%d changes)", cmd.Kind, cmd.Label, len(cmd.Commands))
+	default:
+		return fmt.Sprintf("%s %s %s %q -> %q", cmd.Kind, cmd.Point.String(), cmd.Dir.String(), cmd.Before, cmd.After)
+	}
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPuzzle_Undo_Order(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+
+	// Changes of different kinds are undone in the reverse order they
+	// were made
+	puzzle.SetText(word, "ABCDE")
+	puzzle.Toggle(NewPoint(1, 1))
+	puzzle.RenumberCells()
+	puzzle.SetLetter(NewPoint(1, 2), "X")
+
+	undo, redo := puzzle.GetHistory()
+	assert.Equal(t, 3, len(undo))
+	assert.Equal(t, 0, len(redo))
+
+	assert.Equal(t, LETTER_COMMAND, puzzle.Undo().Kind)
+	assert.Equal(t, "B", puzzle.GetLetter(NewPoint(1, 2)))
+	// The letter lost to the black cell is restored with it
+	assert.Equal(t, GROUP_COMMAND, puzzle.Undo().Kind)
+	assert.False(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.Equal(t, "ABCDE", puzzle.GetText(puzzle.LookupWordByNumber(1, ACROSS)))
+	assert.Equal(t, WORD_COMMAND, puzzle.Undo().Kind)
+	assert.Equal(t, "     ", puzzle.GetText(puzzle.LookupWordByNumber(1, ACROSS)))
+	assert.Nil(t, puzzle.Undo())
+
+	// And redone in the order they were made
+	assert.Equal(t, WORD_COMMAND, puzzle.Redo().Kind)
+	assert.Equal(t, GROUP_COMMAND, puzzle.Redo().Kind)
+	assert.Equal(t, LETTER_COMMAND, puzzle.Redo().Kind)
+	assert.Nil(t, puzzle.Redo())
+	assert.True(t, puzzle.IsBlackCell(NewPoint(1, 1)))
+	assert.Equal(t, "X", puzzle.GetLetter(NewPoint(1, 2)))
+}
+
+func TestPuzzle_Undo_NewChangeClearsRedo(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+
+	puzzle.SetText(word, "ABCDE")
+	puzzle.SetText(word, "FGHIJ")
+	puzzle.Undo()
+	assert.True(t, puzzle.CanRedo())
+
+	puzzle.SetText(word, "KLMNO")
+	assert.False(t, puzzle.CanRedo())
+	undo, _ := puzzle.GetHistory()
+	assert.Equal(t, 2, len(undo))
+	assert.Equal(t, "ABCDE", undo[1].Before)
+}
+
+func TestPuzzle_Undo_Clue(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(2, DOWN)
+
+	puzzle.SetClue(word, "First")
+	puzzle.SetClue(word, "Second")
+	puzzle.Undo()
+	assert.Equal(t, "First", word.clue)
+	puzzle.Undo()
+	assert.Equal(t, "", word.clue)
+	puzzle.Redo()
+	assert.Equal(t, "First", word.clue)
+}
+
+func TestPuzzle_Undo_Rename(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.SetPuzzleName("Old")
+	puzzle.SetPuzzleName("New")
+
+	cmd := puzzle.Undo()
+	assert.Equal(t, RENAME_COMMAND, cmd.Kind)
+	assert.Equal(t, "Old", puzzle.GetPuzzleName())
+	puzzle.Redo()
+	assert.Equal(t, "New", puzzle.GetPuzzleName())
+}
+
+func TestPuzzle_Undo_Group(t *testing.T) {
+	puzzle := NewPuzzle(5)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+	puzzle.SetText(word, "ABCDE")
+
+	// Nested groups are part of the outermost one, and empty groups
+	// are not recorded
+	puzzle.BeginGroup("outer")
+	puzzle.Toggle(NewPoint(3, 3))
+	puzzle.BeginGroup("inner")
+	puzzle.SetLetter(NewPoint(2, 1), "Z")
+	puzzle.SetClue(word, "Letters")
+	puzzle.EndGroup()
+	puzzle.EndGroup()
+	puzzle.BeginGroup("empty")
+	puzzle.EndGroup()
+
+	undo, _ := puzzle.GetHistory()
+	assert.Equal(t, 2, len(undo))
+	group := undo[1]
+	assert.Equal(t, GROUP_COMMAND, group.Kind)
+	assert.Equal(t, "outer", group.Label)
+	assert.Equal(t, 3, len(group.Commands))
+	assert.Equal(t, `group "outer" (3 changes)`, group.String())
+
+	// The whole group is undone and redone at once
+	puzzle.Undo()
+	assert.False(t, puzzle.IsBlackCell(NewPoint(3, 3)))
+	assert.Equal(t, " ", puzzle.GetLetter(NewPoint(2, 1)))
+	assert.Equal(t, "", word.clue)
+	assert.Equal(t, "ABCDE", puzzle.GetText(word))
+
+	puzzle.Redo()
+	assert.True(t, puzzle.IsBlackCell(NewPoint(3, 3)))
+	assert.Equal(t, "Z", puzzle.GetLetter(NewPoint(2, 1)))
+	word = puzzle.LookupWordByNumber(1, ACROSS)
+	assert.Equal(t, "Letters", word.clue)
+}
+
+func TestCommand_String(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+		want string
+	}{
+		{"black cell", Command{Kind: BLACK_CELL_COMMAND, Point: NewPoint(1, 2)}, "blackcell {r:1,c:2}"},
+		{"black cell state", Command{Kind: BLACK_CELL_COMMAND, Point: NewPoint(1, 2), Before: LETTER_CELL_STATE, After: BLACK_CELL_STATE}, `blackcell {r:1,c:2} "letter" -> "black"`},
+		{"style", Command{Kind: STYLE_COMMAND, Point: NewPoint(2, 2), Before: `{"circled":true}`}, `style {r:2,c:2} "{\"circled\":true}" -> ""`},
+		{"rename", Command{Kind: RENAME_COMMAND, Before: "A", After: "B"}, `rename "A" -> "B"`},
+		{"letter", Command{Kind: LETTER_COMMAND, Point: NewPoint(3, 4), Before: " ", After: "X"}, `letter {r:3,c:4} " " -> "X"`},
+		{"word", Command{Kind: WORD_COMMAND, Point: NewPoint(1, 1), Dir: ACROSS, Before: "   ", After: "CAT"}, `word {r:1,c:1} across "   " -> "CAT"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cmd.String())
+		})
+	}
+}
+
+func TestPuzzle_SaveHistory(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "History"
+
+		puzzle := getGoodPuzzle()
+		puzzle.ClearHistory()
+		err := savePuzzle(puzzle, puzzleName)
+		assert.Nil(t, err)
+		undo, _ := puzzle.GetHistory()
+		assert.Equal(t, 9, len(undo))
+
+		// Change a clue and undo it, then save the history
+		word := puzzle.LookupWordByNumber(20, DOWN)
+		puzzle.SetClue(word, "Greeting")
+		puzzle.Undo()
+		assert.Nil(t, puzzle.SaveHistory(TEST_USERID))
+
+		// A newly loaded puzzle has no history until it is loaded
+		reloaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.False(t, reloaded.CanUndo())
+		assert.Nil(t, reloaded.LoadHistory(TEST_USERID))
+		undo, redo := reloaded.GetHistory()
+		assert.Equal(t, 9, len(undo))
+		assert.Equal(t, 1, len(redo))
+		assert.Equal(t, CLUE_COMMAND, redo[0].Kind)
+
+		// Undo and redo work in the reloaded puzzle
+		word = reloaded.LookupWordByNumber(20, DOWN)
+		reloaded.Redo()
+		assert.Equal(t, "Greeting", word.clue)
+		reloaded.Undo()
+		assert.Equal(t, RENAME_COMMAND, reloaded.Undo().Kind)
+		assert.Equal(t, "", reloaded.GetPuzzleName())
+		reloaded.Undo()
+		assert.Equal(t, "", word.clue)
+		reloaded.Undo()
+		assert.Equal(t, "   ", reloaded.GetText(word))
+		reloaded.Redo()
+		reloaded.Redo()
+		reloaded.Redo()
+		assert.Equal(t, puzzleName, reloaded.GetPuzzleName())
+		assert.Equal(t, "HOW", reloaded.GetText(word))
+		assert.Equal(t, "In what manner", word.clue)
+
+		// Saving again replaces the old history
+		reloaded.ClearHistory()
+		assert.Nil(t, reloaded.SaveHistory(TEST_USERID))
+		assert.Nil(t, reloaded.LoadHistory(TEST_USERID))
+		assert.False(t, reloaded.CanUndo())
+		assert.False(t, reloaded.CanRedo())
+
+		// A puzzle that has not been saved has no history to save
+		puzzle = NewPuzzle(5)
+		puzzle.SetPuzzleName("Unsaved")
+		assert.NotNil(t, puzzle.SaveHistory(TEST_USERID))
+	})(t)
+}
+
+func TestPuzzle_SavePuzzleWithHistory(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "WithHistory"
+
+		puzzle := getGoodPuzzle()
+		puzzle.SetPuzzleName(puzzleName)
+		word := puzzle.LookupWordByNumber(20, DOWN)
+		puzzle.SetClue(word, "Greeting")
+		undo, _ := puzzle.GetHistory()
+		assert.Nil(t, puzzle.SavePuzzleWithHistory(TEST_USERID))
+
+		reloaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, "Greeting", reloaded.LookupWordByNumber(20, DOWN).clue)
+		assert.Nil(t, reloaded.LoadHistory(TEST_USERID))
+		reloadedUndo, _ := reloaded.GetHistory()
+		assert.Equal(t, len(undo), len(reloadedUndo))
+
+		// A puzzle without a name saves neither
+		puzzle = NewPuzzle(5)
+		assert.NotNil(t, puzzle.SavePuzzleWithHistory(TEST_USERID))
+	})(t)
+}
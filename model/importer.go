@@ -7,8 +7,11 @@ package model
 // Importer is an interface that must be implemented by any source of
 // puzzle data that can be imported (e.g., AcrossLite)
 type Importer interface {
-	// Returns the number of rows or columns in this puzzle
-	GetSize() int
+	// Returns the number of rows in this puzzle
+	GetRows() int
+
+	// Returns the number of columns in this puzzle
+	GetCols() int
 
 	// Returns the puzzle name, which will be used as part of the key in
 	// the database representation.
@@ -24,7 +27,7 @@ type Importer interface {
 	// Returns the letter at a given point in the grid.  These are
 	// relative to 1, not 0, so
 	//
-	//  r = 1, 2, ..., n c = 1, 2, ..., n
+	//  r = 1, 2, ..., nRows c = 1, 2, ..., nCols
 	//
 	// If the letter value is '\x00', it is a black cell.  Otherwise, it is
 	// converted to uppercase.  If the letter is not a black cell and
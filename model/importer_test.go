@@ -27,12 +27,13 @@ func newTestImporter() *TestImporter {
 
 	return ti
 }
-func (ti *TestImporter) GetSize() int     { return 9 }
+func (ti *TestImporter) GetRows() int     { return 9 }
+func (ti *TestImporter) GetCols() int     { return 9 }
 func (ti *TestImporter) GetName() string  { return "good9" }
 func (ti *TestImporter) GetTitle() string { return "what does this mean?" }
 func (ti *TestImporter) GetCell(r, c int) (byte, error) {
-	n := ti.GetSize()
-	if r < 1 || r > n || c < 1 || c > n {
+	nRows, nCols := ti.GetRows(), ti.GetCols()
+	if r < 1 || r > nRows || c < 1 || c > nCols {
 		return 0, fmt.Errorf("Invalid index: r=%d,c=%d", r, c)
 	}
 	i, j := r-1, c-1
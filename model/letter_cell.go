@@ -12,9 +12,11 @@ import (
 // Letter cell is an ordinary point in the grid. It contains:
 //   - The location of the cell, a Point(r, c)
 //   - The character in the cell
+//   - The style of the cell (circled, shaded, or barred)
 type LetterCell struct {
-	point  Point  // Location of this letter cell
-	letter string // Character in the cell
+	point  Point     // Location of this letter cell
+	letter string    // Character in the cell
+	style  CellStyle // Optional style attributes
 }
 
 // ---------------------------------------------------------------------
@@ -58,6 +60,9 @@ func (lc LetterCell) String() string {
 	parts := make([]string, 0)
 	parts = append(parts, fmt.Sprintf(`point:{%d,%d}`, lc.point.r, lc.point.c))
 	parts = append(parts, fmt.Sprintf("letter:%q", lc.letter))
+	if !lc.style.IsPlain() {
+		parts = append(parts, fmt.Sprintf("style:%+v", lc.style))
+	}
 	s := strings.Join(parts, ",")
 	return s
 }
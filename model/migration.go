@@ -0,0 +1,539 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Migration is one numbered change to the database schema.  The
+// versions that have been applied to a database are recorded in its
+// schema_version table, and Migrate applies the rest in order, each in
+// its own transaction.
+//
+// Databases created before there was a schema_version table may have
+// been made with any earlier version of the DDL, so each migration
+// checks for what is already there and only adds what is missing.
+type Migration struct {
+	Version     int
+	Description string
+	apply       func(tx *sql.Tx) error
+}
+
+// MigrationStatus tells whether a migration has been applied to the
+// database, and when.
+type MigrationStatus struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Applied     string `json:"applied,omitempty"` // Datetime, or "" if pending
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// migrations is the list of all schema changes, in version order.  New
+// ones are added at the end, and ddl.sql is changed to match, so that a
+// new database has the same schema as an old one that has been
+// migrated.
+var migrations = []Migration{
+	{1, "Initial schema", migrateInitialSchema},
+	{2, "Rectangular grids", migrateRectangularGrids},
+	{3, "Symmetry modes", migrateSymmetry},
+	{4, "Cell styles", migrateCellStyles},
+	{5, "User word lists", migrateUserWords},
+	{6, "Undo/redo history", migrateHistory},
+	{7, "Puzzle revisions", migrateRevisions},
+	{8, "Per-user puzzle names and sharing", migrateSharing},
+	{9, "Login sessions", migrateSessions},
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Migrate brings the database schema up to date by applying the
+// migrations that have not been applied yet, and returns those that
+// were.  An empty database is created from ddl.sql instead.  If a
+// migration fails, the ones before it are kept, and the error is
+// returned.
+func (repo *Repository) Migrate() ([]Migration, error) {
+	applied := make([]Migration, 0)
+
+	// Start a new database with the full schema
+	empty, err := isEmptyDatabase(repo.db)
+	if err != nil {
+		return applied, err
+	}
+	if empty {
+		if _, err := repo.db.Exec(GetDDL()); err != nil {
+			return applied, err
+		}
+		err := repo.inTransaction(func(tx *sql.Tx) error {
+			for _, m := range migrations {
+				if err := recordMigration(tx, m); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return applied, err
+		}
+		return append(applied, migrations...), nil
+	}
+
+	// Otherwise apply the pending migrations in order
+	if _, err := repo.db.Exec(schemaVersionDDL); err != nil {
+		return applied, err
+	}
+	version, err := repo.SchemaVersion()
+	if err != nil {
+		return applied, err
+	}
+
+	// Foreign keys are turned off while migrating, so that a table can
+	// be rebuilt without cascading deletes, and checked before each
+	// migration is committed.  This can only be done outside a
+	// transaction, on the connection that the transactions will use.
+	ctx := context.Background()
+	conn, err := repo.db.Conn(ctx)
+	if err != nil {
+		return applied, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys=OFF`); err != nil {
+		return applied, err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys=ON`)
+
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		log.Printf("Applied migration %d: %s\n", m.Version, m.Description)
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// MigrationStatus returns the status of each migration, in version
+// order.
+func (repo *Repository) MigrationStatus() ([]MigrationStatus, error) {
+	appliedAt := make(map[int]string)
+	exists, err := hasTable(repo.db, "schema_version")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		rows, err := repo.db.Query(`SELECT version, applied FROM schema_version`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				version int
+				applied sql.NullString
+			)
+			if err := rows.Scan(&version, &applied); err != nil {
+				return nil, err
+			}
+			appliedAt[version] = applied.String
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version:     m.Version,
+			Description: m.Description,
+			Applied:     appliedAt[m.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// SchemaVersion returns the version of the last migration applied to
+// the database, or 0 if none has been.
+func (repo *Repository) SchemaVersion() (int, error) {
+	exists, err := hasTable(repo.db, "schema_version")
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version sql.NullInt64
+	err = repo.db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// LatestSchemaVersion returns the version of the last migration, which
+// is the version of an up-to-date database.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate is a shortcut for Repository.Migrate.
+func Migrate() ([]Migration, error) {
+	var applied []Migration
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		applied, err = repo.Migrate()
+		return err
+	})
+	return applied, err
+}
+
+// GetMigrationStatus is a shortcut for Repository.MigrationStatus.
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		statuses, err = repo.MigrationStatus()
+		return err
+	})
+	return statuses, err
+}
+
+// schemaVersionDDL creates the table of applied migrations.
+const schemaVersionDDL = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version         INTEGER PRIMARY KEY,    -- Migration version
+    description     TEXT,                   -- What the migration does
+    applied         TEXT                    -- Datetime the migration was applied
+)`
+
+// applyMigration applies one migration and records it in the
+// schema_version table, in a single transaction.
+func applyMigration(ctx context.Context, conn *sql.Conn, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = m.apply(tx)
+	if err == nil {
+		err = checkForeignKeys(tx)
+	}
+	if err == nil {
+		err = recordMigration(tx, m)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys returns an error if any row refers to one that does
+// not exist.
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var (
+			table  string
+			rowid  sql.NullInt64
+			parent string
+			fkid   int
+		)
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("row %d of %s refers to a missing row of %s",
+			rowid.Int64, table, parent)
+	}
+	return rows.Err()
+}
+
+// recordMigration adds a migration to the schema_version table.
+func recordMigration(tx *sql.Tx, m Migration) error {
+	_, err := tx.Exec(`INSERT INTO schema_version VALUES(?, ?, ?)`,
+		m.Version, m.Description, time.Now().Format(time.RFC3339))
+	return err
+}
+
+// isEmptyDatabase returns true if the database has no tables.
+func isEmptyDatabase(q queryer) (bool, error) {
+	rows, err := q.Query(`SELECT name FROM sqlite_master WHERE type='table'`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return false, nil
+	}
+	return true, rows.Err()
+}
+
+// hasTable returns true if the database has a table with this name.
+func hasTable(q queryer, table string) (bool, error) {
+	rows, err := q.Query(`SELECT name FROM sqlite_master WHERE type='table' AND name=?`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return true, nil
+	}
+	return false, rows.Err()
+}
+
+// hasColumn returns true if the table has a column with this name.
+func hasColumn(q queryer, table, column string) (bool, error) {
+	rows, err := q.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumn adds a column to a table, unless it is already there.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := hasColumn(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// execAll runs each of the statements in turn, stopping at the first
+// error.
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateInitialSchema creates the tables of the original schema, in
+// which every puzzle was square.
+func migrateInitialSchema(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE IF NOT EXISTS users (
+    userid          INTEGER PRIMARY KEY,
+    username        TEXT NOT NULL UNIQUE,
+    password        BLOB NOT NULL,
+    created         TEXT,
+    email           TEXT,
+    confirmed       TEXT,
+    author_name     TEXT,
+    address_line_1  TEXT,
+    address_line_2  TEXT,
+    address_city    TEXT,
+    address_state   TEXT,
+    address_zip     TEXT
+)`, `
+CREATE TABLE IF NOT EXISTS puzzles (
+    id              INTEGER PRIMARY KEY,
+    userid          INTEGER NOT NULL,
+    puzzlename      TEXT NOT NULL UNIQUE,
+    created         TEXT,
+    modified        TEXT,
+    n               INTEGER
+)`, `
+CREATE TABLE IF NOT EXISTS cells (
+    id              INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    letter          TEXT,
+    PRIMARY KEY (id, r, c),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+)`, `
+CREATE TABLE IF NOT EXISTS words (
+    id              INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    dir             TEXT,
+    length          INTEGER,
+    clue            TEXT,
+    PRIMARY KEY (id, r, c, dir),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+)`)
+}
+
+// migrateRectangularGrids replaces the single puzzle size n with
+// separate row and column counts.
+func migrateRectangularGrids(tx *sql.Tx) error {
+	exists, err := hasColumn(tx, "puzzles", "n")
+	if err != nil || !exists {
+		return err
+	}
+	if err := addColumn(tx, "puzzles", "nrows", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "puzzles", "ncols", "INTEGER"); err != nil {
+		return err
+	}
+	return execAll(tx,
+		`UPDATE puzzles SET nrows=n, ncols=n WHERE nrows IS NULL`,
+		`ALTER TABLE puzzles DROP COLUMN n`,
+	)
+}
+
+// migrateSymmetry adds the black cell symmetry mode of each puzzle.
+// Existing puzzles have none, which is taken to be rotational.
+func migrateSymmetry(tx *sql.Tx) error {
+	return addColumn(tx, "puzzles", "symmetry", "TEXT")
+}
+
+// migrateCellStyles adds the circles, shading, and bars of each cell.
+func migrateCellStyles(tx *sql.Tx) error {
+	if err := addColumn(tx, "cells", "circled", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "cells", "shade", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	return addColumn(tx, "cells", "bars", "TEXT DEFAULT ''")
+}
+
+// migrateUserWords adds the table of words each user has added to or
+// banned from the dictionary.
+func migrateUserWords(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE IF NOT EXISTS user_words (
+    userid          INTEGER NOT NULL,
+    word            TEXT NOT NULL,
+    score           INTEGER,
+    banned          INTEGER DEFAULT 0,
+    PRIMARY KEY (userid, word),
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
+)`)
+}
+
+// migrateHistory adds the table of saved undo/redo history.
+func migrateHistory(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE IF NOT EXISTS history (
+    id              INTEGER,
+    seq             INTEGER,
+    command         TEXT,
+    undone          INTEGER DEFAULT 0,
+    PRIMARY KEY (id, seq),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+)`)
+}
+
+// migrateRevisions adds the tables of saved revisions, and makes the
+// current version of each existing puzzle its first revision.
+func migrateRevisions(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE IF NOT EXISTS revisions (
+    id              INTEGER,
+    revision        INTEGER,
+    saved           TEXT,
+    nrows           INTEGER,
+    ncols           INTEGER,
+    symmetry        TEXT,
+    PRIMARY KEY (id, revision),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+)`, `
+CREATE TABLE IF NOT EXISTS revision_cells (
+    id              INTEGER,
+    revision        INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    letter          TEXT,
+    circled         INTEGER DEFAULT 0,
+    shade           TEXT DEFAULT '',
+    bars            TEXT DEFAULT '',
+    PRIMARY KEY (id, revision, r, c),
+    FOREIGN KEY (id, revision) REFERENCES revisions (id, revision) ON DELETE CASCADE
+)`, `
+CREATE TABLE IF NOT EXISTS revision_words (
+    id              INTEGER,
+    revision        INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    dir             TEXT,
+    length          INTEGER,
+    clue            TEXT,
+    PRIMARY KEY (id, revision, r, c, dir),
+    FOREIGN KEY (id, revision) REFERENCES revisions (id, revision) ON DELETE CASCADE
+)`, `
+INSERT INTO revisions (id, revision, saved, nrows, ncols, symmetry)
+    SELECT id, 1, modified, nrows, ncols, symmetry FROM puzzles
+    WHERE id NOT IN (SELECT id FROM revisions)`, `
+INSERT INTO revision_cells (id, revision, r, c, letter, circled, shade, bars)
+    SELECT id, 1, r, c, letter, circled, shade, bars FROM cells
+    WHERE id NOT IN (SELECT id FROM revision_cells)`, `
+INSERT INTO revision_words (id, revision, r, c, dir, length, clue)
+    SELECT id, 1, r, c, dir, length, clue FROM words
+    WHERE id NOT IN (SELECT id FROM revision_words)`)
+}
+
+// migrateSharing makes puzzle names unique for each user rather than
+// for all users, and adds the table of puzzles shared with other users.
+// SQLite cannot drop a constraint, so the puzzles table is rebuilt.
+func migrateSharing(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE puzzles_new (
+    id              INTEGER PRIMARY KEY,
+    userid          INTEGER NOT NULL,
+    puzzlename      TEXT NOT NULL,
+    created         TEXT,
+    modified        TEXT,
+    nrows           INTEGER,
+    ncols           INTEGER,
+    symmetry        TEXT,
+    UNIQUE (userid, puzzlename)
+)`, `
+INSERT INTO puzzles_new (id, userid, puzzlename, created, modified, nrows, ncols, symmetry)
+    SELECT id, userid, puzzlename, created, modified, nrows, ncols, symmetry FROM puzzles`,
+		`DROP TABLE puzzles`,
+		`ALTER TABLE puzzles_new RENAME TO puzzles`, `
+CREATE TABLE IF NOT EXISTS puzzle_shares (
+    id              INTEGER NOT NULL,
+    userid          INTEGER NOT NULL,
+    role            TEXT NOT NULL,
+    PRIMARY KEY (id, userid),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE,
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
+)`)
+}
+
+// migrateSessions adds the table of login sessions.
+func migrateSessions(tx *sql.Tx) error {
+	return execAll(tx, `
+CREATE TABLE IF NOT EXISTS sessions (
+    id              TEXT PRIMARY KEY,
+    userid          INTEGER NOT NULL,
+    username        TEXT,
+    expires         TEXT,
+    FOREIGN KEY (userid) REFERENCES users (userid) ON DELETE CASCADE
+)`)
+}
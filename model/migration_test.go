@@ -0,0 +1,169 @@
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/philhanna/cwcomp"
+	"github.com/stretchr/testify/assert"
+)
+
+// The schema before there was a schema_version table, when all puzzles
+// were square
+const legacyDDL = `
+CREATE TABLE users (
+    userid          INTEGER PRIMARY KEY,
+    username        TEXT NOT NULL UNIQUE,
+    password        BLOB NOT NULL,
+    created         TEXT,
+    email           TEXT,
+    confirmed       TEXT,
+    author_name     TEXT,
+    address_line_1  TEXT,
+    address_line_2  TEXT,
+    address_city    TEXT,
+    address_state   TEXT,
+    address_zip     TEXT
+);
+INSERT INTO users (userid, username, password) VALUES(1, 'test', X'00');
+CREATE TABLE puzzles (
+    id              INTEGER PRIMARY KEY,
+    userid          INTEGER NOT NULL,
+    puzzlename      TEXT NOT NULL UNIQUE,
+    created         TEXT,
+    modified        TEXT,
+    n               INTEGER
+);
+CREATE TABLE cells (
+    id              INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    letter          TEXT,
+    PRIMARY KEY (id, r, c),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+);
+CREATE TABLE words (
+    id              INTEGER,
+    r               INTEGER,
+    c               INTEGER,
+    dir             TEXT,
+    length          INTEGER,
+    clue            TEXT,
+    PRIMARY KEY (id, r, c, dir),
+    FOREIGN KEY (id) REFERENCES puzzles (id) ON DELETE CASCADE
+);
+INSERT INTO puzzles VALUES(1, 1, 'legacy', '2020-01-01', '2020-01-02', 3);
+INSERT INTO cells VALUES(1, 1, 1, 'C'), (1, 1, 2, 'A'), (1, 1, 3, 'T'), (1, 2, 2, X'00');
+INSERT INTO words VALUES(1, 1, 1, 'A', 3, 'Feline');
+`
+
+// Returns the column names of each table in the test database
+func getSchema(t *testing.T) map[string][]string {
+	con, err := Connect()
+	assert.Nil(t, err)
+	defer con.Close()
+
+	rows, err := con.Query(`SELECT name FROM sqlite_master WHERE type='table'`)
+	assert.Nil(t, err)
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		rows.Scan(&table)
+		tables = append(tables, table)
+	}
+	rows.Close()
+
+	schema := make(map[string][]string)
+	for _, table := range tables {
+		rows, err := con.Query(`SELECT name FROM pragma_table_info(?)`, table)
+		assert.Nil(t, err)
+		for rows.Next() {
+			var column string
+			rows.Scan(&column)
+			schema[table] = append(schema[table], column)
+		}
+		rows.Close()
+	}
+	return schema
+}
+
+func TestMigrate_NewDatabase(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		version, err := repo.SchemaVersion()
+		assert.Nil(t, err)
+		assert.Equal(t, LatestSchemaVersion(), version)
+
+		applied, err := repo.Migrate()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+
+		statuses, err := repo.MigrationStatus()
+		assert.Nil(t, err)
+		assert.Equal(t, len(migrations), len(statuses))
+		for _, status := range statuses {
+			assert.NotEqual(t, "", status.Applied)
+		}
+	})(t)
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	runtest(func(*testing.T) {
+		want := getSchema(t)
+
+		// Replace the test database with one made from the old DDL
+		os.Remove(cwcomp.GetConfiguration().DATABASE.NAME)
+		con, err := Connect()
+		assert.Nil(t, err)
+		_, err = con.Exec(legacyDDL)
+		assert.Nil(t, err)
+		con.Close()
+
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		version, err := repo.SchemaVersion()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, version)
+		statuses, err := repo.MigrationStatus()
+		assert.Nil(t, err)
+		assert.Equal(t, "", statuses[0].Applied)
+
+		// Upgrade it in place
+		applied, err := repo.Migrate()
+		assert.Nil(t, err)
+		assert.Equal(t, len(migrations), len(applied))
+		version, err = repo.SchemaVersion()
+		assert.Nil(t, err)
+		assert.Equal(t, LatestSchemaVersion(), version)
+		assert.Equal(t, want, getSchema(t))
+
+		// The old puzzle can still be loaded, and is its first revision
+		puzzle, err := repo.LoadPuzzle(TEST_USERID, "legacy")
+		assert.Nil(t, err)
+		assert.Equal(t, 3, puzzle.GetRows())
+		assert.Equal(t, 3, puzzle.GetCols())
+		assert.Equal(t, ROTATIONAL, puzzle.GetSymmetry())
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		assert.Equal(t, "CAT", puzzle.GetText(word))
+		clue, _ := puzzle.GetClue(word)
+		assert.Equal(t, "Feline", clue)
+		assert.True(t, puzzle.IsBlackCell(NewPoint(2, 2)))
+
+		revisions, err := repo.GetRevisions(TEST_USERID, "legacy")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(revisions))
+		first, err := repo.LoadRevision(TEST_USERID, "legacy", 1)
+		assert.Nil(t, err)
+		assert.Equal(t, puzzle.String(), first.String())
+
+		// Migrating again does nothing
+		applied, err = repo.Migrate()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})(t)
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 )
@@ -51,16 +52,38 @@ func (p *Point) Equal(other Point) bool {
 	return *p == other
 }
 
+// MarshalJSON writes the point as {"r": r, "c": c}.
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		R int `json:"r"`
+		C int `json:"c"`
+	}{p.r, p.c})
+}
+
+// UnmarshalJSON reads a point written by MarshalJSON.
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		R int `json:"r"`
+		C int `json:"c"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*p = NewPoint(obj.R, obj.C)
+	return nil
+}
+
 // PointIterator is a generator for all the points in the grid, from
-// top bottom and left to right (i.e, (1, 1), (1, 2), ..., (1, n),
-// (2, 1), (2, 2), ..., (2, n), ..., (n, 1) (n, 2), ..., (n, n)).
+// top bottom and left to right (i.e, (1, 1), (1, 2), ..., (1, nCols),
+// (2, 1), (2, 2), ..., (2, nCols), ..., (nRows, 1) (nRows, 2), ...,
+// (nRows, nCols)).
 func (puzzle *Puzzle) PointIterator() <-chan Point {
 	out := make(chan Point)
 	go func() {
 		defer close(out)
-		n := puzzle.n
-		for r := 1; r <= n; r++ {
-			for c := 1; c <= n; c++ {
+		nRows, nCols := puzzle.nRows, puzzle.nCols
+		for r := 1; r <= nRows; r++ {
+			for c := 1; c <= nCols; c++ {
 				out <- NewPoint(r, c)
 			}
 		}
@@ -70,10 +93,10 @@ func (puzzle *Puzzle) PointIterator() <-chan Point {
 
 // SymmetricPoint returns the point of the cell at 180 degrees rotation.
 func (puzzle *Puzzle) SymmetricPoint(point Point) Point {
-	n := puzzle.n
+	nRows, nCols := puzzle.nRows, puzzle.nCols
 	r := point.r
 	c := point.c
-	return NewPoint(n+1-r, n+1-c)
+	return NewPoint(nRows+1-r, nCols+1-c)
 }
 
 // ToXY converts a point (that uses 1-based coordinates) to a pair (x,
@@ -87,8 +110,8 @@ func (p *Point) ToXY() (int, int) {
 // ValidIndex whether a point is a valid index in this grid.
 func (puzzle *Puzzle) ValidIndex(point Point) error {
 	r, c := point.r, point.c
-	validRow := r >= 1 && r <= puzzle.n
-	validCol := c >= 1 && c <= puzzle.n
+	validRow := r >= 1 && r <= puzzle.nRows
+	validCol := c >= 1 && c <= puzzle.nCols
 	if validRow && validCol {
 		return nil
 	}
@@ -34,6 +34,20 @@ func TestPuzzle_PointIterator(t *testing.T) {
 	assert.Equal(t, list1, list2)
 }
 
+func TestPuzzle_PointIterator_Rectangular(t *testing.T) {
+	const nRows, nCols = 2, 3
+	puzzle := NewRectangularPuzzle(nRows, nCols)
+	want := []Point{
+		{1, 1}, {1, 2}, {1, 3},
+		{2, 1}, {2, 2}, {2, 3},
+	}
+	have := make([]Point, 0)
+	for point := range puzzle.PointIterator() {
+		have = append(have, point)
+	}
+	assert.Equal(t, want, have)
+}
+
 func TestPuzzle_SymmetricPoint(t *testing.T) {
 	puzzle := NewPuzzle(9)
 	tests := []struct {
@@ -185,3 +199,28 @@ func TestPuzzle_ValidIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestPuzzle_SymmetricPoint_Rectangular(t *testing.T) {
+	puzzle := NewRectangularPuzzle(15, 21)
+	tests := []struct {
+		p  Point
+		sp Point
+	}{
+		{NewPoint(1, 1), NewPoint(15, 21)},
+		{NewPoint(3, 5), NewPoint(13, 17)},
+		{NewPoint(8, 11), NewPoint(8, 11)},
+	}
+	for _, tt := range tests {
+		want := tt.sp
+		have := puzzle.SymmetricPoint(tt.p)
+		assert.Equal(t, want, have)
+	}
+}
+
+func TestPuzzle_ValidIndex_Rectangular(t *testing.T) {
+	puzzle := NewRectangularPuzzle(15, 21)
+	assert.Nil(t, puzzle.ValidIndex(NewPoint(15, 21)))
+	assert.NotNil(t, puzzle.ValidIndex(NewPoint(21, 15)))
+	assert.NotNil(t, puzzle.ValidIndex(NewPoint(16, 1)))
+	assert.NotNil(t, puzzle.ValidIndex(NewPoint(1, 22)))
+}
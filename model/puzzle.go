@@ -3,8 +3,7 @@ package model
 import (
 	"errors"
 	"fmt"
-
-	"github.com/philhanna/collections"
+	"strings"
 )
 
 // ---------------------------------------------------------------------
@@ -13,56 +12,65 @@ import (
 
 // Puzzle contains the cells of a grid.
 //
-// The grid is constructed with the single parameter n, which is the size
-// (n x n) of the grid.
+// The grid is constructed with the number of rows and the number of
+// columns, which need not be the same.  A square grid of size n x n can
+// be created with NewPuzzle(n).
 //
 // Any of the cells in the puzzle can be "black cells", which act as the
 // boundaries of where the words can go. The model automatically takes
-// care of matching a black cell with its symmetric twin 180 degrees
+// care of matching a black cell with its symmetric twin(s), according to
+// the puzzle's symmetry mode.  By default, this is the twin 180 degrees
 // from it.
 //
 // Wherever an across or down word starts, the puzzle assigns the next
 // available word number to the cell and keeps track of the lengths of
 // the across and down words.
 //
-// Puzzle supports a full "undo/redo" capability for the current session
-// (from load to save).  Any black cell additions or deletions are
-// pushed on an undo collections.
+// Puzzle supports a full "undo/redo" capability.  Every change to black
+// cells, symmetry, letters, words, clues, or the puzzle name is recorded
+// in one ordered history (see History), which can be saved with the
+// puzzle so that a later session can resume with it.
 type Puzzle struct {
-	n              int                       // Size of the grid (n x n square)
-	puzzleName     string                    // The puzzle name
-	cells          [][]Cell                  // Black cells and letter cells
-	words          []*Word                   // Pointers to the words in this grid
-	wordNumbers    []*WordNumber             // Word number pointers
-	undoPointStack collections.Stack[Point]  // Undo stack for black cells
-	redoPointStack collections.Stack[Point]  // Redo stack for black cells
-	undoWordStack  collections.Stack[Doable] // Undo stack for whole words
-	redoWordStack  collections.Stack[Doable] // Redo stack for whold words
+	nRows       int           // Number of rows in the grid
+	nCols       int           // Number of columns in the grid
+	puzzleName  string        // The puzzle name
+	symmetry    Symmetry      // How black cells are mirrored
+	cells       [][]Cell      // Black cells and letter cells
+	words       []*Word       // Pointers to the words in this grid
+	wordNumbers []*WordNumber // Word number pointers
+	history     History       // Undo/redo history
+	userWords   *UserWords    // User's words merged with the dictionary
+	revision    int           // Revision loaded from or last saved as
 }
 
 // ---------------------------------------------------------------------
 // Constructor
 // ---------------------------------------------------------------------
 
-// NewPuzzle creates a puzzle of the specified size.
+// NewPuzzle creates a square puzzle of the specified size.
 func NewPuzzle(n int) *Puzzle {
-	g := new(Puzzle)
-	g.n = n
+	return NewRectangularPuzzle(n, n)
+}
 
-	// Create an n x n matrix of cell objects
-	g.cells = make([][]Cell, n)
-	for i := 0; i < n; i++ {
-		g.cells[i] = make([]Cell, n)
-		for j := 0; j < n; j++ {
+// NewRectangularPuzzle creates a puzzle with the specified number of
+// rows and columns.
+func NewRectangularPuzzle(nRows, nCols int) *Puzzle {
+	g := new(Puzzle)
+	g.nRows = nRows
+	g.nCols = nCols
+	g.symmetry = ROTATIONAL
+
+	// Create an nRows x nCols matrix of cell objects
+	g.cells = make([][]Cell, nRows)
+	for i := 0; i < nRows; i++ {
+		g.cells[i] = make([]Cell, nCols)
+		for j := 0; j < nCols; j++ {
 			point := NewPoint(i+1, j+1)
 			cell := NewLetterCell(point)
 			g.cells[i][j] = cell
 		}
 	}
 
-	g.undoPointStack = collections.NewStack[Point]()
-	g.redoPointStack = collections.NewStack[Point]()
-
 	return g
 }
 
@@ -101,11 +109,27 @@ func (puzzle *Puzzle) GetClue(word *Word) (string, error) {
 	return word.clue, nil
 }
 
+// GetCols returns the number of columns in the grid
+func (puzzle *Puzzle) GetCols() int {
+	return puzzle.nCols
+}
+
 // GetPuzzleName returns the puzzle name
 func (puzzle *Puzzle) GetPuzzleName() string {
 	return puzzle.puzzleName
 }
 
+// GetRevision returns the number of the revision that the puzzle was
+// loaded from or last saved as, or 0 if it has not been.
+func (puzzle *Puzzle) GetRevision() int {
+	return puzzle.revision
+}
+
+// GetRows returns the number of rows in the grid
+func (puzzle *Puzzle) GetRows() int {
+	return puzzle.nRows
+}
+
 // GetLength returns the length of the word.
 func (puzzle *Puzzle) GetLength(word *Word) (int, error) {
 	err := puzzle.wordPointerIsValid(word)
@@ -116,8 +140,9 @@ func (puzzle *Puzzle) GetLength(word *Word) (int, error) {
 }
 
 // GetLetter returns the value of the cell at this point.  The length of
-// the returned value is always 1, unless the point refers to a black
-// cell, in which case the length is zero.
+// the returned value is 1, unless the point refers to a black cell, in
+// which case the length is zero, or to a rebus cell, in which case it is
+// the number of letters in the cell.
 func (puzzle *Puzzle) GetLetter(point Point) string {
 	letter := ""
 	cell := puzzle.GetCell(point)
@@ -131,34 +156,19 @@ func (puzzle *Puzzle) GetLetter(point Point) string {
 	return letter
 }
 
-// GetText returns the text of the word.
+// GetText returns the text of the word.  Rebus cells are enclosed in
+// brackets (see SplitCells).
 func (puzzle *Puzzle) GetText(word *Word) string {
 	err := puzzle.wordPointerIsValid(word)
 	if err != nil {
 		return ""
 	}
-
-	length, _ := puzzle.GetLength(word)
-
-	var s string
-	var point Point
-	for i := 0; i < length; i++ {
-		switch word.direction {
-		case ACROSS:
-			point = NewPoint(word.point.r, word.point.c+i)
-		case DOWN:
-			point = NewPoint(word.point.r+i, word.point.c)
-		}
-		letter := puzzle.GetLetter(point)
-		s += letter
-	}
-	return s
+	return JoinCells(puzzle.GetCellTexts(word))
 }
 
 // ImportPuzzle creates a puzzle from an external source
 func ImportPuzzle(source Importer) (*Puzzle, error) {
-	n := source.GetSize()
-	puzzle := NewPuzzle(n)
+	puzzle := NewRectangularPuzzle(source.GetRows(), source.GetCols())
 	puzzle.puzzleName = source.GetName()
 	for point := range puzzle.PointIterator() {
 		r, c := point.r, point.c
@@ -170,9 +180,20 @@ func ImportPuzzle(source Importer) (*Puzzle, error) {
 			// Black cell
 			puzzle.cells[i][j] = NewBlackCell(point)
 		} else {
-			// Letter cell
+			// Letter cell, which may be a rebus
 			lc := NewLetterCell(point)
 			lc.letter = string(value)
+			if rebusSource, ok := source.(RebusImporter); ok {
+				if rebus := rebusSource.GetRebus(r, c); len(rebus) > 1 {
+					lc.letter = strings.ToUpper(rebus)
+				}
+			}
+			switch styleSource := source.(type) {
+			case StyleImporter:
+				lc.style = styleSource.GetCellStyle(r, c)
+			case CircleImporter:
+				lc.style.Circled = styleSource.IsCircled(r, c)
+			}
 			puzzle.cells[i][j] = lc
 		}
 	}
@@ -195,6 +216,9 @@ func ImportPuzzle(source Importer) (*Puzzle, error) {
 	setClues(ACROSS, source.GetAcrossClues)
 	setClues(DOWN, source.GetDownClues)
 
+	// The imported puzzle starts with no history
+	puzzle.ClearHistory()
+
 	return puzzle, nil
 }
 
@@ -250,9 +274,20 @@ func (puzzle *Puzzle) LookupWordNumberForStartingPoint(point Point) *WordNumber
 }
 
 // RenumberCells assigns the word numbers based on the locations of the
-// black cells.
+// black cells.  Words that still start at the same point in the same
+// direction keep their clues.
 func (puzzle *Puzzle) RenumberCells() {
 
+	// Save the existing clues
+	type wordKey struct {
+		point     Point
+		direction Direction
+	}
+	oldClues := make(map[wordKey]string)
+	for _, word := range puzzle.words {
+		oldClues[wordKey{word.point, word.direction}] = word.clue
+	}
+
 	// Get the word numbers
 	cells := PuzzleToSimpleMatrix(puzzle)
 	ncs := GetNumberedCells(cells)
@@ -269,14 +304,14 @@ func (puzzle *Puzzle) RenumberCells() {
 	for _, nc := range ncs {
 		point := NewPoint(nc.Row, nc.Col)
 		if nc.StartA {
-			word := NewWord(point, ACROSS, 0, "")
+			word := NewWord(point, ACROSS, 0, oldClues[wordKey{point, ACROSS}])
 			for range puzzle.WordIterator(point, ACROSS) {
 				word.length++
 			}
 			puzzle.words = append(puzzle.words, word)
 		}
 		if nc.StartD {
-			word := NewWord(point, DOWN, 0, "")
+			word := NewWord(point, DOWN, 0, oldClues[wordKey{point, DOWN}])
 			for range puzzle.WordIterator(point, DOWN) {
 				word.length++
 			}
@@ -291,17 +326,41 @@ func (puzzle *Puzzle) SetCell(point Point, cell Cell) {
 	puzzle.cells[y][x] = cell
 }
 
-// SetClue sets the specified clue in the specified word.
+// SetClue sets the specified clue in the specified word.  The change is
+// recorded in the history.
 func (puzzle *Puzzle) SetClue(word *Word, clue string) error {
 	if word == nil {
 		return fmt.Errorf("word pointer is nil")
 	}
+	puzzle.record(&Command{
+		Kind:   CLUE_COMMAND,
+		Point:  word.point,
+		Dir:    word.direction,
+		Before: word.clue,
+		After:  clue,
+	})
 	word.clue = clue
 	return nil
 }
 
-// SetLetter sets the letter value of the cell at the specified point
+// SetLetter sets the letter value of the cell at the specified point.
+// The change is recorded in the history.
 func (puzzle *Puzzle) SetLetter(point Point, letter string) {
+	if _, ok := puzzle.GetCell(point).(LetterCell); !ok {
+		return
+	}
+	puzzle.record(&Command{
+		Kind:   LETTER_COMMAND,
+		Point:  point,
+		Before: puzzle.GetLetter(point),
+		After:  letter,
+	})
+	puzzle.setLetter(point, letter)
+}
+
+// setLetter sets the letter value of the cell at the specified point
+// without recording the change in the history.
+func (puzzle *Puzzle) setLetter(point Point, letter string) {
 	cell := puzzle.GetCell(point)
 	switch typedCell := cell.(type) {
 	case LetterCell:
@@ -310,12 +369,20 @@ func (puzzle *Puzzle) SetLetter(point Point, letter string) {
 	}
 }
 
-// SetPuzzleName sets the puzzle name
+// SetPuzzleName sets the puzzle name.  The change is recorded in the
+// history.
 func (puzzle *Puzzle) SetPuzzleName(name string) {
+	puzzle.record(&Command{
+		Kind:   RENAME_COMMAND,
+		Before: puzzle.puzzleName,
+		After:  name,
+	})
 	puzzle.puzzleName = name
 }
 
-// SetText sets the text in the puzzle for a specified word.
+// SetText sets the text in the puzzle for a specified word.  A rebus
+// cell is written with its letters enclosed in brackets, e.g.,
+// "[HEART]BREAK" (see SplitCells).
 func (puzzle *Puzzle) SetText(word *Word, text string) error {
 
 	// Make sure this is a valid word pointer
@@ -324,43 +391,57 @@ func (puzzle *Puzzle) SetText(word *Word, text string) error {
 		return err
 	}
 
-	// Make sure the text is not longer than the word allows
-	if len(text) > word.length {
-		errmsg := fmt.Sprintf(`Text %q length > expected %d`, len(text), word.length)
+	// Make sure the text is valid and not longer than the word allows
+	cells, err := SplitCells(text)
+	if err != nil {
+		return err
+	}
+	if len(cells) > word.length {
+		errmsg := fmt.Sprintf(`Text %q length %d > expected %d`, text, len(cells), word.length)
 		err := errors.New(errmsg)
 		return err
 	}
 
-	// Create a new Doable for this word and push it on the undoWordStack
-	doable := NewDoable(puzzle, word)
-	puzzle.undoWordStack.Push(doable)
-
 	// Pad the text with blanks if too short
-	for len(text) < word.length {
-		text += " "
+	for len(cells) < word.length {
+		cells = append(cells, " ")
 	}
 
+	// Record the change in the history
+	puzzle.record(&Command{
+		Kind:   WORD_COMMAND,
+		Point:  word.point,
+		Dir:    word.direction,
+		Before: puzzle.GetText(word),
+		After:  JoinCells(cells),
+	})
+
 	// Iterate through the points of the word, storing the text into it
-	// letter by letter.
-	puzzle.SetTextWithoutPush(word, text)
+	// cell by cell.
+	puzzle.SetTextWithoutPush(word, JoinCells(cells))
 
 	// OK
 	return nil
 }
 
+// SetTextWithoutPush sets the text of the word without recording the
+// change in the history.  The text is assumed to be valid.
 func (puzzle *Puzzle) SetTextWithoutPush(word *Word, text string) {
+	cells, _ := SplitCells(text)
 	i := 0
 	for point := range puzzle.WordIterator(word.point, word.direction) {
-		ch := text[i]
+		if i >= len(cells) {
+			// The word has grown since the text was saved
+			continue
+		}
+		puzzle.setLetter(point, cells[i])
 		i++
-		letter := string(ch)
-		puzzle.SetLetter(point, letter)
 	}
 }
 
 // String returns a string representation of the puzzle
 func (puzzle *Puzzle) String() string {
-	n := puzzle.n
+	nRows, nCols := puzzle.nRows, puzzle.nCols
 	sb := ""
 	if puzzle.puzzleName == "" {
 		sb += "(Untitled)"
@@ -371,23 +452,23 @@ func (puzzle *Puzzle) String() string {
 
 	// Row of column numbers at the top
 	sb += "    " // indent for row numbers
-	for c := 1; c <= n; c++ {
+	for c := 1; c <= nCols; c++ {
 		sb += fmt.Sprintf(" %2d ", c)
 	}
 	sb += "\n"
 
 	// Separator line
 	sep := "    " // indent for row numbers
-	for c := 1; c <= n; c++ {
+	for c := 1; c <= nCols; c++ {
 		sep += "+---"
 	}
 	sep += "+"
 
 	// Each row
-	for r := 1; r <= n; r++ {
+	for r := 1; r <= nRows; r++ {
 		sb += sep + "\n"
 		sb += fmt.Sprintf(" %2d ", r)
-		for c := 1; c <= n; c++ {
+		for c := 1; c <= nCols; c++ {
 			point := NewPoint(r, c)
 			cell := puzzle.GetCell(point)
 			switch cell.(type) {
@@ -395,7 +476,12 @@ func (puzzle *Puzzle) String() string {
 				sb += "|***"
 			case LetterCell:
 				letter := puzzle.GetLetter(point)
-				sb += fmt.Sprintf("| %s ", letter)
+				if len(letter) > 1 {
+					// Mark a rebus cell with its first letter and a "+"
+					sb += fmt.Sprintf("| %s+", letter[:1])
+				} else {
+					sb += fmt.Sprintf("| %s ", letter)
+				}
 			}
 		}
 		sb += "|"
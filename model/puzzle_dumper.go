@@ -6,7 +6,7 @@ import (
 )
 
 // DumpPuzzle is a diagnostic function that shows the exact composition
-// of each cell in the grid.
+// of each cell in the grid, the clues, and the grid statistics.
 func DumpPuzzle(puzzle *Puzzle) {
 
 	fmt.Printf(puzzle.String())
@@ -37,14 +37,17 @@ func DumpPuzzle(puzzle *Puzzle) {
 
 	fmt.Println("Down:")
 	dumpClues(DOWN)
+
+	fmt.Println("Statistics:")
+	fmt.Print(puzzle.GetStatistics().String())
 }
 
 // TracePuzzle is a diagnostic function that shows how each cell is
 // constructed
 func TracePuzzle(puzzle *Puzzle) {
-	n := puzzle.n
-	for r := 1; r <= n; r++ {
-		for c := 1; c <= n; c++ {
+	nRows, nCols := puzzle.nRows, puzzle.nCols
+	for r := 1; r <= nRows; r++ {
+		for c := 1; c <= nCols; c++ {
 			point := NewPoint(r, c)
 			cell := puzzle.GetCell(point)
 			switch typedCell := cell.(type) {
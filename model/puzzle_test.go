@@ -385,6 +385,7 @@ func TestPuzzle_SetText(t *testing.T) {
 	)
 
 	puzzle := getGoodPuzzle()
+	puzzle.ClearHistory()
 	type testWord struct {
 		seq  int
 		dir  Direction
@@ -399,7 +400,8 @@ func TestPuzzle_SetText(t *testing.T) {
 		assert.NotNil(t, word)
 		err = puzzle.SetText(word, sw.text)
 		assert.Nil(t, err)
-		assert.Equal(t, i+1, puzzle.undoWordStack.Len())
+		undo, _ := puzzle.GetHistory()
+		assert.Equal(t, i+1, len(undo))
 	}
 
 	values := []testWord{
@@ -505,3 +507,21 @@ func TestPuzzle_LookupWordNumberForStartingPoint(t *testing.T) {
 	have = puzzle.LookupWordNumberForStartingPoint(point)
 	assert.Nil(t, have)
 }
+
+func TestPuzzle_RenumberCells_KeepsClues(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	kept := puzzle.LookupWord(NewPoint(9, 1), ACROSS)
+	puzzle.SetClue(kept, "Bottom row")
+	lost := puzzle.LookupWord(NewPoint(1, 2), ACROSS)
+	puzzle.SetClue(lost, "Top row")
+
+	// Moving a black cell changes the first across word, but not the
+	// last one
+	puzzle.Toggle(NewPoint(1, 1))
+	puzzle.RenumberCells()
+
+	clue, _ := puzzle.GetClue(puzzle.LookupWord(NewPoint(9, 1), ACROSS))
+	assert.Equal(t, "Bottom row", clue)
+	clue, _ = puzzle.GetClue(puzzle.LookupWord(NewPoint(1, 1), ACROSS))
+	assert.Equal(t, "", clue)
+}
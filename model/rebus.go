@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// RebusImporter is an optional extension of the Importer interface for
+// sources that support rebus cells, i.e., cells containing more than
+// one letter.
+type RebusImporter interface {
+	Importer
+
+	// Returns the full text of the cell at a given point in the grid
+	// (relative to 1, not 0) if it is a rebus cell, or "" otherwise.
+	GetRebus(r, c int) string
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// In the text of a word, a rebus cell is written with its letters
+// enclosed in these brackets, e.g., "[HEART]BREAK" for a six-cell word.
+const (
+	REBUS_START = '['
+	REBUS_END   = ']'
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// JoinCells is the inverse of SplitCells.  It creates the text of a
+// word from the contents of its cells, enclosing multi-letter cells in
+// brackets.
+func JoinCells(cells []string) string {
+	sb := strings.Builder{}
+	for _, cell := range cells {
+		if len(cell) > 1 {
+			sb.WriteRune(REBUS_START)
+			sb.WriteString(cell)
+			sb.WriteRune(REBUS_END)
+		} else {
+			sb.WriteString(cell)
+		}
+	}
+	return sb.String()
+}
+
+// SplitCells splits the text of a word into the contents of its cells.
+// Each character is one cell, except that letters enclosed in brackets
+// are a single rebus cell.  An error is returned if the brackets are
+// unbalanced, nested, or empty.
+func SplitCells(text string) ([]string, error) {
+	cells := make([]string, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case REBUS_START:
+			end := strings.IndexByte(text[i+1:], REBUS_END)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed %q in %q", REBUS_START, text)
+			}
+			cell := text[i+1 : i+1+end]
+			if cell == "" || strings.ContainsRune(cell, REBUS_START) {
+				return nil, fmt.Errorf("invalid rebus cell in %q", text)
+			}
+			cells = append(cells, cell)
+			i += end + 1
+		case REBUS_END:
+			return nil, fmt.Errorf("unexpected %q in %q", REBUS_END, text)
+		default:
+			cells = append(cells, text[i:i+1])
+		}
+	}
+	return cells, nil
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetAnswer returns the letters of the word without any rebus
+// brackets, e.g., "HEARTBREAK".  This is the form that is looked up in
+// the dictionary.
+func (puzzle *Puzzle) GetAnswer(word *Word) string {
+	return strings.Join(puzzle.GetCellTexts(word), "")
+}
+
+// GetCellTexts returns the contents of each cell of the word.  An empty
+// cell is " ".
+func (puzzle *Puzzle) GetCellTexts(word *Word) []string {
+	if err := puzzle.wordPointerIsValid(word); err != nil {
+		return nil
+	}
+	texts := make([]string, 0, word.length)
+	for point := range puzzle.WordIterator(word.point, word.direction) {
+		texts = append(texts, puzzle.GetLetter(point))
+	}
+	return texts
+}
+
+// IsRebus returns true if the cell at this point contains more than one
+// letter.
+func (puzzle *Puzzle) IsRebus(point Point) bool {
+	return len(puzzle.GetLetter(point)) > 1
+}
+
+// PuzzleToTextMatrix builds an nRows x nCols matrix of the full
+// contents of the cells, where "" represents a black cell and " " an
+// empty cell.  Unlike PuzzleToSimpleMatrix, rebus cells are not
+// truncated to their first letter.
+func PuzzleToTextMatrix(puzzle *Puzzle) [][]string {
+	nRows, nCols := puzzle.nRows, puzzle.nCols
+	texts := make([][]string, nRows)
+	for i := 0; i < nRows; i++ {
+		texts[i] = make([]string, nCols)
+		for j := 0; j < nCols; j++ {
+			texts[i][j] = puzzle.GetLetter(NewPoint(i+1, j+1))
+		}
+	}
+	return texts
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCells(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"plain", "NOW", []string{"N", "O", "W"}, false},
+		{"blanks", "N W", []string{"N", " ", "W"}, false},
+		{"rebus first", "[HEART]BY", []string{"HEART", "B", "Y"}, false},
+		{"rebus last", "BY[HEART]", []string{"B", "Y", "HEART"}, false},
+		{"two rebus", "[ONE][TWO]", []string{"ONE", "TWO"}, false},
+		{"empty", "", []string{}, false},
+		{"unclosed", "[HEART", nil, true},
+		{"unopened", "HEART]", nil, true},
+		{"nested", "[HE[A]RT]", nil, true},
+		{"empty rebus", "A[]B", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := SplitCells(tt.text)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, have)
+			assert.Equal(t, tt.text, JoinCells(have))
+		})
+	}
+}
+
+func TestPuzzle_SetText_Rebus(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+
+	assert.Nil(t, puzzle.SetText(word, "[HEART]OW"))
+	assert.Equal(t, "[HEART]OW", puzzle.GetText(word))
+	assert.Equal(t, "HEARTOW", puzzle.GetAnswer(word))
+	assert.Equal(t, []string{"HEART", "O", "W"}, puzzle.GetCellTexts(word))
+	assert.True(t, puzzle.IsRebus(NewPoint(1, 2)))
+	assert.False(t, puzzle.IsRebus(NewPoint(1, 3)))
+
+	// The crossing word sees the whole rebus cell
+	down := puzzle.LookupWordByNumber(1, DOWN)
+	assert.Equal(t, "[HEART]", puzzle.GetText(down)[:7])
+
+	// Too many cells and bad brackets are rejected
+	assert.NotNil(t, puzzle.SetText(word, "[HEART]OWS"))
+	assert.NotNil(t, puzzle.SetText(word, "[HEARTOW"))
+
+	// Undo restores the previous text
+	puzzle.Undo()
+	assert.Equal(t, "   ", puzzle.GetText(word))
+	puzzle.Redo()
+	assert.Equal(t, "[HEART]OW", puzzle.GetText(word))
+}
+
+func TestPuzzleToTextMatrix(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+	puzzle.SetText(word, "N[OW]W")
+
+	texts := PuzzleToTextMatrix(puzzle)
+	assert.Equal(t, 9, len(texts))
+	assert.Equal(t, []string{"", "N", "OW", "W", ""}, texts[0][:5])
+	assert.Equal(t, byte('O'), PuzzleToSimpleMatrix(puzzle)[0][2])
+}
+
+func TestPuzzle_SavePuzzle_Rebus(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "rebus-test"
+		puzzle := getGoodPuzzle()
+		puzzle.SetPuzzleName(puzzleName)
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		assert.Nil(t, puzzle.SetText(word, "[HEART]OW"))
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+		loaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		word = loaded.LookupWordByNumber(1, ACROSS)
+		assert.Equal(t, "[HEART]OW", loaded.GetText(word))
+	})(t)
+}
@@ -10,8 +10,8 @@ import (
 
 type NumberedCell struct {
 	Seq    int  // The word number (1, 2, ...)
-	Row    int  // The row number (1, 2, ..., n)
-	Col    int  // The column number (1, 2, ..., n)
+	Row    int  // The row number (1, 2, ..., nRows)
+	Col    int  // The column number (1, 2, ..., nCols)
 	StartA bool // This is the start of an across word
 	StartD bool // This is the start of a down word
 }
@@ -21,13 +21,15 @@ type NumberedCell struct {
 // ---------------------------------------------------------------------
 
 // GetNumberedCells determines the points in the grid that are the start
-// of an across word and/or a down word.
+// of an across word and/or a down word.  The matrix need not be square,
+// but all its rows must be the same length.
 func GetNumberedCells(cells [][]byte) []NumberedCell {
-	var n = len(cells)
+	var nRows = len(cells)
 	var seq = 0
 	ncs := make([]NumberedCell, 0)
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
+	for i := 0; i < nRows; i++ {
+		nCols := len(cells[i])
+		for j := 0; j < nCols; j++ {
 			if cells[i][j] != BLACK_CELL {
 				startD := (i == 0) || (cells[i-1][j] == 0)
 				startA := (j == 0) || (cells[i][j-1] == 0)
@@ -49,15 +51,17 @@ func GetNumberedCells(cells [][]byte) []NumberedCell {
 	return ncs
 }
 
-// PuzzleToSimpleMatrix builds a simple representation of a grid as an n
-// x n matrix of bytes, where '\x00' represents a black cell, and the
-// rest are the letters in that cell.
+// PuzzleToSimpleMatrix builds a simple representation of a grid as an
+// nRows x nCols matrix of bytes, where '\x00' represents a black cell,
+// and the rest are the letters in that cell.  A rebus cell is
+// represented by its first letter; use PuzzleToTextMatrix to get the
+// full contents.
 func PuzzleToSimpleMatrix(puzzle *Puzzle) [][]byte {
-	n := puzzle.n
-	cells := make([][]byte, n)
-	for i := 0; i < n; i++ {
-		cells[i] = make([]byte, n)
-		for j := 0; j < n; j++ {
+	nRows, nCols := puzzle.nRows, puzzle.nCols
+	cells := make([][]byte, nRows)
+	for i := 0; i < nRows; i++ {
+		cells[i] = make([]byte, nCols)
+		for j := 0; j < nCols; j++ {
 			point := NewPoint(i+1, j+1)
 			if puzzle.IsBlackCell(point) {
 				cells[i][j] = BLACK_CELL
@@ -78,3 +78,22 @@ func TestGetNumberedCells(t *testing.T) {
 
 	assert.Equal(t, 25, len(ncs))
 }
+
+func TestGetNumberedCells_Rectangular(t *testing.T) {
+	puzzle := NewRectangularPuzzle(3, 5)
+	puzzle.Toggle(NewPoint(1, 1))
+	cells := PuzzleToSimpleMatrix(puzzle)
+	assert.Equal(t, 3, len(cells))
+	assert.Equal(t, 5, len(cells[0]))
+
+	ncs := GetNumberedCells(cells)
+	want := []NumberedCell{
+		{1, 1, 2, true, true},
+		{2, 1, 3, false, true},
+		{3, 1, 4, false, true},
+		{4, 1, 5, false, true},
+		{5, 2, 1, true, true},
+		{6, 3, 1, true, false},
+	}
+	assert.Equal(t, want, ncs)
+}
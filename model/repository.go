@@ -0,0 +1,781 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Repository reads and writes puzzles in the database.  Each method
+// runs in a single transaction, so that a failure part way through
+// leaves the database as it was, and every error is returned.  Missing
+// puzzles are reported with a NotFoundError, and names that are already
+// in use with a NameConflictError.
+type Repository struct {
+	db *sql.DB
+}
+
+// NotFoundError is returned when a puzzle, a revision of one, a user,
+// a share of a puzzle with a user, a session, or a word in a user's word
+// list is not in the database.
+// It matches ErrNotFound with errors.Is.
+type NotFoundError struct {
+	Name     string // Name of the puzzle, if looked up by name
+	ID       int    // ID of the puzzle, if looked up by ID
+	Revision int    // Revision number, if a revision was looked up
+	Username string // Name of the user, if a user was looked up by name
+	UserID   int    // ID of the user, if a user was looked up by ID
+	Session  string // ID of the session, if a session was looked up
+	Word     string // Word, if a user's word was looked up
+}
+
+// NameConflictError is returned when a puzzle cannot be given a name
+// because the user already has a puzzle by that name, or a user cannot
+// be added because the username is taken.  It matches ErrNameConflict
+// with errors.Is.
+type NameConflictError struct {
+	Name     string // Name of the puzzle, if a puzzle was named
+	Username string // Name of the user, if a user was added
+}
+
+// ChangedError is returned when a copy of a puzzle is saved over one
+// that has been saved since the copy was loaded, so that saving it
+// would lose the other changes.  It matches ErrChanged with errors.Is.
+type ChangedError struct {
+	ID       int // ID of the puzzle
+	Revision int // Revision the copy was loaded from
+	Latest   int // Revision now in the database
+}
+
+// queryer is the part of the interface shared by *sql.DB and *sql.Tx
+// that the repository uses.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrNameConflict = errors.New("name already used")
+	ErrChanged      = errors.New("changed since it was loaded")
+)
+
+// ---------------------------------------------------------------------
+// Constructor
+// ---------------------------------------------------------------------
+
+// NewRepository opens a repository on the cwcomp database.  The caller
+// must close it when done.
+func NewRepository() (*Repository, error) {
+	db, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	repo := new(Repository)
+	repo.db = db
+	return repo, nil
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Error returns the error message for a missing puzzle, revision,
+// user, share, session, or user's word.
+func (e *NotFoundError) Error() string {
+	switch {
+	case e.Word != "":
+		return fmt.Sprintf("word %q not found in the word list of user %d", e.Word, e.UserID)
+	case e.Session != "":
+		return fmt.Sprintf("session id %q not found", e.Session)
+	case e.Username != "" && e.Name != "":
+		return fmt.Sprintf("puzzle %q is not shared with user %q", e.Name, e.Username)
+	case e.Username != "":
+		return fmt.Sprintf("no user named %q found", e.Username)
+	case e.UserID != 0:
+		return fmt.Sprintf("no user with id %d found", e.UserID)
+	case e.Revision != 0:
+		return fmt.Sprintf("no revision %d of puzzle %q found", e.Revision, e.Name)
+	case e.Name == "":
+		return fmt.Sprintf("no puzzle with id %d found", e.ID)
+	default:
+		return fmt.Sprintf("no puzzle named %q found", e.Name)
+	}
+}
+
+// Is makes a NotFoundError match ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Error returns the error message for a name that is already used.
+func (e *NameConflictError) Error() string {
+	if e.Username != "" {
+		return fmt.Sprintf("username %q is already used", e.Username)
+	}
+	return fmt.Sprintf("puzzle name %q is already used", e.Name)
+}
+
+// Is makes a NameConflictError match ErrNameConflict.
+func (e *NameConflictError) Is(target error) bool {
+	return target == ErrNameConflict
+}
+
+// Error returns the error message for a puzzle that has changed.
+func (e *ChangedError) Error() string {
+	return fmt.Sprintf("puzzle %d has been saved as revision %d since this copy of revision %d was loaded",
+		e.ID, e.Latest, e.Revision)
+}
+
+// Is makes a ChangedError match ErrChanged.
+func (e *ChangedError) Is(target error) bool {
+	return target == ErrChanged
+}
+
+// Close closes the repository's database connection.
+func (repo *Repository) Close() error {
+	return repo.db.Close()
+}
+
+// DeletePuzzle deletes a puzzle, along with its cells, words, history,
+// and revisions.
+func (repo *Repository) DeletePuzzle(userid int, puzzlename string) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM puzzles WHERE userid=? AND puzzlename=?`,
+			userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &NotFoundError{Name: puzzlename}
+		}
+		return nil
+	})
+}
+
+// GetPuzzleList returns the names of the user's puzzles, in order of
+// when they were last modified.
+func (repo *Repository) GetPuzzleList(userid int) ([]string, error) {
+	puzzlenames := make([]string, 0)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT		puzzlename
+			FROM		puzzles
+			WHERE		userid = ?
+			ORDER BY	modified`, userid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var puzzlename string
+			if err := rows.Scan(&puzzlename); err != nil {
+				return err
+			}
+			puzzlenames = append(puzzlenames, puzzlename)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzlenames, nil
+}
+
+// LoadHistory replaces the puzzle's undo/redo history with the one
+// saved by SaveHistory.  The puzzle must already be saved under its
+// name.
+func (repo *Repository) LoadHistory(userid int, puzzle *Puzzle) error {
+	history := History{}
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzle.puzzleName)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.Query(`
+			SELECT		command, undone
+			FROM		history
+			WHERE		id=?
+			ORDER BY	seq`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				jsonstr string
+				undone  bool
+			)
+			if err := rows.Scan(&jsonstr, &undone); err != nil {
+				return err
+			}
+			cmd := new(Command)
+			if err := json.Unmarshal([]byte(jsonstr), cmd); err != nil {
+				return err
+			}
+			history.commands = append(history.commands, cmd)
+			if !undone {
+				history.cursor = len(history.commands)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+	puzzle.history = history
+	return nil
+}
+
+// LoadPuzzle reads a puzzle from the database.  The puzzle starts with
+// no undo/redo history (see LoadHistory), and remembers the revision it
+// was loaded from (see GetRevision).
+func (repo *Repository) LoadPuzzle(userid int, puzzlename string) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT	id, nrows, ncols, symmetry
+			FROM	puzzles
+			WHERE	userid=? AND puzzlename=?`,
+			userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		var (
+			id, nRows, nCols int
+			symmetryName     sql.NullString
+		)
+		found := rows.Next()
+		if found {
+			err = rows.Scan(&id, &nRows, &nCols, &symmetryName)
+		}
+		rows.Close()
+		switch {
+		case err != nil:
+			return err
+		case !found:
+			return &NotFoundError{Name: puzzlename}
+		}
+
+		puzzle, err = populatePuzzle(tx, puzzlename, nRows, nCols, symmetryName.String,
+			`SELECT r, c, letter, circled, shade, bars FROM cells WHERE id=?`,
+			`SELECT r, c, dir, clue FROM words WHERE id=?`,
+			id)
+		if err != nil {
+			return err
+		}
+		puzzle.revision, err = latestRevision(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzle, nil
+}
+
+// LookupPuzzleID returns the ID of the user's puzzle with the specified
+// name.
+func (repo *Repository) LookupPuzzleID(userid int, puzzlename string) (int, error) {
+	var id int
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		id, err = lookupPuzzleID(tx, userid, puzzlename)
+		return err
+	})
+	return id, err
+}
+
+// LookupPuzzleName returns the name of the user's puzzle with the
+// specified ID.
+func (repo *Repository) LookupPuzzleName(userid int, id int) (string, error) {
+	var puzzlename string
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT puzzlename FROM puzzles WHERE userid=? AND id=?`,
+			userid, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return &NotFoundError{ID: id}
+		}
+		return rows.Scan(&puzzlename)
+	})
+	if err != nil {
+		return "", err
+	}
+	return puzzlename, nil
+}
+
+// PuzzleNameUsed returns true if the user already has a puzzle with the
+// specified name.
+func (repo *Repository) PuzzleNameUsed(userid int, puzzlename string) (bool, error) {
+	var used bool
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		used, err = puzzleNameUsed(tx, userid, puzzlename)
+		return err
+	})
+	return used, err
+}
+
+// RenamePuzzle renames one of the user's puzzles.  The new name must not
+// already be in use.
+func (repo *Repository) RenamePuzzle(userid int, oldPuzzleName, newPuzzleName string) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		if _, err := lookupPuzzleID(tx, userid, oldPuzzleName); err != nil {
+			return err
+		}
+		if newPuzzleName == oldPuzzleName {
+			return nil
+		}
+		used, err := puzzleNameUsed(tx, userid, newPuzzleName)
+		switch {
+		case err != nil:
+			return err
+		case used:
+			return &NameConflictError{Name: newPuzzleName}
+		}
+		_, err = tx.Exec(`
+			UPDATE	puzzles
+			SET		puzzlename=?
+			WHERE	userid=?
+			AND		puzzlename=?`,
+			newPuzzleName, userid, oldPuzzleName)
+		return nameConflict(err, newPuzzleName)
+	})
+}
+
+// SaveHistory writes the puzzle's undo/redo history to the database,
+// replacing any that was saved before.  The puzzle must already be
+// saved under its name.
+func (repo *Repository) SaveHistory(userid int, puzzle *Puzzle) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzle.puzzleName)
+		if err != nil {
+			return err
+		}
+		return storeHistory(tx, id, puzzle)
+	})
+}
+
+// SavePuzzle adds or updates a record for this puzzle in the database.
+// If there is already a record, its ID and creation time are kept (so
+// that the ID can still be used as a key by clients), but its cells and
+// words are replaced.  Each save also adds a new revision (see
+// GetRevisions), so earlier versions of the puzzle are not lost.
+func (repo *Repository) SavePuzzle(userid int, puzzle *Puzzle) error {
+	var revision int
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		revision, err = storePuzzle(tx, userid, puzzle)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	puzzle.revision = revision
+	return nil
+}
+
+// SavePuzzleWithHistory saves the puzzle, as SavePuzzle does, and its
+// undo/redo history, as SaveHistory does, in a single transaction, so
+// that the history saved is always that of the puzzle saved.
+func (repo *Repository) SavePuzzleWithHistory(userid int, puzzle *Puzzle) error {
+	var revision int
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		revision, err = storePuzzle(tx, userid, puzzle)
+		if err != nil {
+			return err
+		}
+		id, err := lookupPuzzleID(tx, userid, puzzle.puzzleName)
+		if err != nil {
+			return err
+		}
+		return storeHistory(tx, id, puzzle)
+	})
+	if err != nil {
+		return err
+	}
+	puzzle.revision = revision
+	return nil
+}
+
+// SavePuzzleByID saves the puzzle and its undo/redo history over the
+// puzzle with the specified ID, in a single transaction.  Unlike
+// SavePuzzle, this never adds a puzzle: if there is no puzzle with the
+// ID, because it has been deleted, a NotFoundError is returned.  The
+// name in the database is kept, even if the puzzle was renamed since
+// this copy was loaded, and the copy is given that name.
+//
+// If the puzzle has been saved (or a revision of it restored) since
+// this copy was loaded or last saved, a ChangedError is returned and
+// nothing is saved, unless overwrite is true.
+func (repo *Repository) SavePuzzleByID(id int, puzzle *Puzzle, overwrite bool) error {
+	var (
+		puzzlename string
+		revision   int
+	)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		puzzlename, err = lookupPuzzleNameByID(tx, id)
+		if err != nil {
+			return err
+		}
+		latest, err := latestRevision(tx, id)
+		if err != nil {
+			return err
+		}
+		if latest != puzzle.revision && !overwrite {
+			return &ChangedError{ID: id, Revision: puzzle.revision, Latest: latest}
+		}
+		modified := time.Now().Format(time.RFC3339)
+		revision, err = storePuzzleData(tx, id, puzzle, modified)
+		if err != nil {
+			return err
+		}
+		return storeHistory(tx, id, puzzle)
+	})
+	if err != nil {
+		return err
+	}
+	puzzle.puzzleName = puzzlename
+	puzzle.revision = revision
+	return nil
+}
+
+// inTransaction runs a function in a transaction, which is committed if
+// the function returns nil and rolled back otherwise.
+func (repo *Repository) inTransaction(f func(tx *sql.Tx) error) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// withRepository opens a repository, runs a function with it, and
+// closes it again.
+func withRepository(f func(repo *Repository) error) error {
+	repo, err := NewRepository()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	return f(repo)
+}
+
+// lookupPuzzleID returns the ID of the puzzle with the specified name,
+// provided it belongs to the specified user.
+func lookupPuzzleID(q queryer, userid int, puzzlename string) (int, error) {
+	rows, err := q.Query(`SELECT id FROM puzzles WHERE userid=? AND puzzlename=?`,
+		userid, puzzlename)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, &NotFoundError{Name: puzzlename}
+	}
+	var id int
+	if err := rows.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// nameConflict converts a unique constraint violation on the puzzle
+// name into a NameConflictError.  Any other error is returned as is.
+func nameConflict(err error, puzzlename string) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return &NameConflictError{Name: puzzlename}
+	}
+	return err
+}
+
+// populatePuzzle creates a puzzle of the specified size and symmetry,
+// and fills it in from the database.  The cell query must select the
+// columns r, c, letter, circled, shade, and bars, and the word query
+// must select r, c, dir, and clue, both using the same arguments.
+func populatePuzzle(q queryer, puzzlename string, nRows, nCols int, symmetryName string,
+	cellQuery, wordQuery string, args ...any) (*Puzzle, error) {
+
+	// Create an empty puzzle and begin populating it from the database
+	puzzle := NewRectangularPuzzle(nRows, nCols)
+	puzzle.SetPuzzleName(puzzlename)
+	symmetry, err := SymmetryFromString(symmetryName)
+	if err != nil {
+		return nil, err
+	}
+	if err := puzzle.SetSymmetry(symmetry); err != nil {
+		return nil, err
+	}
+
+	// Populate the cells (black cells and other)
+	if err := populateCells(q, puzzle, cellQuery, args...); err != nil {
+		return nil, err
+	}
+
+	// Renumber the puzzle to create the word and word number arrays
+	puzzle.RenumberCells()
+
+	// Populate the words
+	if err := populateWords(q, puzzle, wordQuery, args...); err != nil {
+		return nil, err
+	}
+
+	// The loaded puzzle starts with no history (see LoadHistory)
+	puzzle.ClearHistory()
+
+	// Return the newly reconstituted puzzle with no error
+	return puzzle, nil
+}
+
+// populateCells sets the black cells, letters, and cell styles of a
+// puzzle from the rows selected by the cell query.
+func populateCells(q queryer, puzzle *Puzzle, cellQuery string, args ...any) error {
+	rows, err := q.Query(cellQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			r, c        int
+			letter      string
+			circled     sql.NullBool
+			shade, bars sql.NullString
+		)
+		if err := rows.Scan(&r, &c, &letter, &circled, &shade, &bars); err != nil {
+			return err
+		}
+		point := NewPoint(r, c)
+		if err := puzzle.ValidIndex(point); err != nil {
+			return err
+		}
+		switch letter {
+		case string(BLACK_CELL):
+			puzzle.SetCell(point, NewBlackCell(point))
+		default:
+			puzzle.SetLetter(point, letter)
+			style := CellStyle{
+				Circled: circled.Bool,
+				Shade:   shade.String,
+				Bars:    Bars(bars.String),
+			}
+			if !style.IsPlain() {
+				puzzle.SetCellStyle(point, style)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// populateWords sets the clues of a puzzle from the rows selected by
+// the word query.  Words that are no longer in the grid are ignored.
+func populateWords(q queryer, puzzle *Puzzle, wordQuery string, args ...any) error {
+	rows, err := q.Query(wordQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			r, c      int
+			dir, clue string
+		)
+		if err := rows.Scan(&r, &c, &dir, &clue); err != nil {
+			return err
+		}
+		word := puzzle.LookupWord(NewPoint(r, c), DirectionFromString(dir))
+		if word == nil {
+			continue
+		}
+		puzzle.SetClue(word, clue)
+	}
+	return rows.Err()
+}
+
+// storePuzzle saves a puzzle and a new revision of it, as described in
+// Repository.SavePuzzle, and returns the number of the revision.
+func storePuzzle(tx *sql.Tx, userid int, puzzle *Puzzle) (int, error) {
+
+	// Ensure the puzzle has been named
+	puzzlename := puzzle.GetPuzzleName()
+	if puzzlename == "" {
+		return 0, fmt.Errorf("cannot save a puzzle without a name")
+	}
+	modified := time.Now().Format(time.RFC3339)
+
+	id, err := lookupPuzzleID(tx, userid, puzzlename)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNotFound):
+		created := modified
+		result, err := tx.Exec(`
+			INSERT INTO puzzles(userid, puzzlename, created, modified, nrows, ncols, symmetry)
+			VALUES(?, ?, ?, ?, ?, ?, ?)`,
+			userid, puzzlename, created, modified, puzzle.nRows, puzzle.nCols, puzzle.symmetry)
+		if err != nil {
+			return 0, nameConflict(err, puzzlename)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = int(lastID)
+	default:
+		return 0, err
+	}
+	return storePuzzleData(tx, id, puzzle, modified)
+}
+
+// storePuzzleData replaces the cells and words of the puzzle with the
+// specified ID, which must already be in the puzzles table, and adds a
+// new revision of it, whose number is returned.
+func storePuzzleData(tx *sql.Tx, id int, puzzle *Puzzle, modified string) (int, error) {
+	_, err := tx.Exec(`
+		UPDATE	puzzles
+		SET		modified=?, nrows=?, ncols=?, symmetry=?
+		WHERE	id=?`,
+		modified, puzzle.nRows, puzzle.nCols, puzzle.symmetry, id)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`DELETE FROM cells WHERE id=?`, id); err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`DELETE FROM words WHERE id=?`, id); err != nil {
+		return 0, err
+	}
+
+	// Save the cell data in the cells table
+	for cell := range puzzle.CellIterator() {
+		var (
+			letter string
+			style  CellStyle
+		)
+		point := cell.GetPoint()
+		switch typedCell := cell.(type) {
+		case LetterCell:
+			letter = typedCell.letter
+			style = typedCell.style
+		case BlackCell:
+			letter = string(BLACK_CELL)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO cells(id, r, c, letter, circled, shade, bars)
+			VALUES(?, ?, ?, ?, ?, ?, ?)`,
+			id, point.r, point.c, letter, style.Circled, style.Shade, string(style.Bars))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Save the word data in the words table
+	for _, word := range puzzle.words {
+		point := word.point
+		_, err = tx.Exec(`
+			INSERT INTO words(id, r, c, dir, length, clue)
+			VALUES(?, ?, ?, ?, ?, ?)`,
+			id, point.r, point.c, word.direction, word.length, word.clue)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Keep a copy of what was saved as the next revision
+	return saveRevision(tx, id, modified)
+}
+
+// lookupPuzzleNameByID returns the name of the puzzle with the
+// specified ID, whoever owns it.
+func lookupPuzzleNameByID(q queryer, id int) (string, error) {
+	rows, err := q.Query(`SELECT puzzlename FROM puzzles WHERE id=?`, id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", &NotFoundError{ID: id}
+	}
+	var puzzlename string
+	if err := rows.Scan(&puzzlename); err != nil {
+		return "", err
+	}
+	return puzzlename, nil
+}
+
+// storeHistory replaces the undo/redo history of the puzzle with the
+// specified ID, as described in Repository.SaveHistory.
+func storeHistory(tx *sql.Tx, id int, puzzle *Puzzle) error {
+	if _, err := tx.Exec(`DELETE FROM history WHERE id=?`, id); err != nil {
+		return err
+	}
+	h := &puzzle.history
+	for seq, cmd := range h.commands {
+		jsonBlob, err := json.Marshal(cmd)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO history(id, seq, command, undone)
+			VALUES(?, ?, ?, ?)`,
+			id, seq, string(jsonBlob), seq >= h.cursor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// puzzleNameUsed returns true if the user already has a puzzle with the
+// specified name.
+func puzzleNameUsed(q queryer, userid int, puzzlename string) (bool, error) {
+	_, err := lookupPuzzleID(q, userid, puzzlename)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
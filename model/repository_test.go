@@ -0,0 +1,188 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_NotFound(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		_, err = repo.LoadPuzzle(TEST_USERID, "bogus")
+		assert.True(t, errors.Is(err, ErrNotFound))
+		var notFound *NotFoundError
+		assert.True(t, errors.As(err, &notFound))
+		assert.Equal(t, "bogus", notFound.Name)
+		assert.Equal(t, `no puzzle named "bogus" found`, err.Error())
+
+		_, err = repo.LookupPuzzleName(TEST_USERID, 12345)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, "no puzzle with id 12345 found", err.Error())
+
+		err = repo.DeletePuzzle(TEST_USERID, "bogus")
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		err = repo.RenamePuzzle(TEST_USERID, "bogus", "other")
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), "real"))
+		_, err = repo.LoadRevision(TEST_USERID, "real", 7)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, `no revision 7 of puzzle "real" found`, err.Error())
+		assert.False(t, errors.Is(err, ErrNameConflict))
+	})(t)
+}
+
+func TestRepository_NameConflict(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), "first"))
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), "second"))
+
+		err = repo.RenamePuzzle(TEST_USERID, "first", "second")
+		assert.True(t, errors.Is(err, ErrNameConflict))
+		var conflict *NameConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, "second", conflict.Name)
+		assert.Equal(t, `puzzle name "second" is already used`, err.Error())
+
+		_, err = repo.ForkRevision(TEST_USERID, "first", 1, "second")
+		assert.True(t, errors.Is(err, ErrNameConflict))
+
+		// Renaming a puzzle to its own name is not a conflict
+		assert.Nil(t, repo.RenamePuzzle(TEST_USERID, "first", "first"))
+
+		used, err := repo.PuzzleNameUsed(TEST_USERID, "second")
+		assert.Nil(t, err)
+		assert.True(t, used)
+	})(t)
+}
+
+func TestRepository_SavePuzzle_Rollback(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Atomic"
+
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, puzzleName))
+
+		// Make the last step of the save fail, after the cells and words
+		// have been replaced
+		_, err = repo.db.Exec(`DROP TABLE revision_words`)
+		assert.Nil(t, err)
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		puzzle.SetText(word, "HOW")
+		assert.NotNil(t, repo.SavePuzzle(TEST_USERID, puzzle))
+
+		// The puzzle is still as it was before the save
+		reloaded, err := repo.LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, "NOW", reloaded.GetText(reloaded.LookupWordByNumber(1, ACROSS)))
+	})(t)
+}
+
+func TestRepository_GetPuzzleList(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		names, err := repo.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{}, names)
+
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), "listed"))
+		names, err = repo.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"listed"}, names)
+
+		id, err := repo.LookupPuzzleID(TEST_USERID, "listed")
+		assert.Nil(t, err)
+		name, err := repo.LookupPuzzleName(TEST_USERID, id)
+		assert.Nil(t, err)
+		assert.Equal(t, "listed", name)
+
+		assert.Nil(t, repo.DeletePuzzle(TEST_USERID, "listed"))
+		names, err = repo.GetPuzzleList(TEST_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(names))
+	})(t)
+}
+
+func TestRepository_SavePuzzleByID(t *testing.T) {
+	runtest(func(t *testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, "byid"))
+		id, err := repo.LookupPuzzleID(TEST_USERID, "byid")
+		assert.Nil(t, err)
+
+		// A stale name is replaced by the one in the database
+		assert.Nil(t, repo.RenamePuzzle(TEST_USERID, "byid", "byid-renamed"))
+		puzzle.SetClue(puzzle.LookupWordByNumber(1, ACROSS), "Changed")
+		assert.Nil(t, repo.SavePuzzleByID(id, puzzle, false))
+		assert.Equal(t, "byid-renamed", puzzle.GetPuzzleName())
+		names, _ := repo.GetPuzzleList(TEST_USERID)
+		assert.Equal(t, []string{"byid-renamed"}, names)
+		reloaded, err := repo.LoadPuzzle(TEST_USERID, "byid-renamed")
+		assert.Nil(t, err)
+		assert.Equal(t, "Changed", reloaded.LookupWordByNumber(1, ACROSS).clue)
+
+		// A deleted puzzle is not added again
+		assert.Nil(t, repo.DeletePuzzle(TEST_USERID, "byid-renamed"))
+		assert.ErrorIs(t, repo.SavePuzzleByID(id, puzzle, false), ErrNotFound)
+		names, _ = repo.GetPuzzleList(TEST_USERID)
+		assert.Equal(t, 0, len(names))
+	})(t)
+}
+
+func TestRepository_SavePuzzleByID_Changed(t *testing.T) {
+	runtest(func(t *testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), "changed"))
+		id, _ := repo.LookupPuzzleID(TEST_USERID, "changed")
+		first, _ := repo.LoadPuzzle(TEST_USERID, "changed")
+		second, _ := repo.LoadPuzzle(TEST_USERID, "changed")
+		assert.Equal(t, 1, first.GetRevision())
+
+		// The first save wins, and the second is refused
+		first.SetClue(first.LookupWordByNumber(1, ACROSS), "First")
+		assert.Nil(t, repo.SavePuzzleByID(id, first, false))
+		assert.Equal(t, 2, first.GetRevision())
+		second.SetClue(second.LookupWordByNumber(1, ACROSS), "Second")
+		err = repo.SavePuzzleByID(id, second, false)
+		var changed *ChangedError
+		assert.True(t, errors.As(err, &changed))
+		assert.ErrorIs(t, err, ErrChanged)
+		assert.Equal(t, 1, changed.Revision)
+		assert.Equal(t, 2, changed.Latest)
+
+		// A restored revision also counts as a change
+		_, err = repo.RestoreRevision(TEST_USERID, "changed", 1)
+		assert.Nil(t, err)
+		assert.ErrorIs(t, repo.SavePuzzleByID(id, first, false), ErrChanged)
+
+		// Unless it is overwritten
+		assert.Nil(t, repo.SavePuzzleByID(id, second, true))
+		assert.Equal(t, 4, second.GetRevision())
+		reloaded, _ := repo.LoadPuzzle(TEST_USERID, "changed")
+		assert.Equal(t, "Second", reloaded.LookupWordByNumber(1, ACROSS).clue)
+	})(t)
+}
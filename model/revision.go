@@ -0,0 +1,429 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Revision is one saved version of a puzzle.  Revisions are numbered
+// 1, 2, ... in the order they were saved.
+type Revision struct {
+	Number int    `json:"revision"`
+	Saved  string `json:"saved"`
+}
+
+// DifferenceKind identifies the type of change found by DiffPuzzles
+type DifferenceKind string
+
+// Difference is one change between two versions of a puzzle:
+//   - SIZE_DIFFERENCE: the size changed from Before to After, e.g.,
+//     "15x15" to "15x21"
+//   - SYMMETRY_DIFFERENCE: the symmetry changed from Before to After
+//   - CELL_DIFFERENCE: the cell at Point changed from Before to After,
+//     where a black cell is shown as "." and an empty cell as " ", as in
+//     the AcrossLite text format
+//   - CLUE_DIFFERENCE: the clue of the word starting at Point in
+//     direction Dir changed from Before to After.  If the word is only
+//     in one of the versions, its clue in the other is "".
+type Difference struct {
+	Kind   DifferenceKind `json:"kind"`
+	Point  Point          `json:"point"`
+	Dir    Direction      `json:"dir,omitempty"`
+	Before string         `json:"before"`
+	After  string         `json:"after"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+const (
+	SIZE_DIFFERENCE     DifferenceKind = "size"
+	SYMMETRY_DIFFERENCE DifferenceKind = "symmetry"
+	CELL_DIFFERENCE     DifferenceKind = "cell"
+	CLUE_DIFFERENCE     DifferenceKind = "clue"
+)
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// String returns a string representation of this difference.
+func (d Difference) String() string {
+	switch d.Kind {
+	case CELL_DIFFERENCE:
+		return fmt.Sprintf("%s %s %q -> %q", d.Kind, d.Point.String(), d.Before, d.After)
+	case CLUE_DIFFERENCE:
+		return fmt.Sprintf("%s %s %s %q -> %q", d.Kind, d.Point.String(), d.Dir.String(), d.Before, d.After)
+	default:
+		return fmt.Sprintf("%s %q -> %q", d.Kind, d.Before, d.After)
+	}
+}
+
+// DiffRevisions returns the differences between two saved revisions of
+// a puzzle, as described in DiffPuzzles.
+func (repo *Repository) DiffRevisions(userid int, puzzlename string, from, to int) ([]Difference, error) {
+	var diffs []Difference
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		before, err := loadRevision(tx, userid, puzzlename, from)
+		if err != nil {
+			return err
+		}
+		after, err := loadRevision(tx, userid, puzzlename, to)
+		if err != nil {
+			return err
+		}
+		diffs = DiffPuzzles(before, after)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// ForkRevision saves a copy of a revision of a puzzle as a new puzzle
+// with the specified name, and returns it.  The new name must not
+// already be in use by this user.
+func (repo *Repository) ForkRevision(userid int, puzzlename string, revision int, newPuzzleName string) (*Puzzle, error) {
+	if newPuzzleName == "" {
+		return nil, fmt.Errorf("cannot save a puzzle without a name")
+	}
+	var puzzle *Puzzle
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		puzzle, err = loadRevision(tx, userid, puzzlename, revision)
+		if err != nil {
+			return err
+		}
+		used, err := puzzleNameUsed(tx, userid, newPuzzleName)
+		switch {
+		case err != nil:
+			return err
+		case used:
+			return &NameConflictError{Name: newPuzzleName}
+		}
+		puzzle.SetPuzzleName(newPuzzleName)
+		puzzle.ClearHistory()
+		puzzle.revision, err = storePuzzle(tx, userid, puzzle)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzle, nil
+}
+
+// GetRevisions returns the saved revisions of a puzzle, oldest first.
+func (repo *Repository) GetRevisions(userid int, puzzlename string) ([]Revision, error) {
+	revisions := make([]Revision, 0)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.Query(`
+			SELECT		revision, saved
+			FROM		revisions
+			WHERE		id=?
+			ORDER BY	revision`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			rev := Revision{}
+			if err := rows.Scan(&rev.Number, &rev.Saved); err != nil {
+				return err
+			}
+			revisions = append(revisions, rev)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// LoadRevision reads a saved revision of a puzzle from the database.
+// The puzzle has the current name of the saved puzzle and no history.
+func (repo *Repository) LoadRevision(userid int, puzzlename string, revision int) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		puzzle, err = loadRevision(tx, userid, puzzlename, revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzle, nil
+}
+
+// RestoreRevision makes a saved revision of a puzzle the current
+// version, and returns it.  This is saved as a new revision, so the
+// versions since the restored one are not lost, and the saved undo/redo
+// history is cleared in the same transaction.
+func (repo *Repository) RestoreRevision(userid int, puzzlename string, revision int) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		puzzle, err = loadRevision(tx, userid, puzzlename, revision)
+		if err != nil {
+			return err
+		}
+		puzzle.revision, err = storePuzzle(tx, userid, puzzle)
+		if err != nil {
+			return err
+		}
+		id, err := lookupPuzzleID(tx, userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		return storeHistory(tx, id, puzzle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzle, nil
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// DiffRevisions is a shortcut for Repository.DiffRevisions.
+func DiffRevisions(userid int, puzzlename string, from, to int) ([]Difference, error) {
+	var diffs []Difference
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		diffs, err = repo.DiffRevisions(userid, puzzlename, from, to)
+		return err
+	})
+	return diffs, err
+}
+
+// ForkRevision is a shortcut for Repository.ForkRevision.
+func ForkRevision(userid int, puzzlename string, revision int, newPuzzleName string) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		puzzle, err = repo.ForkRevision(userid, puzzlename, revision, newPuzzleName)
+		return err
+	})
+	return puzzle, err
+}
+
+// GetRevisions is a shortcut for Repository.GetRevisions.
+func GetRevisions(userid int, puzzlename string) ([]Revision, error) {
+	var revisions []Revision
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		revisions, err = repo.GetRevisions(userid, puzzlename)
+		return err
+	})
+	return revisions, err
+}
+
+// LoadRevision is a shortcut for Repository.LoadRevision.
+func LoadRevision(userid int, puzzlename string, revision int) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		puzzle, err = repo.LoadRevision(userid, puzzlename, revision)
+		return err
+	})
+	return puzzle, err
+}
+
+// RestoreRevision is a shortcut for Repository.RestoreRevision.
+func RestoreRevision(userid int, puzzlename string, revision int) (*Puzzle, error) {
+	var puzzle *Puzzle
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		puzzle, err = repo.RestoreRevision(userid, puzzlename, revision)
+		return err
+	})
+	return puzzle, err
+}
+
+// DiffPuzzles returns the differences between two versions of a puzzle,
+// which is empty if there are none.  The size and symmetry are compared
+// first, then the cells that are in both grids (in point order), and
+// finally the clues (in order of starting point, across before down).
+func DiffPuzzles(before, after *Puzzle) []Difference {
+	diffs := make([]Difference, 0)
+
+	// Size and symmetry
+	beforeSize := fmt.Sprintf("%dx%d", before.nRows, before.nCols)
+	afterSize := fmt.Sprintf("%dx%d", after.nRows, after.nCols)
+	if beforeSize != afterSize {
+		diffs = append(diffs, Difference{Kind: SIZE_DIFFERENCE, Before: beforeSize, After: afterSize})
+	}
+	if before.symmetry != after.symmetry {
+		diffs = append(diffs, Difference{
+			Kind:   SYMMETRY_DIFFERENCE,
+			Before: string(before.symmetry),
+			After:  string(after.symmetry),
+		})
+	}
+
+	// Cells
+	cellValue := func(puzzle *Puzzle, point Point) string {
+		if puzzle.IsBlackCell(point) {
+			return "."
+		}
+		return puzzle.GetLetter(point)
+	}
+	for r := 1; r <= min(before.nRows, after.nRows); r++ {
+		for c := 1; c <= min(before.nCols, after.nCols); c++ {
+			point := NewPoint(r, c)
+			b, a := cellValue(before, point), cellValue(after, point)
+			if b != a {
+				diffs = append(diffs, Difference{Kind: CELL_DIFFERENCE, Point: point, Before: b, After: a})
+			}
+		}
+	}
+
+	// Clues
+	type wordKey struct {
+		point Point
+		dir   Direction
+	}
+	clues := func(puzzle *Puzzle) map[wordKey]string {
+		m := make(map[wordKey]string)
+		for _, word := range puzzle.words {
+			m[wordKey{word.point, word.direction}] = word.clue
+		}
+		return m
+	}
+	beforeClues, afterClues := clues(before), clues(after)
+	keys := make([]wordKey, 0)
+	for key := range beforeClues {
+		keys = append(keys, key)
+	}
+	for key := range afterClues {
+		if _, found := beforeClues[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if cmp := keys[i].point.Compare(keys[j].point); cmp != 0 {
+			return cmp < 0
+		}
+		return keys[i].dir == ACROSS && keys[j].dir == DOWN
+	})
+	for _, key := range keys {
+		b, a := beforeClues[key], afterClues[key]
+		if b != a {
+			diffs = append(diffs, Difference{Kind: CLUE_DIFFERENCE, Point: key.point, Dir: key.dir, Before: b, After: a})
+		}
+	}
+
+	return diffs
+}
+
+// loadRevision reads a saved revision of a puzzle, as described in
+// Repository.LoadRevision.
+func loadRevision(tx *sql.Tx, userid int, puzzlename string, revision int) (*Puzzle, error) {
+	id, err := lookupPuzzleID(tx, userid, puzzlename)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		SELECT	nrows, ncols, symmetry
+		FROM	revisions
+		WHERE	id=? AND revision=?`, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		nRows, nCols int
+		symmetryName sql.NullString
+	)
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&nRows, &nCols, &symmetryName)
+	}
+	rows.Close()
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, &NotFoundError{Name: puzzlename, Revision: revision}
+	}
+
+	return populatePuzzle(tx, puzzlename, nRows, nCols, symmetryName.String,
+		`SELECT r, c, letter, circled, shade, bars FROM revision_cells WHERE id=? AND revision=?`,
+		`SELECT r, c, dir, clue FROM revision_words WHERE id=? AND revision=?`,
+		id, revision)
+}
+
+// latestRevision returns the number of the last revision of the puzzle
+// with the specified ID, or 0 if it has none.
+func latestRevision(q queryer, id int) (int, error) {
+	rows, err := q.Query(`SELECT COALESCE(MAX(revision), 0) FROM revisions WHERE id=?`, id)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	revision := 0
+	if rows.Next() {
+		if err := rows.Scan(&revision); err != nil {
+			return 0, err
+		}
+	}
+	return revision, rows.Err()
+}
+
+// saveRevision copies the saved cells and words of a puzzle into the
+// next revision, and returns its number.
+func saveRevision(tx *sql.Tx, id int, saved string) (int, error) {
+	rows, err := tx.Query(`SELECT COALESCE(MAX(revision), 0) + 1 FROM revisions WHERE id=?`, id)
+	if err != nil {
+		return 0, err
+	}
+	revision := 1
+	if rows.Next() {
+		err = rows.Scan(&revision)
+	}
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO revisions(id, revision, saved, nrows, ncols, symmetry)
+		SELECT	id, ?, ?, nrows, ncols, symmetry
+		FROM	puzzles
+		WHERE	id=?`,
+		revision, saved, id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO revision_cells(id, revision, r, c, letter, circled, shade, bars)
+		SELECT	id, ?, r, c, letter, circled, shade, bars
+		FROM	cells
+		WHERE	id=?`,
+		revision, id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO revision_words(id, revision, r, c, dir, length, clue)
+		SELECT	id, ?, r, c, dir, length, clue
+		FROM	words
+		WHERE	id=?`,
+		revision, id)
+	if err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
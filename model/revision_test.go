@@ -0,0 +1,159 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPuzzle_SavePuzzle_Revisions(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Revised"
+
+		_, err := GetRevisions(TEST_USERID, puzzleName)
+		assert.NotNil(t, err)
+
+		// Each save adds a revision
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, puzzleName))
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		puzzle.SetText(word, "HOW")
+		puzzle.SetClue(word, "In what way")
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+		revisions, err := GetRevisions(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(revisions))
+		assert.Equal(t, 1, revisions[0].Number)
+		assert.Equal(t, 2, revisions[1].Number)
+		assert.NotEqual(t, "", revisions[0].Saved)
+
+		// The first revision is still there as it was saved
+		first, err := LoadRevision(TEST_USERID, puzzleName, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, puzzleName, first.GetPuzzleName())
+		assert.Equal(t, "NOW", first.GetText(first.LookupWordByNumber(1, ACROSS)))
+		assert.Equal(t, "At this time", first.LookupWordByNumber(1, ACROSS).clue)
+		assert.False(t, first.CanUndo())
+
+		_, err = LoadRevision(TEST_USERID, puzzleName, 3)
+		assert.NotNil(t, err)
+	})(t)
+}
+
+func TestDiffRevisions(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Diffed"
+
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, puzzleName))
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		puzzle.SetText(word, "HOW")
+		puzzle.SetClue(word, "In what way")
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+		want := []Difference{
+			{Kind: CELL_DIFFERENCE, Point: NewPoint(1, 2), Before: "N", After: "H"},
+			{Kind: CLUE_DIFFERENCE, Point: NewPoint(1, 2), Dir: ACROSS, Before: "At this time", After: "In what way"},
+		}
+		have, err := DiffRevisions(TEST_USERID, puzzleName, 1, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, want, have)
+
+		have, err = DiffRevisions(TEST_USERID, puzzleName, 2, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(have))
+
+		_, err = DiffRevisions(TEST_USERID, puzzleName, 1, 5)
+		assert.NotNil(t, err)
+	})(t)
+}
+
+func TestDiffPuzzles(t *testing.T) {
+	before := NewPuzzle(3)
+	before.RenumberCells()
+	before.SetClue(before.LookupWordByNumber(1, ACROSS), "Top")
+
+	after := NewRectangularPuzzle(3, 4)
+	after.SetSymmetry(NO_SYMMETRY)
+	after.Toggle(NewPoint(1, 1))
+	after.RenumberCells()
+	after.SetLetter(NewPoint(2, 2), "X")
+	after.SetClue(after.LookupWordByNumber(1, ACROSS), "Top")
+
+	want := []Difference{
+		{Kind: SIZE_DIFFERENCE, Before: "3x3", After: "3x4"},
+		{Kind: SYMMETRY_DIFFERENCE, Before: "rotational", After: "none"},
+		{Kind: CELL_DIFFERENCE, Point: NewPoint(1, 1), Before: " ", After: "."},
+		{Kind: CELL_DIFFERENCE, Point: NewPoint(2, 2), Before: " ", After: "X"},
+		{Kind: CLUE_DIFFERENCE, Point: NewPoint(1, 1), Dir: ACROSS, Before: "Top", After: ""},
+		{Kind: CLUE_DIFFERENCE, Point: NewPoint(1, 2), Dir: ACROSS, Before: "", After: "Top"},
+	}
+	have := DiffPuzzles(before, after)
+	assert.Equal(t, want, have)
+	assert.Equal(t, `cell {r:1,c:1} " " -> "."`, have[2].String())
+}
+
+func TestRestoreRevision(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Restored"
+
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, puzzleName))
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		puzzle.SetText(word, "HOW")
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+		assert.Nil(t, puzzle.SaveHistory(TEST_USERID))
+
+		// Restoring the first revision saves it as the third
+		restored, err := RestoreRevision(TEST_USERID, puzzleName, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, "NOW", restored.GetText(restored.LookupWordByNumber(1, ACROSS)))
+		revisions, _ := GetRevisions(TEST_USERID, puzzleName)
+		assert.Equal(t, 3, len(revisions))
+
+		reloaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.True(t, restored.Equal(reloaded))
+
+		// The saved history is cleared
+		assert.Nil(t, reloaded.LoadHistory(TEST_USERID))
+		assert.False(t, reloaded.CanUndo())
+
+		_, err = RestoreRevision(TEST_USERID, puzzleName, 9)
+		assert.NotNil(t, err)
+	})(t)
+}
+
+func TestForkRevision(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Original"
+
+		puzzle := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(puzzle, puzzleName))
+		word := puzzle.LookupWordByNumber(1, ACROSS)
+		puzzle.SetText(word, "HOW")
+		assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+		// The fork is a new puzzle with a revision history of its own
+		fork, err := ForkRevision(TEST_USERID, puzzleName, 1, "Copy")
+		assert.Nil(t, err)
+		assert.Equal(t, "Copy", fork.GetPuzzleName())
+		assert.Equal(t, "NOW", fork.GetText(fork.LookupWordByNumber(1, ACROSS)))
+		revisions, _ := GetRevisions(TEST_USERID, "Copy")
+		assert.Equal(t, 1, len(revisions))
+
+		// The original is unchanged
+		original, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, "HOW", original.GetText(original.LookupWordByNumber(1, ACROSS)))
+		revisions, _ = GetRevisions(TEST_USERID, puzzleName)
+		assert.Equal(t, 2, len(revisions))
+
+		// The new name must be unused and not empty
+		_, err = ForkRevision(TEST_USERID, puzzleName, 1, "Copy")
+		assert.NotNil(t, err)
+		_, err = ForkRevision(TEST_USERID, puzzleName, 1, "")
+		assert.NotNil(t, err)
+	})(t)
+}
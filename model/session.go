@@ -0,0 +1,131 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// SessionRecord is a login session as stored in the sessions table, so
+// that it outlasts a restart of the server.  Only who is logged in and
+// until when is stored, not the working copies of their puzzles.
+type SessionRecord struct {
+	ID       string
+	UserID   int
+	Username string
+	Expires  time.Time
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// DeleteExpiredSessions deletes the sessions that expired before the
+// specified time, and returns how many there were.
+func (repo *Repository) DeleteExpiredSessions(now time.Time) (int, error) {
+	var n int64
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM sessions WHERE expires < ?`, formatExpires(now))
+		if err != nil {
+			return err
+		}
+		n, err = result.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+// DeleteSession deletes the session with the specified ID, if there is
+// one.
+func (repo *Repository) DeleteSession(id string) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM sessions WHERE id=?`, id)
+		return err
+	})
+}
+
+// LoadSession returns the session with the specified ID, whether or not
+// it has expired.  If there is none, a NotFoundError is returned.
+func (repo *Repository) LoadSession(id string) (*SessionRecord, error) {
+	record := new(SessionRecord)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var expires string
+		err := tx.QueryRow(`SELECT userid, username, expires FROM sessions WHERE id=?`,
+			id).Scan(&record.UserID, &record.Username, &expires)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &NotFoundError{Session: id}
+		case err != nil:
+			return err
+		}
+		record.ID = id
+		record.Expires, err = time.Parse(time.RFC3339, expires)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// SaveSession adds a session, or replaces the one with the same ID.
+func (repo *Repository) SaveSession(record *SessionRecord) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO sessions(id, userid, username, expires)
+			VALUES(?, ?, ?, ?)`,
+			record.ID, record.UserID, record.Username, formatExpires(record.Expires))
+		return err
+	})
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// DeleteExpiredSessions is a shortcut for Repository.DeleteExpiredSessions.
+func DeleteExpiredSessions(now time.Time) (int, error) {
+	var n int
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		n, err = repo.DeleteExpiredSessions(now)
+		return err
+	})
+	return n, err
+}
+
+// DeleteSession is a shortcut for Repository.DeleteSession.
+func DeleteSession(id string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.DeleteSession(id)
+	})
+}
+
+// LoadSession is a shortcut for Repository.LoadSession.
+func LoadSession(id string) (*SessionRecord, error) {
+	var record *SessionRecord
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		record, err = repo.LoadSession(id)
+		return err
+	})
+	return record, err
+}
+
+// SaveSession is a shortcut for Repository.SaveSession.
+func SaveSession(record *SessionRecord) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.SaveSession(record)
+	})
+}
+
+// formatExpires formats an expiration time in UTC, so that the times in
+// the sessions table have the same width and can be compared as
+// strings.
+func formatExpires(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
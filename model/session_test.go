@@ -0,0 +1,64 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_Sessions(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		now := time.Now().Truncate(time.Second)
+		live := &SessionRecord{"live", TEST_USERID, "saspeh", now.Add(time.Hour)}
+		dead := &SessionRecord{"dead", OTHER_USERID, OTHER_USERNAME, now.Add(-time.Minute)}
+		assert.Nil(t, repo.SaveSession(live))
+		assert.Nil(t, repo.SaveSession(dead))
+
+		// Expired sessions can still be loaded until they are deleted
+		record, err := repo.LoadSession("dead")
+		assert.Nil(t, err)
+		assert.Equal(t, OTHER_USERNAME, record.Username)
+		assert.True(t, record.Expires.Equal(dead.Expires))
+
+		// Saving again replaces the expiration time
+		live.Expires = now.Add(2 * time.Hour)
+		assert.Nil(t, repo.SaveSession(live))
+		record, err = repo.LoadSession("live")
+		assert.Nil(t, err)
+		assert.Equal(t, TEST_USERID, record.UserID)
+		assert.True(t, record.Expires.Equal(live.Expires))
+
+		n, err := repo.DeleteExpiredSessions(now)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+		_, err = repo.LoadSession("dead")
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, `session id "dead" not found`, err.Error())
+
+		assert.Nil(t, repo.DeleteSession("live"))
+		assert.Nil(t, repo.DeleteSession("live"))
+		_, err = repo.LoadSession("live")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})(t)
+}
+
+func TestRepository_Sessions_DeletedWithUser(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		record := &SessionRecord{"other", OTHER_USERID, OTHER_USERNAME, time.Now().Add(time.Hour)}
+		assert.Nil(t, repo.SaveSession(record))
+		_, err = repo.db.Exec(`DELETE FROM users WHERE userid=?`, OTHER_USERID)
+		assert.Nil(t, err)
+		_, err = repo.LoadSession("other")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})(t)
+}
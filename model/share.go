@@ -0,0 +1,320 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Role is what a user can do with a puzzle (according to the enumerated
+// constants below).  The owner of a puzzle can do anything with it, and
+// can share it with other users as a viewer or an editor.
+type Role string
+
+const (
+	VIEW_ROLE  Role = "view"  // Can open the puzzle, but not change it
+	EDIT_ROLE  Role = "edit"  // Can change and save the puzzle
+	OWNER_ROLE Role = "owner" // Can also rename, delete, and share it
+)
+
+// Share is a user that a puzzle is shared with, and their role.
+type Share struct {
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+}
+
+// PuzzleAccess is what a user can do with a puzzle, which is either
+// their own or shared with them.  Puzzles are stored under the user ID
+// of their owner, so that is the one to load and save them with.
+type PuzzleAccess struct {
+	ID         int
+	Owner      int // User ID of the owner
+	Puzzlename string
+	Role       Role
+}
+
+// SharedPuzzle is a puzzle that another user has shared with this one.
+type SharedPuzzle struct {
+	ID         int    `json:"id"`
+	Puzzlename string `json:"puzzlename"`
+	Modified   string `json:"modified"`
+	Owner      string `json:"owner"` // Username of the owner
+	Role       Role   `json:"role"`
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Allows returns true if this role can do what the required role can.
+// An owner can do what an editor can, and an editor what a viewer can.
+func (role Role) Allows(required Role) bool {
+	rank := func(r Role) int {
+		switch r {
+		case OWNER_ROLE:
+			return 3
+		case EDIT_ROLE:
+			return 2
+		case VIEW_ROLE:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return rank(role) >= rank(required) && rank(required) > 0
+}
+
+// GetPuzzleAccess returns what the user can do with the puzzle with the
+// specified ID.  A puzzle that neither belongs to the user nor is
+// shared with them is reported as not found, the same as one that does
+// not exist.
+func (repo *Repository) GetPuzzleAccess(userid int, id int) (*PuzzleAccess, error) {
+	access := new(PuzzleAccess)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT		p.userid, p.puzzlename, s.role
+			FROM		puzzles p
+			LEFT JOIN	puzzle_shares s ON s.id = p.id AND s.userid = ?
+			WHERE		p.id = ?`,
+			userid, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return &NotFoundError{ID: id}
+		}
+		var role sql.NullString
+		if err := rows.Scan(&access.Owner, &access.Puzzlename, &role); err != nil {
+			return err
+		}
+		access.ID = id
+		switch {
+		case access.Owner == userid:
+			access.Role = OWNER_ROLE
+		case role.Valid:
+			access.Role = Role(role.String)
+		default:
+			return &NotFoundError{ID: id}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// GetShares returns the users that one of the user's puzzles is shared
+// with, in order of username.
+func (repo *Repository) GetShares(userid int, puzzlename string) ([]Share, error) {
+	shares := make([]Share, 0)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.Query(`
+			SELECT		u.username, s.role
+			FROM		puzzle_shares s
+			JOIN		users u ON u.userid = s.userid
+			WHERE		s.id = ?
+			ORDER BY	u.username`, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var share Share
+			if err := rows.Scan(&share.Username, &share.Role); err != nil {
+				return err
+			}
+			shares = append(shares, share)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// GetSharedPuzzles returns the puzzles that other users have shared with
+// this one, most recently modified first.
+func (repo *Repository) GetSharedPuzzles(userid int) ([]SharedPuzzle, error) {
+	puzzles := make([]SharedPuzzle, 0)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT		p.id, p.puzzlename, p.modified, u.username, s.role
+			FROM		puzzle_shares s
+			JOIN		puzzles p ON p.id = s.id
+			JOIN		users u ON u.userid = p.userid
+			WHERE		s.userid = ?
+			ORDER BY	p.modified DESC, p.puzzlename`, userid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				sp       SharedPuzzle
+				modified sql.NullString
+			)
+			if err := rows.Scan(&sp.ID, &sp.Puzzlename, &modified, &sp.Owner, &sp.Role); err != nil {
+				return err
+			}
+			sp.Modified = modified.String
+			puzzles = append(puzzles, sp)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return puzzles, nil
+}
+
+// SharePuzzle shares one of the user's puzzles with another user in the
+// specified role, replacing any role they had before.
+func (repo *Repository) SharePuzzle(userid int, puzzlename, username string, role Role) error {
+	if role != VIEW_ROLE && role != EDIT_ROLE {
+		return fmt.Errorf("%q is not a valid role", role)
+	}
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		shareUserID, err := lookupUserID(tx, username)
+		if err != nil {
+			return err
+		}
+		if shareUserID == userid {
+			return fmt.Errorf("cannot share a puzzle with its owner")
+		}
+		_, err = tx.Exec(`
+			INSERT INTO	puzzle_shares(id, userid, role)
+			VALUES		(?, ?, ?)
+			ON CONFLICT	(id, userid) DO UPDATE SET role=excluded.role`,
+			id, shareUserID, role)
+		return err
+	})
+}
+
+// UnsharePuzzle stops sharing one of the user's puzzles with another
+// user.
+func (repo *Repository) UnsharePuzzle(userid int, puzzlename, username string) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		id, err := lookupPuzzleID(tx, userid, puzzlename)
+		if err != nil {
+			return err
+		}
+		shareUserID, err := lookupUserID(tx, username)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM puzzle_shares WHERE id=? AND userid=?`,
+			id, shareUserID)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &NotFoundError{Name: puzzlename, Username: username}
+		}
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// GetPuzzleAccess is a shortcut for Repository.GetPuzzleAccess.
+func GetPuzzleAccess(userid int, id int) (*PuzzleAccess, error) {
+	var access *PuzzleAccess
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		access, err = repo.GetPuzzleAccess(userid, id)
+		return err
+	})
+	return access, err
+}
+
+// GetShares is a shortcut for Repository.GetShares.
+func GetShares(userid int, puzzlename string) ([]Share, error) {
+	var shares []Share
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		shares, err = repo.GetShares(userid, puzzlename)
+		return err
+	})
+	return shares, err
+}
+
+// GetSharedPuzzles is a shortcut for Repository.GetSharedPuzzles.
+func GetSharedPuzzles(userid int) ([]SharedPuzzle, error) {
+	var puzzles []SharedPuzzle
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		puzzles, err = repo.GetSharedPuzzles(userid)
+		return err
+	})
+	return puzzles, err
+}
+
+// RoleFromString parses a string for a role that a puzzle can be shared
+// with, which is either "view" or "edit".  The comparison is case
+// insensitive.
+func RoleFromString(s string) (Role, error) {
+	switch role := Role(strings.ToLower(strings.TrimSpace(s))); role {
+	case VIEW_ROLE, EDIT_ROLE:
+		return role, nil
+	default:
+		return "", fmt.Errorf("%q is not a valid role", s)
+	}
+}
+
+// SharePuzzle is a shortcut for Repository.SharePuzzle.
+func SharePuzzle(userid int, puzzlename, username string, role Role) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.SharePuzzle(userid, puzzlename, username, role)
+	})
+}
+
+// UnsharePuzzle is a shortcut for Repository.UnsharePuzzle.
+func UnsharePuzzle(userid int, puzzlename, username string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.UnsharePuzzle(userid, puzzlename, username)
+	})
+}
+
+// lookupUserID returns the ID of the user with the specified name.
+func lookupUserID(q queryer, username string) (int, error) {
+	rows, err := q.Query(`SELECT userid FROM users WHERE username=?`, username)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, &NotFoundError{Username: username}
+	}
+	var id int
+	if err := rows.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
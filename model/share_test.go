@@ -0,0 +1,155 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The user added by createTestDatabase, which puzzles are shared with
+const (
+	OTHER_USERID   = 2
+	OTHER_USERNAME = "test"
+)
+
+func TestRole_Allows(t *testing.T) {
+	tests := []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{OWNER_ROLE, OWNER_ROLE, true},
+		{OWNER_ROLE, EDIT_ROLE, true},
+		{OWNER_ROLE, VIEW_ROLE, true},
+		{EDIT_ROLE, OWNER_ROLE, false},
+		{EDIT_ROLE, EDIT_ROLE, true},
+		{EDIT_ROLE, VIEW_ROLE, true},
+		{VIEW_ROLE, EDIT_ROLE, false},
+		{VIEW_ROLE, VIEW_ROLE, true},
+		{Role(""), VIEW_ROLE, false},
+		{OWNER_ROLE, Role("bogus"), false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.Allows(tt.required))
+		})
+	}
+}
+
+func TestRoleFromString(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Role
+		wantErr bool
+	}{
+		{"view", VIEW_ROLE, false},
+		{" Edit ", EDIT_ROLE, false},
+		{"owner", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			have, err := RoleFromString(tt.s)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestPuzzle_SameNameForTwoUsers(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "Shared name"
+
+		mine := getGoodPuzzle()
+		assert.Nil(t, savePuzzle(mine, puzzleName))
+
+		// Another user saves a different puzzle under the same name
+		theirs := NewPuzzle(5)
+		theirs.SetPuzzleName(puzzleName)
+		assert.Nil(t, theirs.SavePuzzle(OTHER_USERID))
+
+		// Neither one replaces the other
+		reloaded, err := LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, mine.String(), reloaded.String())
+		reloaded, err = LoadPuzzle(OTHER_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, reloaded.GetRows())
+
+		// Nor does deleting one delete the other
+		assert.Nil(t, mine.DeletePuzzle(OTHER_USERID, puzzleName))
+		_, err = LoadPuzzle(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+	})(t)
+}
+
+func TestRepository_SharePuzzle(t *testing.T) {
+	runtest(func(*testing.T) {
+		const puzzleName = "To share"
+
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		assert.Nil(t, savePuzzle(getGoodPuzzle(), puzzleName))
+		id, err := repo.LookupPuzzleID(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+
+		// Before it is shared, the other user cannot see it
+		access, err := repo.GetPuzzleAccess(TEST_USERID, id)
+		assert.Nil(t, err)
+		assert.Equal(t, &PuzzleAccess{ID: id, Owner: TEST_USERID, Puzzlename: puzzleName, Role: OWNER_ROLE}, access)
+		_, err = repo.GetPuzzleAccess(OTHER_USERID, id)
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		// Share it as a viewer, then as an editor
+		assert.Nil(t, repo.SharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME, VIEW_ROLE))
+		access, err = repo.GetPuzzleAccess(OTHER_USERID, id)
+		assert.Nil(t, err)
+		assert.Equal(t, VIEW_ROLE, access.Role)
+		assert.Equal(t, TEST_USERID, access.Owner)
+
+		assert.Nil(t, repo.SharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME, EDIT_ROLE))
+		shares, err := repo.GetShares(TEST_USERID, puzzleName)
+		assert.Nil(t, err)
+		assert.Equal(t, []Share{{Username: OTHER_USERNAME, Role: EDIT_ROLE}}, shares)
+
+		shared, err := repo.GetSharedPuzzles(OTHER_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(shared))
+		assert.Equal(t, id, shared[0].ID)
+		assert.Equal(t, puzzleName, shared[0].Puzzlename)
+		assert.Equal(t, "saspeh", shared[0].Owner)
+		assert.Equal(t, EDIT_ROLE, shared[0].Role)
+
+		// The owner's own list does not include it twice
+		shared, err = repo.GetSharedPuzzles(TEST_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(shared))
+
+		// Errors
+		err = repo.SharePuzzle(TEST_USERID, puzzleName, "bogus", VIEW_ROLE)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, `no user named "bogus" found`, err.Error())
+		assert.NotNil(t, repo.SharePuzzle(TEST_USERID, puzzleName, "saspeh", VIEW_ROLE))
+		assert.NotNil(t, repo.SharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME, OWNER_ROLE))
+		err = repo.SharePuzzle(OTHER_USERID, puzzleName, "saspeh", VIEW_ROLE)
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		// Stop sharing it
+		assert.Nil(t, repo.UnsharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME))
+		_, err = repo.GetPuzzleAccess(OTHER_USERID, id)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		err = repo.UnsharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		assert.Equal(t, `puzzle "To share" is not shared with user "test"`, err.Error())
+
+		// Deleting the puzzle deletes its shares
+		assert.Nil(t, repo.SharePuzzle(TEST_USERID, puzzleName, OTHER_USERNAME, VIEW_ROLE))
+		assert.Nil(t, repo.DeletePuzzle(TEST_USERID, puzzleName))
+		shared, err = repo.GetSharedPuzzles(OTHER_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(shared))
+	})(t)
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Statistics is a summary of the grid of the kind that newspaper
+// editors use to decide whether a puzzle is acceptable.  A "word" here
+// is an entry of at least two letters, so the single cells between
+// black cells are not counted.
+type Statistics struct {
+	Rows              int            `json:"nrows"`
+	Cols              int            `json:"ncols"`
+	WordCount         int            `json:"wordCount"`
+	AcrossCount       int            `json:"acrossCount"`
+	DownCount         int            `json:"downCount"`
+	BlockCount        int            `json:"blockCount"`
+	AverageWordLength float64        `json:"averageWordLength"`
+	WordLengths       map[int]int    `json:"wordLengths"`
+	OpenSquares       int            `json:"openSquares"`
+	CheaterSquares    []Point        `json:"cheaterSquares"`
+	Pangram           bool           `json:"pangram"`
+	MissingLetters    string         `json:"missingLetters"`
+	LetterFrequency   map[string]int `json:"letterFrequency"`
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetStatistics computes the statistics of the puzzle:
+//   - The number of words (across, down, and total), and the number of
+//     black cells (blocks)
+//   - The average word length and a histogram of word lengths
+//   - The number of open squares, i.e., letter cells none of whose
+//     eight neighbors is a black cell or the edge of the grid
+//   - The cheater squares, i.e., black cells that could be made white
+//     without changing the number of words
+//   - Whether the filled-in letters form a pangram (use all 26 letters
+//     of the alphabet), and if not, which are missing
+//   - The number of times each letter is used
+func (puzzle *Puzzle) GetStatistics() *Statistics {
+	stats := new(Statistics)
+	stats.Rows = puzzle.nRows
+	stats.Cols = puzzle.nCols
+	stats.BlockCount = puzzle.CountBlackCells()
+
+	// Word counts and lengths
+	stats.WordLengths = make(map[int]int)
+	totalLength := 0
+	for _, word := range puzzle.words {
+		if word.length < 2 {
+			continue
+		}
+		stats.WordCount++
+		if word.direction == ACROSS {
+			stats.AcrossCount++
+		} else {
+			stats.DownCount++
+		}
+		stats.WordLengths[word.length]++
+		totalLength += word.length
+	}
+	if stats.WordCount > 0 {
+		stats.AverageWordLength = float64(totalLength) / float64(stats.WordCount)
+	}
+
+	// Open squares and cheater squares
+	cells := PuzzleToSimpleMatrix(puzzle)
+	stats.CheaterSquares = make([]Point, 0)
+	for point := range puzzle.PointIterator() {
+		i, j := point.r-1, point.c-1
+		if cells[i][j] != BLACK_CELL {
+			if isOpenSquare(cells, i, j) {
+				stats.OpenSquares++
+			}
+			continue
+		}
+		cells[i][j] = ' '
+		if countWords(cells) == stats.WordCount {
+			stats.CheaterSquares = append(stats.CheaterSquares, point)
+		}
+		cells[i][j] = BLACK_CELL
+	}
+
+	// Letters
+	stats.LetterFrequency = make(map[string]int)
+	for point := range puzzle.PointIterator() {
+		for _, ch := range puzzle.GetLetter(point) {
+			if ch >= 'A' && ch <= 'Z' {
+				stats.LetterFrequency[string(ch)]++
+			}
+		}
+	}
+	missing := strings.Builder{}
+	for ch := 'A'; ch <= 'Z'; ch++ {
+		if stats.LetterFrequency[string(ch)] == 0 {
+			missing.WriteRune(ch)
+		}
+	}
+	stats.MissingLetters = missing.String()
+	stats.Pangram = stats.MissingLetters == ""
+
+	return stats
+}
+
+// String returns a text report of the statistics.
+func (stats *Statistics) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Size: %d x %d\n", stats.Rows, stats.Cols))
+	sb.WriteString(fmt.Sprintf("Words: %d (%d across, %d down)\n",
+		stats.WordCount, stats.AcrossCount, stats.DownCount))
+	sb.WriteString(fmt.Sprintf("Blocks: %d\n", stats.BlockCount))
+	sb.WriteString(fmt.Sprintf("Average word length: %.2f\n", stats.AverageWordLength))
+
+	lengths := make([]int, 0, len(stats.WordLengths))
+	for length := range stats.WordLengths {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+	sb.WriteString("Word lengths:\n")
+	for _, length := range lengths {
+		sb.WriteString(fmt.Sprintf("\t%2d: %d\n", length, stats.WordLengths[length]))
+	}
+
+	sb.WriteString(fmt.Sprintf("Open squares: %d\n", stats.OpenSquares))
+	cheaters := make([]string, len(stats.CheaterSquares))
+	for i, point := range stats.CheaterSquares {
+		cheaters[i] = point.String()
+	}
+	sb.WriteString(fmt.Sprintf("Cheater squares: %d %s\n",
+		len(stats.CheaterSquares), strings.Join(cheaters, ",")))
+
+	if stats.Pangram {
+		sb.WriteString("Pangram: yes\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Pangram: no (missing %s)\n", stats.MissingLetters))
+	}
+	letters := make([]string, 0)
+	for ch := 'A'; ch <= 'Z'; ch++ {
+		if n := stats.LetterFrequency[string(ch)]; n > 0 {
+			letters = append(letters, fmt.Sprintf("%c:%d", ch, n))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Letter frequency: %s\n", strings.Join(letters, " ")))
+	return sb.String()
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// countWords returns the number of words of at least two letters in a
+// simple matrix of the grid.
+func countWords(cells [][]byte) int {
+	count := 0
+	nRows := len(cells)
+	for i := 0; i < nRows; i++ {
+		nCols := len(cells[i])
+		for j := 0; j < nCols; j++ {
+			if cells[i][j] == BLACK_CELL {
+				continue
+			}
+			startA := j == 0 || cells[i][j-1] == BLACK_CELL
+			if startA && j+1 < nCols && cells[i][j+1] != BLACK_CELL {
+				count++
+			}
+			startD := i == 0 || cells[i-1][j] == BLACK_CELL
+			if startD && i+1 < nRows && cells[i+1][j] != BLACK_CELL {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// isOpenSquare returns true if none of the eight neighbors of a cell
+// in a simple matrix of the grid is a black cell or off the grid.
+func isOpenSquare(cells [][]byte, i, j int) bool {
+	for di := -1; di <= 1; di++ {
+		for dj := -1; dj <= 1; dj++ {
+			ii, jj := i+di, j+dj
+			if ii < 0 || ii >= len(cells) || jj < 0 || jj >= len(cells[ii]) {
+				return false
+			}
+			if cells[ii][jj] == BLACK_CELL {
+				return false
+			}
+		}
+	}
+	return true
+}
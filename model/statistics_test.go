@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPuzzle_GetStatistics(t *testing.T) {
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+	for i, text := range []string{"CAT", "ARE", "TEA"} {
+		word := puzzle.LookupWord(NewPoint(i+1, 1), ACROSS)
+		assert.Nil(t, puzzle.SetText(word, text))
+	}
+
+	stats := puzzle.GetStatistics()
+	assert.Equal(t, 6, stats.WordCount)
+	assert.Equal(t, 3, stats.AcrossCount)
+	assert.Equal(t, 3, stats.DownCount)
+	assert.Equal(t, 0, stats.BlockCount)
+	assert.Equal(t, 3.0, stats.AverageWordLength)
+	assert.Equal(t, map[int]int{3: 6}, stats.WordLengths)
+	assert.Equal(t, 1, stats.OpenSquares)
+	assert.Equal(t, 0, len(stats.CheaterSquares))
+	assert.False(t, stats.Pangram)
+	assert.Equal(t, "BDFGHIJKLMNOPQSUVWXYZ", stats.MissingLetters)
+	assert.Equal(t, map[string]int{"A": 3, "C": 1, "E": 2, "R": 1, "T": 2}, stats.LetterFrequency)
+}
+
+func TestPuzzle_GetStatistics_Cheaters(t *testing.T) {
+
+	// A black cell in the corner does not change the word count
+	puzzle := getBlackPuzzle(3, 4, []Point{{1, 1}})
+	stats := puzzle.GetStatistics()
+	assert.Equal(t, 7, stats.WordCount)
+	assert.Equal(t, 1, stats.BlockCount)
+	assert.Equal(t, []Point{{1, 1}}, stats.CheaterSquares)
+	assert.Equal(t, map[int]int{2: 1, 3: 4, 4: 2}, stats.WordLengths)
+	assert.InDelta(t, 3.14, stats.AverageWordLength, 0.01)
+	assert.Equal(t, 1, stats.OpenSquares)
+
+	// But one in the middle of an edge does
+	puzzle = getBlackPuzzle(3, 5, []Point{{1, 3}})
+	assert.Equal(t, 0, len(puzzle.GetStatistics().CheaterSquares))
+}
+
+func TestPuzzle_GetStatistics_Good(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	stats := puzzle.GetStatistics()
+	assert.Equal(t, puzzle.CountBlackCells(), stats.BlockCount)
+	assert.Equal(t, stats.AcrossCount+stats.DownCount, stats.WordCount)
+	total := 0
+	for _, n := range stats.WordLengths {
+		total += n
+	}
+	assert.Equal(t, stats.WordCount, total)
+
+	jsonBlob, err := json.Marshal(stats)
+	assert.Nil(t, err)
+	assert.Contains(t, string(jsonBlob), `"blockCount":16`)
+
+	report := stats.String()
+	assert.True(t, strings.HasPrefix(report, "Size: 9 x 9\n"))
+	assert.Contains(t, report, "Blocks: 16\n")
+	assert.Contains(t, report, "Pangram: no (missing ABCDEFGHIJKLMNOPQRSTUVWXYZ)\n")
+}
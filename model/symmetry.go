@@ -0,0 +1,133 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// Symmetry is the rule used to mirror a black cell to its twin(s) when
+// it is toggled (according to the enumerated constants below).
+type Symmetry string
+
+const (
+	ROTATIONAL  Symmetry = "rotational" // 180 degree rotation (the default)
+	LEFT_RIGHT  Symmetry = "left-right" // Mirror about the vertical axis
+	TOP_BOTTOM  Symmetry = "top-bottom" // Mirror about the horizontal axis
+	DIAGONAL    Symmetry = "diagonal"   // Mirror about the main diagonal
+	FOUR_WAY    Symmetry = "four-way"   // Mirror about both axes
+	NO_SYMMETRY Symmetry = "none"       // No twin at all
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// SymmetryFromString parses a string for a symmetry mode.  The
+// comparison is case insensitive.  An empty string is taken to be the
+// default, ROTATIONAL.
+func SymmetryFromString(s string) (Symmetry, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ROTATIONAL, nil
+	}
+	for _, symmetry := range []Symmetry{
+		ROTATIONAL,
+		LEFT_RIGHT,
+		TOP_BOTTOM,
+		DIAGONAL,
+		FOUR_WAY,
+		NO_SYMMETRY,
+	} {
+		if s == string(symmetry) {
+			return symmetry, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a valid symmetry", s)
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetSymmetry returns the symmetry mode of this puzzle.
+func (puzzle *Puzzle) GetSymmetry() Symmetry {
+	return puzzle.symmetry
+}
+
+// SetSymmetry changes the symmetry mode used when toggling black cells.
+// Diagonal symmetry is only possible in a square grid.
+//
+// The change is recorded in the history, so that black cells toggled
+// under an earlier symmetry are undone under that symmetry.
+func (puzzle *Puzzle) SetSymmetry(symmetry Symmetry) error {
+	if _, err := SymmetryFromString(string(symmetry)); err != nil {
+		return err
+	}
+	if symmetry == DIAGONAL && puzzle.nRows != puzzle.nCols {
+		return fmt.Errorf("diagonal symmetry requires a square grid, not %dx%d",
+			puzzle.nRows, puzzle.nCols)
+	}
+	if symmetry != puzzle.symmetry {
+		puzzle.record(&Command{
+			Kind:   SYMMETRY_COMMAND,
+			Before: string(puzzle.symmetry),
+			After:  string(symmetry),
+		})
+	}
+	puzzle.symmetry = symmetry
+	return nil
+}
+
+// SymmetricPoints returns the twins of the specified point under the
+// puzzle's symmetry mode.  The point itself is not included, nor are
+// duplicates, so the list is empty for NO_SYMMETRY or for a point that
+// is its own twin (e.g., the center of an odd-sized grid).
+func (puzzle *Puzzle) SymmetricPoints(point Point) []Point {
+	nRows, nCols := puzzle.nRows, puzzle.nCols
+	r, c := point.r, point.c
+
+	candidates := make([]Point, 0)
+	switch puzzle.symmetry {
+	case ROTATIONAL, "":
+		candidates = append(candidates, puzzle.SymmetricPoint(point))
+	case LEFT_RIGHT:
+		candidates = append(candidates, NewPoint(r, nCols+1-c))
+	case TOP_BOTTOM:
+		candidates = append(candidates, NewPoint(nRows+1-r, c))
+	case DIAGONAL:
+		candidates = append(candidates, NewPoint(c, r))
+	case FOUR_WAY:
+		candidates = append(candidates,
+			NewPoint(r, nCols+1-c),
+			NewPoint(nRows+1-r, c),
+			puzzle.SymmetricPoint(point))
+	}
+
+	// Remove the point itself and any duplicates
+	twins := make([]Point, 0)
+	for _, candidate := range candidates {
+		if candidate == point {
+			continue
+		}
+		found := false
+		for _, twin := range twins {
+			if twin == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			twins = append(twins, candidate)
+		}
+	}
+	return twins
+}
+
+// String returns a string representation of this object
+func (symmetry Symmetry) String() string {
+	return string(symmetry)
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymmetryFromString(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Symmetry
+		wantErr bool
+	}{
+		{"empty", "", ROTATIONAL, false},
+		{"rotational", "rotational", ROTATIONAL, false},
+		{"mixed case", "Left-Right", LEFT_RIGHT, false},
+		{"none", "none", NO_SYMMETRY, false},
+		{"bogus", "sideways", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := SymmetryFromString(tt.s)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestPuzzle_SetSymmetry(t *testing.T) {
+	puzzle := NewPuzzle(9)
+	assert.Equal(t, ROTATIONAL, puzzle.GetSymmetry())
+
+	assert.Nil(t, puzzle.SetSymmetry(DIAGONAL))
+	assert.Equal(t, DIAGONAL, puzzle.GetSymmetry())
+
+	assert.NotNil(t, puzzle.SetSymmetry("bogus"))
+	assert.Equal(t, DIAGONAL, puzzle.GetSymmetry())
+
+	puzzle = NewRectangularPuzzle(15, 21)
+	assert.NotNil(t, puzzle.SetSymmetry(DIAGONAL))
+	assert.Equal(t, ROTATIONAL, puzzle.GetSymmetry())
+}
+
+func TestPuzzle_SetSymmetry_Undo(t *testing.T) {
+	puzzle := NewPuzzle(9)
+	puzzle.Toggle(NewPoint(1, 1))
+	assert.True(t, puzzle.CanUndo())
+
+	// Setting the same symmetry is not a change
+	puzzle.SetSymmetry(ROTATIONAL)
+	undo, _ := puzzle.GetHistory()
+	assert.Equal(t, 1, len(undo))
+
+	puzzle.SetSymmetry(LEFT_RIGHT)
+	puzzle.Toggle(NewPoint(2, 2))
+	assert.Equal(t, 4, puzzle.CountBlackCells())
+
+	// Each black cell is undone under the symmetry it was toggled with
+	puzzle.Undo()
+	assert.Equal(t, 2, puzzle.CountBlackCells())
+	puzzle.Undo()
+	assert.Equal(t, ROTATIONAL, puzzle.GetSymmetry())
+	puzzle.Undo()
+	assert.Equal(t, 0, puzzle.CountBlackCells())
+
+	// And redone the same way
+	puzzle.Redo()
+	puzzle.Redo()
+	puzzle.Redo()
+	assert.Equal(t, LEFT_RIGHT, puzzle.GetSymmetry())
+	assert.True(t, puzzle.IsBlackCell(NewPoint(9, 9)))
+	assert.True(t, puzzle.IsBlackCell(NewPoint(2, 8)))
+}
+
+func TestPuzzle_Toggle_Symmetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		symmetry Symmetry
+		point    Point
+		want     []Point
+	}{
+		{"rotational", ROTATIONAL, NewPoint(1, 2), []Point{{1, 2}, {9, 8}}},
+		{"left-right", LEFT_RIGHT, NewPoint(1, 2), []Point{{1, 2}, {1, 8}}},
+		{"top-bottom", TOP_BOTTOM, NewPoint(1, 2), []Point{{1, 2}, {9, 2}}},
+		{"diagonal", DIAGONAL, NewPoint(1, 2), []Point{{1, 2}, {2, 1}}},
+		{"four-way", FOUR_WAY, NewPoint(1, 2), []Point{{1, 2}, {1, 8}, {9, 2}, {9, 8}}},
+		{"none", NO_SYMMETRY, NewPoint(1, 2), []Point{{1, 2}}},
+		{"own twin", ROTATIONAL, NewPoint(5, 5), []Point{{5, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzle := NewPuzzle(9)
+			assert.Nil(t, puzzle.SetSymmetry(tt.symmetry))
+
+			puzzle.Toggle(tt.point)
+			have := make([]Point, 0)
+			for bc := range puzzle.BlackCellIterator() {
+				have = append(have, bc.point)
+			}
+			assert.Equal(t, tt.want, have)
+
+			// Undo should remove them all, and redo restore them
+			puzzle.Undo()
+			assert.Equal(t, 0, puzzle.CountBlackCells())
+			puzzle.Redo()
+			assert.Equal(t, len(tt.want), puzzle.CountBlackCells())
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/philhanna/cwcomp/util"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// User is a row of the users table, without the password.  The author
+// and address fields are the ones used when submitting a puzzle.
+type User struct {
+	ID           int    `json:"userid"`
+	Username     string `json:"username"`
+	Created      string `json:"created"`
+	Email        string `json:"email"`
+	Confirmed    string `json:"confirmed,omitempty"`
+	AuthorName   string `json:"author_name"`
+	AddressLine1 string `json:"address_line_1"`
+	AddressLine2 string `json:"address_line_2"`
+	AddressCity  string `json:"address_city"`
+	AddressState string `json:"address_state"`
+	AddressZip   string `json:"address_zip"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// ErrBadCredentials is returned when a username and password do not
+// match those of any user.
+var ErrBadCredentials = errors.New("invalid username or password")
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Authenticate checks a username and password and returns the user.  If
+// the user's password is stored in an older, weaker form, it is
+// replaced with a new hash of the same password, if it can be.  If
+// there is no such user, a NotFoundError is returned, and if the
+// password is wrong, ErrBadCredentials.
+func (repo *Repository) Authenticate(username, password string) (*User, error) {
+	var user *User
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var hash []byte
+		err := tx.QueryRow(`SELECT userid, password FROM users WHERE username=?`,
+			username).Scan(new(int), &hash)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &NotFoundError{Username: username}
+		case err != nil:
+			return err
+		}
+		ok, needsUpgrade := util.CheckPassword(hash, password)
+		if !ok {
+			return ErrBadCredentials
+		}
+		if needsUpgrade {
+			// A password that cannot be rehashed, such as one longer
+			// than bcrypt allows, is left as it is rather than refusing
+			// the login.
+			newHash, err := util.HashPassword(password)
+			if err != nil {
+				log.Printf("Could not upgrade the password of user %q: %v\n", username, err)
+			} else {
+				_, err = tx.Exec(`UPDATE users SET password=? WHERE username=?`,
+					newHash, username)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		user, err = loadUser(tx, `username=?`, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the user's password, provided the old one is
+// given correctly.
+func (repo *Repository) ChangePassword(userid int, oldPassword, newPassword string) error {
+	if err := util.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	newHash, err := util.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		var hash []byte
+		err := tx.QueryRow(`SELECT password FROM users WHERE userid=?`, userid).Scan(&hash)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &NotFoundError{UserID: userid}
+		case err != nil:
+			return err
+		}
+		if ok, _ := util.CheckPassword(hash, oldPassword); !ok {
+			return ErrBadCredentials
+		}
+		_, err = tx.Exec(`UPDATE users SET password=? WHERE userid=?`, newHash, userid)
+		return err
+	})
+}
+
+// CreateUser adds a user with the specified name and password, and
+// returns the new user.  The username must not already be taken.
+func (repo *Repository) CreateUser(username, password string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("username must not be empty")
+	}
+	if err := util.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	var user *User
+	err = repo.inTransaction(func(tx *sql.Tx) error {
+		created := time.Now().Format(time.RFC3339)
+		_, err := tx.Exec(`INSERT INTO users (username, password, created) VALUES(?, ?, ?)`,
+			username, hash, created)
+		if err != nil {
+			if errors.Is(nameConflict(err, username), ErrNameConflict) {
+				return &NameConflictError{Username: username}
+			}
+			return err
+		}
+		user, err = loadUser(tx, `username=?`, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser returns the user with the specified ID.
+func (repo *Repository) GetUser(userid int) (*User, error) {
+	var user *User
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = loadUser(tx, `userid=?`, userid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByName returns the user with the specified username.
+func (repo *Repository) GetUserByName(username string) (*User, error) {
+	var user *User
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		var err error
+		user, err = loadUser(tx, `username=?`, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces the user's password without checking the old
+// one.  It is meant for administrators, not for the user themselves.
+func (repo *Repository) SetPassword(username, password string) error {
+	if err := util.ValidatePassword(password); err != nil {
+		return err
+	}
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE users SET password=? WHERE username=?`, hash, username)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &NotFoundError{Username: username}
+		}
+		return nil
+	})
+}
+
+// UpdateProfile replaces the email, author, and address fields of the
+// user with the same ID.  The username, creation time, and password are
+// not changed.
+func (repo *Repository) UpdateProfile(user *User) error {
+	return repo.inTransaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE	users
+			SET		email=?, author_name=?,
+					address_line_1=?, address_line_2=?,
+					address_city=?, address_state=?, address_zip=?
+			WHERE	userid=?`,
+			user.Email, user.AuthorName,
+			user.AddressLine1, user.AddressLine2,
+			user.AddressCity, user.AddressState, user.AddressZip,
+			user.ID)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &NotFoundError{UserID: user.ID}
+		}
+		return nil
+	})
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// Authenticate is a shortcut for Repository.Authenticate.
+func Authenticate(username, password string) (*User, error) {
+	var user *User
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		user, err = repo.Authenticate(username, password)
+		return err
+	})
+	return user, err
+}
+
+// ChangePassword is a shortcut for Repository.ChangePassword.
+func ChangePassword(userid int, oldPassword, newPassword string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.ChangePassword(userid, oldPassword, newPassword)
+	})
+}
+
+// CreateUser is a shortcut for Repository.CreateUser.
+func CreateUser(username, password string) (*User, error) {
+	var user *User
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		user, err = repo.CreateUser(username, password)
+		return err
+	})
+	return user, err
+}
+
+// GetUser is a shortcut for Repository.GetUser.
+func GetUser(userid int) (*User, error) {
+	var user *User
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		user, err = repo.GetUser(userid)
+		return err
+	})
+	return user, err
+}
+
+// GetUserByName is a shortcut for Repository.GetUserByName.
+func GetUserByName(username string) (*User, error) {
+	var user *User
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		user, err = repo.GetUserByName(username)
+		return err
+	})
+	return user, err
+}
+
+// SetPassword is a shortcut for Repository.SetPassword.
+func SetPassword(username, password string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.SetPassword(username, password)
+	})
+}
+
+// UpdateProfile is a shortcut for Repository.UpdateProfile.
+func UpdateProfile(user *User) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.UpdateProfile(user)
+	})
+}
+
+// loadUser returns the one user selected by the where clause.
+func loadUser(q queryer, where string, args ...any) (*User, error) {
+	rows, err := q.Query(`
+		SELECT	userid, username, created, email, confirmed, author_name,
+				address_line_1, address_line_2, address_city, address_state, address_zip
+		FROM	users
+		WHERE	`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		switch key := args[0].(type) {
+		case int:
+			return nil, &NotFoundError{UserID: key}
+		default:
+			return nil, &NotFoundError{Username: fmt.Sprint(key)}
+		}
+	}
+	var (
+		user   = new(User)
+		fields [9]sql.NullString
+	)
+	err = rows.Scan(&user.ID, &user.Username,
+		&fields[0], &fields[1], &fields[2], &fields[3],
+		&fields[4], &fields[5], &fields[6], &fields[7], &fields[8])
+	if err != nil {
+		return nil, err
+	}
+	user.Created = fields[0].String
+	user.Email = fields[1].String
+	user.Confirmed = fields[2].String
+	user.AuthorName = fields[3].String
+	user.AddressLine1 = fields[4].String
+	user.AddressLine2 = fields[5].String
+	user.AddressCity = fields[6].String
+	user.AddressState = fields[7].String
+	user.AddressZip = fields[8].String
+	return user, nil
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/philhanna/cwcomp/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the password hash stored for the user
+func getPasswordHash(t *testing.T, username string) []byte {
+	con, err := Connect()
+	assert.Nil(t, err)
+	defer con.Close()
+	var hash []byte
+	err = con.QueryRow(`SELECT password FROM users WHERE username=?`, username).Scan(&hash)
+	assert.Nil(t, err)
+	return hash
+}
+
+func TestRepository_Authenticate(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		// The test user's password is stored as an unsalted SHA-256
+		assert.Equal(t, 32, len(getPasswordHash(t, OTHER_USERNAME)))
+
+		_, err = repo.Authenticate(OTHER_USERNAME, "wrong")
+		assert.True(t, errors.Is(err, ErrBadCredentials))
+		assert.Equal(t, 32, len(getPasswordHash(t, OTHER_USERNAME)))
+
+		// Logging in upgrades it to bcrypt
+		user, err := repo.Authenticate(OTHER_USERNAME, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, OTHER_USERID, user.ID)
+		assert.True(t, strings.HasPrefix(string(getPasswordHash(t, OTHER_USERNAME)), "$2"))
+
+		// And the same password still works
+		_, err = repo.Authenticate(OTHER_USERNAME, "test")
+		assert.Nil(t, err)
+		_, err = repo.Authenticate(OTHER_USERNAME, "wrong")
+		assert.True(t, errors.Is(err, ErrBadCredentials))
+
+		_, err = repo.Authenticate("bogus", "test")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})(t)
+}
+
+func TestRepository_Authenticate_LongLegacyPassword(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		// bcrypt cannot hash a password this long, so the login works
+		// but the old hash is kept
+		password := strings.Repeat("x", 100)
+		con, _ := Connect()
+		_, err = con.Exec(`UPDATE users SET password=? WHERE username=?`,
+			util.Hash256(password), OTHER_USERNAME)
+		con.Close()
+		assert.Nil(t, err)
+
+		user, err := repo.Authenticate(OTHER_USERNAME, password)
+		assert.Nil(t, err)
+		assert.Equal(t, OTHER_USERID, user.ID)
+		assert.Equal(t, 32, len(getPasswordHash(t, OTHER_USERNAME)))
+	})(t)
+}
+
+func TestRepository_CreateUser(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		user, err := repo.CreateUser(" newuser ", "long enough")
+		assert.Nil(t, err)
+		assert.Equal(t, "newuser", user.Username)
+		assert.NotEqual(t, "", user.Created)
+
+		authenticated, err := repo.Authenticate("newuser", "long enough")
+		assert.Nil(t, err)
+		assert.Equal(t, user, authenticated)
+
+		_, err = repo.CreateUser("newuser", "long enough")
+		assert.True(t, errors.Is(err, ErrNameConflict))
+		assert.Equal(t, `username "newuser" is already used`, err.Error())
+
+		_, err = repo.CreateUser("shorty", "short")
+		assert.NotNil(t, err)
+		_, err = repo.CreateUser("", "long enough")
+		assert.NotNil(t, err)
+	})(t)
+}
+
+func TestRepository_ChangePassword(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		err = repo.ChangePassword(OTHER_USERID, "wrong", "new password")
+		assert.True(t, errors.Is(err, ErrBadCredentials))
+		assert.NotNil(t, repo.ChangePassword(OTHER_USERID, "test", "short"))
+
+		assert.Nil(t, repo.ChangePassword(OTHER_USERID, "test", "new password"))
+		_, err = repo.Authenticate(OTHER_USERNAME, "test")
+		assert.True(t, errors.Is(err, ErrBadCredentials))
+		_, err = repo.Authenticate(OTHER_USERNAME, "new password")
+		assert.Nil(t, err)
+
+		// An administrator can set it without the old one
+		assert.Nil(t, repo.SetPassword(OTHER_USERNAME, "reset password"))
+		_, err = repo.Authenticate(OTHER_USERNAME, "reset password")
+		assert.Nil(t, err)
+		err = repo.SetPassword("bogus", "reset password")
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		err = repo.ChangePassword(12345, "test", "new password")
+		assert.Equal(t, "no user with id 12345 found", err.Error())
+	})(t)
+}
+
+func TestRepository_UpdateProfile(t *testing.T) {
+	runtest(func(*testing.T) {
+		repo, err := NewRepository()
+		assert.Nil(t, err)
+		defer repo.Close()
+
+		user, err := repo.GetUserByName(OTHER_USERNAME)
+		assert.Nil(t, err)
+		assert.Equal(t, "", user.AuthorName)
+
+		user.Username = "ignored"
+		user.Email = "test@example.com"
+		user.AuthorName = "Test Author"
+		user.AddressLine1 = "1 Main St."
+		user.AddressCity = "Raleigh"
+		user.AddressState = "NC"
+		user.AddressZip = "27601"
+		assert.Nil(t, repo.UpdateProfile(user))
+
+		reloaded, err := repo.GetUser(OTHER_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, OTHER_USERNAME, reloaded.Username)
+		assert.Equal(t, "test@example.com", reloaded.Email)
+		assert.Equal(t, "Test Author", reloaded.AuthorName)
+		assert.Equal(t, "1 Main St.", reloaded.AddressLine1)
+		assert.Equal(t, "", reloaded.AddressLine2)
+		assert.Equal(t, "Raleigh", reloaded.AddressCity)
+		assert.Equal(t, "NC", reloaded.AddressState)
+		assert.Equal(t, "27601", reloaded.AddressZip)
+
+		_, err = repo.GetUser(12345)
+		assert.True(t, errors.Is(err, ErrNotFound))
+		_, err = repo.GetUserByName("bogus")
+		assert.Equal(t, `no user named "bogus" found`, err.Error())
+		user.ID = 12345
+		assert.True(t, errors.Is(repo.UpdateProfile(user), ErrNotFound))
+	})(t)
+}
@@ -0,0 +1,488 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// UserWords is a user's customization of the dictionary, stored in the
+// user_words table.  A user can add words (or change the score of words
+// already in the dictionary) and ban words so that they are never
+// matched.
+//
+// A nil *UserWords is valid and means the base dictionary alone.
+type UserWords struct {
+	userid  int            // User who owns these words
+	version int            // Version of the user's list when it was read
+	words   Dictionary     // Added and rescored words, best first
+	scores  map[string]int // Scores of the same words
+	banned  map[string]bool
+
+	// Dictionary merged with these words, for Autofill, built when the
+	// dictionary version is indexVersion
+	indexMutex   sync.Mutex
+	indexVersion int
+	byLength     map[int][]string
+	inDict       map[string]bool
+
+	// The same, as a WordIndex for GetHeatMap, built when the dictionary
+	// version is wordIndexVersion
+	wordIndexVersion int
+	wordIndex        *WordIndex
+}
+
+// UserWord is one entry in a user's word list
+type UserWord struct {
+	Word   string `json:"word"`
+	Score  int    `json:"score"`
+	Banned bool   `json:"banned"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// Each user's word list has a version that changes whenever the list
+// does, so that a UserWords that was read earlier can tell that it is
+// out of date.
+var (
+	userWordsVersions = make(map[int]int)
+	userWordsMutex    sync.Mutex
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// LoadUserWords reads the user's word list from the database.
+func LoadUserWords(userid int) (*UserWords, error) {
+	version := getUserWordsVersion(userid)
+	entries, err := GetUserWords(userid)
+	if err != nil {
+		return nil, err
+	}
+	uw := new(UserWords)
+	uw.userid = userid
+	uw.version = version
+	uw.indexVersion = -1
+	uw.wordIndexVersion = -1
+	uw.words = make(Dictionary, 0)
+	uw.scores = make(map[string]int)
+	uw.banned = make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Banned {
+			uw.banned[entry.Word] = true
+			continue
+		}
+		uw.words = append(uw.words, ScoredWord{entry.Word, entry.Score})
+		uw.scores[entry.Word] = entry.Score
+	}
+	sort.Slice(uw.words, func(i, j int) bool {
+		return uw.words[i].ranksBefore(uw.words[j])
+	})
+	return uw, nil
+}
+
+// GetUserWords is a shortcut for Repository.GetUserWords.
+func GetUserWords(userid int) ([]UserWord, error) {
+	var entries []UserWord
+	err := withRepository(func(repo *Repository) error {
+		var err error
+		entries, err = repo.GetUserWords(userid)
+		return err
+	})
+	return entries, err
+}
+
+// AddUserWord is a shortcut for Repository.AddUserWord.
+func AddUserWord(userid int, word string, score int) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.AddUserWord(userid, word, score)
+	})
+}
+
+// SetUserWordScore is a shortcut for Repository.SetUserWordScore.
+func SetUserWordScore(userid int, word string, score int) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.SetUserWordScore(userid, word, score)
+	})
+}
+
+// BanUserWord is a shortcut for Repository.BanUserWord.
+func BanUserWord(userid int, word string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.BanUserWord(userid, word)
+	})
+}
+
+// RemoveUserWord is a shortcut for Repository.RemoveUserWord.
+func RemoveUserWord(userid int, word string) error {
+	return withRepository(func(repo *Repository) error {
+		return repo.RemoveUserWord(userid, word)
+	})
+}
+
+// getUserWordsVersion returns the current version of the user's word
+// list.
+func getUserWordsVersion(userid int) int {
+	userWordsMutex.Lock()
+	defer userWordsMutex.Unlock()
+	return userWordsVersions[userid]
+}
+
+// userWordKey normalizes a word as in the dictionary, and returns an
+// error if nothing is left.
+func userWordKey(word string) (string, error) {
+	key := normalizeWord(word)
+	if key == "" {
+		return "", fmt.Errorf("invalid word %q", word)
+	}
+	return key, nil
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// GetUserWords returns the entries in the user's word list, in
+// alphabetical order.
+func (repo *Repository) GetUserWords(userid int) ([]UserWord, error) {
+	entries := make([]UserWord, 0)
+	err := repo.inTransaction(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			SELECT		word, COALESCE(score, 0), banned
+			FROM		user_words
+			WHERE		userid=?
+			ORDER BY	word`,
+			userid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var entry UserWord
+			if err := rows.Scan(&entry.Word, &entry.Score, &entry.Banned); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AddUserWord adds a word to the user's word list with the specified
+// score.  If the word is already in the base dictionary, this changes
+// its score for this user.  If the word was banned, it no longer is.
+func (repo *Repository) AddUserWord(userid int, word string, score int) error {
+	word, err := userWordKey(word)
+	if err != nil {
+		return err
+	}
+	return repo.changeUserWords(userid, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO user_words(userid, word, score, banned)
+			VALUES(?, ?, ?, 0)
+			ON CONFLICT(userid, word) DO UPDATE SET score=excluded.score, banned=0`,
+			userid, word, score)
+		return err
+	})
+}
+
+// SetUserWordScore changes the score of a word that is already in the
+// user's word list.  A banned word stays banned.  If the word is not in
+// the list, a NotFoundError is returned; use AddUserWord to add it.
+func (repo *Repository) SetUserWordScore(userid int, word string, score int) error {
+	word, err := userWordKey(word)
+	if err != nil {
+		return err
+	}
+	return repo.changeUserWords(userid, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE	user_words
+			SET		score=?
+			WHERE	userid=? AND word=?`,
+			score, userid, word)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &NotFoundError{UserID: userid, Word: word}
+		}
+		return nil
+	})
+}
+
+// BanUserWord bans a word for this user, so that it is never matched,
+// even if it is in the base dictionary.
+func (repo *Repository) BanUserWord(userid int, word string) error {
+	word, err := userWordKey(word)
+	if err != nil {
+		return err
+	}
+	return repo.changeUserWords(userid, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO user_words(userid, word, score, banned)
+			VALUES(?, ?, NULL, 1)
+			ON CONFLICT(userid, word) DO UPDATE SET score=NULL, banned=1`,
+			userid, word)
+		return err
+	})
+}
+
+// RemoveUserWord removes a word from the user's word list, whether it
+// was added or banned, so that the base dictionary applies again.
+func (repo *Repository) RemoveUserWord(userid int, word string) error {
+	word, err := userWordKey(word)
+	if err != nil {
+		return err
+	}
+	return repo.changeUserWords(userid, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM user_words WHERE userid=? AND word=?`,
+			userid, word)
+		return err
+	})
+}
+
+// changeUserWords runs a change to the user's word list in a
+// transaction, and if it succeeds, records that the list has changed.
+func (repo *Repository) changeUserWords(userid int, f func(tx *sql.Tx) error) error {
+	if err := repo.inTransaction(f); err != nil {
+		return err
+	}
+	userWordsMutex.Lock()
+	defer userWordsMutex.Unlock()
+	userWordsVersions[userid]++
+	return nil
+}
+
+// IsCurrent returns false if the user's word list has changed since
+// these words were read, so that they should be read again.  A nil
+// *UserWords is always current.
+func (uw *UserWords) IsCurrent() bool {
+	return uw == nil || uw.version == getUserWordsVersion(uw.userid)
+}
+
+// GetMatchingWords returns a channel of the words that match the
+// regular expression pattern, best first, from the base dictionary
+// merged with the user's words.  Banned words and words below the
+// minimum score are skipped.
+func (uw *UserWords) GetMatchingWords(pattern string, stop <-chan struct{}) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for sw := range uw.GetScoredMatches(pattern, stop) {
+			select {
+			case <-stop:
+				return
+			case ch <- sw.Word:
+			}
+		}
+	}()
+	return ch
+}
+
+// GetScoredMatches is like GetMatchingWords, but returns each word with
+// its score.
+func (uw *UserWords) GetScoredMatches(pattern string, stop <-chan struct{}) <-chan ScoredWord {
+	if uw == nil {
+		return GetScoredMatches(pattern, stop)
+	}
+	base := func(stop <-chan struct{}) <-chan ScoredWord {
+		return GetScoredMatches(pattern, stop)
+	}
+	return uw.merge(base, regexpMatcher(pattern), stop)
+}
+
+// SearchWords returns a channel of the words that match a query, best
+// first, from the base dictionary merged with the user's words.
+func (uw *UserWords) SearchWords(query *WordQuery, stop <-chan struct{}) <-chan ScoredWord {
+	if uw == nil {
+		return SearchWords(query, stop)
+	}
+	base := func(stop <-chan struct{}) <-chan ScoredWord {
+		return SearchWords(query, stop)
+	}
+	return uw.merge(base, query.Matches, stop)
+}
+
+// merge returns a channel of the words from the base dictionary (as
+// returned by the base function) merged with the user's words that
+// satisfy match, best first.  Banned words and base words that the user
+// has rescored are skipped.
+func (uw *UserWords) merge(
+	base func(<-chan struct{}) <-chan ScoredWord,
+	match func(string) bool,
+	stop <-chan struct{},
+) <-chan ScoredWord {
+
+	// The user's words are few, so they can be matched directly
+	minScore := GetMinScore()
+	mine := make([]ScoredWord, 0)
+	for _, sw := range uw.words {
+		if sw.Score < minScore {
+			break
+		}
+		if match(sw.Word) {
+			mine = append(mine, sw)
+		}
+	}
+
+	ch := make(chan ScoredWord)
+	go func() {
+		defer close(ch)
+		baseStop := make(chan struct{})
+		defer close(baseStop)
+		baseWords := base(baseStop)
+
+		// Merge the two lists, which are both sorted best first
+		next, more := <-baseWords
+		j := 0
+		for more || j < len(mine) {
+			var sw ScoredWord
+			switch {
+			case j == len(mine) || (more && next.ranksBefore(mine[j])):
+				sw = next
+				next, more = <-baseWords
+				if _, rescored := uw.scores[sw.Word]; rescored || uw.banned[sw.Word] {
+					continue
+				}
+			default:
+				sw = mine[j]
+				j++
+			}
+			select {
+			case <-stop:
+				return
+			case ch <- sw:
+			}
+		}
+	}()
+	return ch
+}
+
+// GetScore returns the score of a word for this user, and whether it is
+// in the dictionary at all.  A banned word is not in the dictionary.
+func (uw *UserWords) GetScore(word string) (int, bool) {
+	if uw == nil {
+		return GetScore(word)
+	}
+	if uw.banned[word] {
+		return 0, false
+	}
+	if score, ok := uw.scores[word]; ok {
+		return score, true
+	}
+	return GetScore(word)
+}
+
+// getAutofillIndex is like the getAutofillIndex function, but for the
+// base dictionary merged with the user's words: banned words are left
+// out, and rescored words are in their new place.
+func (uw *UserWords) getAutofillIndex() (map[int][]string, map[string]bool) {
+	if uw == nil {
+		return getAutofillIndex()
+	}
+	uw.indexMutex.Lock()
+	defer uw.indexMutex.Unlock()
+
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	if uw.indexVersion != dictionaryVersion {
+		uw.byLength = make(map[int][]string)
+		uw.inDict = make(map[string]bool)
+		uw.eachMergedWord(func(sw ScoredWord) {
+			uw.byLength[len(sw.Word)] = append(uw.byLength[len(sw.Word)], sw.Word)
+			uw.inDict[sw.Word] = true
+		})
+		uw.indexVersion = dictionaryVersion
+	}
+	return uw.byLength, uw.inDict
+}
+
+// getWordIndex returns an index of the base dictionary merged with the
+// user's words, as in getAutofillIndex, and the minimum score.  Without
+// user's words, it is the dictionary's own index.
+func (uw *UserWords) getWordIndex() (*WordIndex, int) {
+	if uw == nil {
+		dictionaryMutex.RLock()
+		defer dictionaryMutex.RUnlock()
+		return dictionaryIndex, dictionaryMinimum
+	}
+	uw.indexMutex.Lock()
+	defer uw.indexMutex.Unlock()
+
+	dictionaryMutex.RLock()
+	defer dictionaryMutex.RUnlock()
+	if uw.wordIndexVersion != dictionaryVersion {
+		merged := make(Dictionary, 0)
+		uw.eachMergedWord(func(sw ScoredWord) {
+			merged = append(merged, sw)
+		})
+		uw.wordIndex = NewWordIndex(merged)
+		uw.wordIndexVersion = dictionaryVersion
+	}
+	return uw.wordIndex, dictionaryMinimum
+}
+
+// eachMergedWord calls f with each word at or above the minimum score in
+// the base dictionary merged with the user's words, best first.  The
+// caller must hold dictionaryMutex.
+func (uw *UserWords) eachMergedWord(f func(ScoredWord)) {
+
+	// Merge the two lists, which are both sorted best first
+	i, j := 0, 0
+	for {
+		more := i < len(dictionary) && dictionary[i].Score >= dictionaryMinimum
+		mine := j < len(uw.words) && uw.words[j].Score >= dictionaryMinimum
+		switch {
+		case more && (!mine || dictionary[i].ranksBefore(uw.words[j])):
+			sw := dictionary[i]
+			i++
+			if _, rescored := uw.scores[sw.Word]; !rescored && !uw.banned[sw.Word] {
+				f(sw)
+			}
+		case mine:
+			f(uw.words[j])
+			j++
+		default:
+			return
+		}
+	}
+}
+
+// GetUserWords returns the user's word list that is used in this
+// puzzle, which may be nil.
+func (puzzle *Puzzle) GetUserWords() *UserWords {
+	return puzzle.userWords
+}
+
+// SetUserWords sets the user's word list that is merged with the base
+// dictionary in GetConstraints.  It may be nil for the base dictionary
+// alone.
+func (puzzle *Puzzle) SetUserWords(uw *UserWords) {
+	puzzle.userWords = uw
+}
+
+// ranksBefore returns true if this word comes before another in the
+// dictionary order, i.e., it has a higher score, or the same score and
+// comes first alphabetically.
+func (sw ScoredWord) ranksBefore(other ScoredWord) bool {
+	if sw.Score != other.Score {
+		return sw.Score > other.Score
+	}
+	return sw.Word < other.Word
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserWords_CRUD(t *testing.T) {
+	runtest(func(t *testing.T) {
+		assert.Nil(t, AddUserWord(TEST_USERID, "New York", 70))
+		assert.Nil(t, AddUserWord(TEST_USERID, "heart", 10))
+		assert.Nil(t, SetUserWordScore(TEST_USERID, "HEART", 20))
+		assert.NotNil(t, SetUserWordScore(TEST_USERID, "BORED", 20))
+		assert.Nil(t, BanUserWord(TEST_USERID, "aahed"))
+		assert.NotNil(t, AddUserWord(TEST_USERID, "123", 50))
+
+		entries, err := GetUserWords(TEST_USERID)
+		assert.Nil(t, err)
+		assert.Equal(t, []UserWord{
+			{"AAHED", 0, true},
+			{"HEART", 20, false},
+			{"NEWYORK", 70, false},
+		}, entries)
+
+		assert.Nil(t, RemoveUserWord(TEST_USERID, "heart"))
+		entries, _ = GetUserWords(TEST_USERID)
+		assert.Equal(t, 2, len(entries))
+	})(t)
+}
+
+func TestSetUserWordScore(t *testing.T) {
+	runtest(func(t *testing.T) {
+		assert.Nil(t, AddUserWord(TEST_USERID, "heart", 10))
+		assert.Nil(t, BanUserWord(TEST_USERID, "aahed"))
+
+		tests := []struct {
+			name       string
+			word       string
+			wantErr    error
+			wantBanned bool
+		}{
+			{"added", "HEART", nil, false},
+			{"banned", "AAHED", nil, true},
+			{"absent", "BORED", ErrNotFound, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := SetUserWordScore(TEST_USERID, tt.word, 30)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+					return
+				}
+				assert.Nil(t, err)
+				entries, _ := GetUserWords(TEST_USERID)
+				for _, entry := range entries {
+					if entry.Word == tt.word {
+						assert.Equal(t, tt.wantBanned, entry.Banned)
+						assert.Equal(t, 30, entry.Score)
+					}
+				}
+			})
+		}
+		entries, _ := GetUserWords(TEST_USERID)
+		assert.Equal(t, 2, len(entries))
+	})(t)
+}
+
+func TestUserWords_IsCurrent(t *testing.T) {
+	runtest(func(t *testing.T) {
+		var none *UserWords
+		assert.True(t, none.IsCurrent())
+
+		uw, _ := LoadUserWords(TEST_USERID)
+		other, _ := LoadUserWords(OTHER_USERID)
+		assert.True(t, uw.IsCurrent())
+		AddUserWord(TEST_USERID, "HEART", 10)
+		assert.False(t, uw.IsCurrent())
+		assert.True(t, other.IsCurrent())
+
+		uw, _ = LoadUserWords(TEST_USERID)
+		assert.True(t, uw.IsCurrent())
+	})(t)
+}
+
+func TestUserWords_GetMatchingWords(t *testing.T) {
+	restore := useWordList(t, "HEART;60\nHEARS;50\nHEARD;40\nHEARX;5")
+	defer restore()
+	SetMinScore(30)
+	runtest(func(t *testing.T) {
+		AddUserWord(TEST_USERID, "HEARY", 55)
+		AddUserWord(TEST_USERID, "HEARD", 70)
+		BanUserWord(TEST_USERID, "HEARS")
+		AddUserWord(TEST_USERID, "HEARZ", 10)
+		uw, err := LoadUserWords(TEST_USERID)
+		assert.Nil(t, err)
+
+		tests := []struct {
+			name string
+			uw   *UserWords
+			want []string
+		}{
+			{"base only", nil, []string{"HEART", "HEARS", "HEARD"}},
+			{"merged", uw, []string{"HEARD", "HEART", "HEARY"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				have := make([]string, 0)
+				for word := range tt.uw.GetMatchingWords("HEAR.", make(chan struct{})) {
+					have = append(have, word)
+				}
+				assert.Equal(t, tt.want, have)
+			})
+		}
+
+		score, ok := uw.GetScore("HEARD")
+		assert.True(t, ok)
+		assert.Equal(t, 70, score)
+		_, ok = uw.GetScore("HEARS")
+		assert.False(t, ok)
+	})(t)
+}
+
+func TestPuzzle_GetConstraints_UserWords(t *testing.T) {
+	defer useWordList(t, "CAT;70\nOAT;50\nOUT;30")()
+	runtest(func(t *testing.T) {
+		AddUserWord(TEST_USERID, "CAT", 20)
+		AddUserWord(TEST_USERID, "OXO", 90)
+		BanUserWord(TEST_USERID, "OAT")
+		uw, _ := LoadUserWords(TEST_USERID)
+
+		puzzle := NewPuzzle(3)
+		puzzle.RenumberCells()
+		puzzle.SetText(puzzle.LookupWordByNumber(1, DOWN), "CAT")
+		puzzle.SetText(puzzle.LookupWordByNumber(2, DOWN), "O  ")
+
+		constraints := puzzle.GetConstraints(puzzle.LookupWordByNumber(1, ACROSS))
+		assert.Equal(t, 70, constraints[0].Score)
+		assert.Equal(t, 2, constraints[1].NChoices)
+		assert.Equal(t, 50, constraints[1].BestScore)
+
+		puzzle.SetUserWords(uw)
+		constraints = puzzle.GetConstraints(puzzle.LookupWordByNumber(1, ACROSS))
+		assert.Equal(t, 20, constraints[0].Score)
+		assert.Equal(t, 2, constraints[1].NChoices)
+		assert.Equal(t, 90, constraints[1].BestScore)
+	})(t)
+}
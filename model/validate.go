@@ -0,0 +1,341 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// DiagnosticKind identifies the type of problem found by Validate
+type DiagnosticKind string
+
+// Diagnostic describes one problem found in a puzzle, with the cells
+// and words involved.
+type Diagnostic struct {
+	Kind    DiagnosticKind `json:"kind"`
+	Message string         `json:"message"`
+	Points  []Point        `json:"points,omitempty"`
+	Words   []WordRef      `json:"words,omitempty"`
+}
+
+// WordRef identifies a word by its number and direction
+type WordRef struct {
+	Seq int       `json:"seq"`
+	Dir Direction `json:"dir"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+const (
+	SHORT_WORD         DiagnosticKind = "short_word"
+	UNCHECKED_CELL     DiagnosticKind = "unchecked_cell"
+	DISCONNECTED_GRID  DiagnosticKind = "disconnected_grid"
+	SYMMETRY_VIOLATION DiagnosticKind = "symmetry_violation"
+	DUPLICATE_ANSWER   DiagnosticKind = "duplicate_answer"
+	EMPTY_CELL         DiagnosticKind = "empty_cell"
+	EMPTY_CLUE         DiagnosticKind = "empty_clue"
+)
+
+// Words shorter than this are not allowed
+const MIN_WORD_LENGTH = 3
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Validate checks whether the puzzle is ready to be submitted, and
+// returns a list of the problems found, which is empty if there are
+// none.  It checks for:
+//   - Words shorter than MIN_WORD_LENGTH (a single cell between black
+//     cells is reported as an unchecked cell instead)
+//   - Unchecked cells, i.e., those that are in only one word
+//   - Letter cells that are not connected to the rest of the grid
+//   - Black cells whose twins under the puzzle's symmetry are not black
+//   - Answers that are used more than once
+//   - Empty cells and words without clues
+//
+// The diagnostics are sorted by kind, in the order above.
+func (puzzle *Puzzle) Validate() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	diagnostics = append(diagnostics, puzzle.validateWordLengths()...)
+	diagnostics = append(diagnostics, puzzle.validateChecking()...)
+	diagnostics = append(diagnostics, puzzle.validateConnected()...)
+	diagnostics = append(diagnostics, puzzle.validateSymmetry()...)
+	diagnostics = append(diagnostics, puzzle.validateDuplicates()...)
+	diagnostics = append(diagnostics, puzzle.validateEmpty()...)
+	return diagnostics
+}
+
+// String returns a string representation of this diagnostic.
+func (d Diagnostic) String() string {
+	parts := []string{fmt.Sprintf("%s: %s", d.Kind, d.Message)}
+	if len(d.Words) > 0 {
+		words := make([]string, len(d.Words))
+		for i, ref := range d.Words {
+			words[i] = ref.String()
+		}
+		parts = append(parts, "words "+strings.Join(words, ", "))
+	}
+	if len(d.Points) > 0 {
+		points := make([]string, len(d.Points))
+		for i, point := range d.Points {
+			points[i] = point.String()
+		}
+		parts = append(parts, "cells "+strings.Join(points, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// String returns a string representation of a word reference, e.g.,
+// "17A".
+func (ref WordRef) String() string {
+	return fmt.Sprintf("%d%s", ref.Seq, string(ref.Dir))
+}
+
+// wordRef returns the reference to a word, by its number and direction
+func (puzzle *Puzzle) wordRef(word *Word) WordRef {
+	ref := WordRef{Dir: word.direction}
+	if wn := puzzle.GetWordNumber(word); wn != nil {
+		ref.Seq = wn.seq
+	}
+	return ref
+}
+
+// wordPoints returns the points in a word
+func (puzzle *Puzzle) wordPoints(word *Word) []Point {
+	points := make([]Point, 0, word.length)
+	for point := range puzzle.WordIterator(word.point, word.direction) {
+		points = append(points, point)
+	}
+	return points
+}
+
+// sortedWords returns the words of the puzzle in order of word number,
+// across before down.
+func (puzzle *Puzzle) sortedWords() []*Word {
+	words := make([]*Word, len(puzzle.words))
+	copy(words, puzzle.words)
+	sort.SliceStable(words, func(i, j int) bool {
+		a, b := puzzle.wordRef(words[i]), puzzle.wordRef(words[j])
+		if a.Seq != b.Seq {
+			return a.Seq < b.Seq
+		}
+		return a.Dir == ACROSS && b.Dir == DOWN
+	})
+	return words
+}
+
+// validateWordLengths reports words that are too short.
+func (puzzle *Puzzle) validateWordLengths() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	for _, word := range puzzle.sortedWords() {
+		if word.length < 2 || word.length >= MIN_WORD_LENGTH {
+			continue
+		}
+		ref := puzzle.wordRef(word)
+		diagnostics = append(diagnostics, Diagnostic{
+			Kind:    SHORT_WORD,
+			Message: fmt.Sprintf("%s has %d letters, minimum is %d", ref, word.length, MIN_WORD_LENGTH),
+			Points:  puzzle.wordPoints(word),
+			Words:   []WordRef{ref},
+		})
+	}
+	return diagnostics
+}
+
+// validateChecking reports letter cells that are not in both an across
+// word and a down word of at least two letters.
+func (puzzle *Puzzle) validateChecking() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	for point := range puzzle.PointIterator() {
+		if puzzle.IsBlackCell(point) {
+			continue
+		}
+		checked := true
+		refs := make([]WordRef, 0)
+		for _, dir := range []Direction{ACROSS, DOWN} {
+			word := puzzle.LookupWord(point, dir)
+			if word == nil || word.length < 2 {
+				checked = false
+			} else {
+				refs = append(refs, puzzle.wordRef(word))
+			}
+		}
+		if !checked {
+			diagnostics = append(diagnostics, Diagnostic{
+				Kind:    UNCHECKED_CELL,
+				Message: fmt.Sprintf("cell %s is in only one word", point.String()),
+				Points:  []Point{point},
+				Words:   refs,
+			})
+		}
+	}
+	return diagnostics
+}
+
+// validateConnected reports regions of letter cells that are not
+// connected to the largest region.  Cells are connected if they are
+// adjacent horizontally or vertically.
+func (puzzle *Puzzle) validateConnected() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+
+	// Find the regions with a flood fill
+	seen := make(map[Point]bool)
+	regions := make([][]Point, 0)
+	for start := range puzzle.PointIterator() {
+		if seen[start] || puzzle.IsBlackCell(start) {
+			continue
+		}
+		region := make([]Point, 0)
+		queue := []Point{start}
+		seen[start] = true
+		for len(queue) > 0 {
+			point := queue[0]
+			queue = queue[1:]
+			region = append(region, point)
+			for _, next := range []Point{
+				NewPoint(point.r-1, point.c),
+				NewPoint(point.r+1, point.c),
+				NewPoint(point.r, point.c-1),
+				NewPoint(point.r, point.c+1),
+			} {
+				if puzzle.ValidIndex(next) != nil || seen[next] || puzzle.IsBlackCell(next) {
+					continue
+				}
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+		regions = append(regions, region)
+	}
+	if len(regions) < 2 {
+		return diagnostics
+	}
+
+	// Report all but the largest region (the first one, in case of a
+	// tie), in order of their first cells.
+	largest := 0
+	for i, region := range regions {
+		if len(region) > len(regions[largest]) {
+			largest = i
+		}
+	}
+	for i, region := range regions {
+		if i == largest {
+			continue
+		}
+		sort.Slice(region, func(a, b int) bool {
+			return region[a].Compare(region[b]) < 0
+		})
+		diagnostics = append(diagnostics, Diagnostic{
+			Kind:    DISCONNECTED_GRID,
+			Message: fmt.Sprintf("region of %d cells is not connected to the rest of the grid", len(region)),
+			Points:  region,
+		})
+	}
+	return diagnostics
+}
+
+// validateSymmetry reports black cells whose symmetric twins are not
+// black.  Each such twin is reported once.
+func (puzzle *Puzzle) validateSymmetry() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	reported := make(map[Point]bool)
+	for point := range puzzle.PointIterator() {
+		if !puzzle.IsBlackCell(point) {
+			continue
+		}
+		for _, twin := range puzzle.SymmetricPoints(point) {
+			if puzzle.IsBlackCell(twin) || reported[twin] {
+				continue
+			}
+			reported[twin] = true
+			diagnostics = append(diagnostics, Diagnostic{
+				Kind: SYMMETRY_VIOLATION,
+				Message: fmt.Sprintf("cell %s is black but %s is not (%s symmetry)",
+					point.String(), twin.String(), puzzle.GetSymmetry()),
+				Points: []Point{point, twin},
+			})
+		}
+	}
+	return diagnostics
+}
+
+// validateDuplicates reports complete answers that appear in more than
+// one word.
+func (puzzle *Puzzle) validateDuplicates() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	answers := make(map[string][]*Word)
+	order := make([]string, 0)
+	for _, word := range puzzle.sortedWords() {
+		if word.length < 2 {
+			continue
+		}
+		answer := puzzle.GetAnswer(word)
+		if strings.Contains(answer, " ") {
+			continue
+		}
+		if _, found := answers[answer]; !found {
+			order = append(order, answer)
+		}
+		answers[answer] = append(answers[answer], word)
+	}
+	for _, answer := range order {
+		words := answers[answer]
+		if len(words) < 2 {
+			continue
+		}
+		d := Diagnostic{
+			Kind:    DUPLICATE_ANSWER,
+			Message: fmt.Sprintf("%s is used %d times", answer, len(words)),
+			Points:  make([]Point, 0),
+			Words:   make([]WordRef, 0),
+		}
+		for _, word := range words {
+			d.Points = append(d.Points, word.point)
+			d.Words = append(d.Words, puzzle.wordRef(word))
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// validateEmpty reports the empty cells (all together) and the words
+// with no clues (one at a time).
+func (puzzle *Puzzle) validateEmpty() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+
+	empty := make([]Point, 0)
+	for point := range puzzle.PointIterator() {
+		if !puzzle.IsBlackCell(point) && strings.TrimSpace(puzzle.GetLetter(point)) == "" {
+			empty = append(empty, point)
+		}
+	}
+	if len(empty) > 0 {
+		diagnostics = append(diagnostics, Diagnostic{
+			Kind:    EMPTY_CELL,
+			Message: fmt.Sprintf("%d cells are empty", len(empty)),
+			Points:  empty,
+		})
+	}
+
+	for _, word := range puzzle.sortedWords() {
+		if word.length < 2 || strings.TrimSpace(word.clue) != "" {
+			continue
+		}
+		ref := puzzle.wordRef(word)
+		diagnostics = append(diagnostics, Diagnostic{
+			Kind:    EMPTY_CLUE,
+			Message: fmt.Sprintf("%s has no clue", ref),
+			Points:  []Point{word.point},
+			Words:   []WordRef{ref},
+		})
+	}
+	return diagnostics
+}
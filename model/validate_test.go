@@ -0,0 +1,108 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getKinds returns the kinds of the diagnostics, in order
+func getKinds(diagnostics []Diagnostic) []DiagnosticKind {
+	kinds := make([]DiagnosticKind, len(diagnostics))
+	for i, d := range diagnostics {
+		kinds[i] = d.Kind
+	}
+	return kinds
+}
+
+// getBlackPuzzle creates a puzzle with black cells set directly, so
+// that symmetry is not enforced.
+func getBlackPuzzle(nRows, nCols int, points []Point) *Puzzle {
+	puzzle := NewRectangularPuzzle(nRows, nCols)
+	for _, point := range points {
+		puzzle.SetCell(point, NewBlackCell(point))
+	}
+	puzzle.RenumberCells()
+	return puzzle
+}
+
+func TestPuzzle_Validate_Good(t *testing.T) {
+	puzzle := getGoodPuzzle()
+	kinds := getKinds(puzzle.Validate())
+	assert.Equal(t, EMPTY_CELL, kinds[0])
+	for _, kind := range kinds[1:] {
+		assert.Equal(t, EMPTY_CLUE, kind)
+	}
+}
+
+func TestPuzzle_Validate_ShortWordsAndSymmetry(t *testing.T) {
+	puzzle := getBlackPuzzle(3, 3, []Point{{1, 3}})
+	diagnostics := puzzle.Validate()
+	assert.Equal(t, []DiagnosticKind{SHORT_WORD, SHORT_WORD, SYMMETRY_VIOLATION, EMPTY_CELL,
+		EMPTY_CLUE, EMPTY_CLUE, EMPTY_CLUE, EMPTY_CLUE, EMPTY_CLUE, EMPTY_CLUE},
+		getKinds(diagnostics))
+
+	assert.Equal(t, []WordRef{{1, ACROSS}}, diagnostics[0].Words)
+	assert.Equal(t, []Point{{1, 1}, {1, 2}}, diagnostics[0].Points)
+	assert.Equal(t, []WordRef{{4, DOWN}}, diagnostics[1].Words)
+	assert.Equal(t, []Point{{1, 3}, {3, 1}}, diagnostics[2].Points)
+
+	// With no symmetry, the black cell is allowed
+	puzzle.SetSymmetry(NO_SYMMETRY)
+	assert.NotContains(t, getKinds(puzzle.Validate()), SYMMETRY_VIOLATION)
+}
+
+func TestPuzzle_Validate_Disconnected(t *testing.T) {
+	puzzle := getBlackPuzzle(3, 3, []Point{{2, 1}, {2, 2}, {2, 3}})
+	diagnostics := puzzle.Validate()
+	kinds := getKinds(diagnostics)
+	assert.Equal(t, UNCHECKED_CELL, kinds[0])
+	for _, kind := range kinds[:6] {
+		assert.Equal(t, UNCHECKED_CELL, kind)
+	}
+	assert.Equal(t, DISCONNECTED_GRID, kinds[6])
+	assert.Equal(t, []Point{{3, 1}, {3, 2}, {3, 3}}, diagnostics[6].Points)
+	assert.Equal(t, []WordRef{{1, ACROSS}}, diagnostics[0].Words)
+}
+
+func TestPuzzle_Validate_Duplicates(t *testing.T) {
+	puzzle := NewPuzzle(3)
+	puzzle.RenumberCells()
+	for i, text := range []string{"CAT", "ARE", "TEA"} {
+		word := puzzle.LookupWord(NewPoint(i+1, 1), ACROSS)
+		assert.Nil(t, puzzle.SetText(word, text))
+		assert.Nil(t, puzzle.SetClue(word, "Clue"))
+	}
+	diagnostics := puzzle.Validate()
+	assert.Equal(t, []DiagnosticKind{DUPLICATE_ANSWER, DUPLICATE_ANSWER, DUPLICATE_ANSWER,
+		EMPTY_CLUE, EMPTY_CLUE, EMPTY_CLUE}, getKinds(diagnostics))
+	assert.Equal(t, "CAT is used 2 times", diagnostics[0].Message)
+	assert.Equal(t, []WordRef{{1, ACROSS}, {1, DOWN}}, diagnostics[0].Words)
+	assert.Equal(t, []WordRef{{2, DOWN}, {4, ACROSS}}, diagnostics[1].Words)
+
+	// Fill in the down clues and the puzzle is clean except for the
+	// duplicates
+	for seq := 1; seq <= 3; seq++ {
+		puzzle.SetClue(puzzle.LookupWordByNumber(seq, DOWN), "Clue")
+	}
+	assert.Equal(t, 3, len(puzzle.Validate()))
+}
+
+func TestDiagnostic_JSON(t *testing.T) {
+	d := Diagnostic{
+		Kind:    SHORT_WORD,
+		Message: "1A has 2 letters, minimum is 3",
+		Points:  []Point{{1, 1}, {1, 2}},
+		Words:   []WordRef{{1, ACROSS}},
+	}
+	jsonBlob, err := json.Marshal(d)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"kind":"short_word","message":"1A has 2 letters, minimum is 3",`+
+		`"points":[{"r":1,"c":1},{"r":1,"c":2}],"words":[{"seq":1,"dir":"A"}]}`, string(jsonBlob))
+
+	var other Diagnostic
+	assert.Nil(t, json.Unmarshal(jsonBlob, &other))
+	assert.Equal(t, d, other)
+	assert.Equal(t, "short_word: 1A has 2 letters, minimum is 3; words 1A; cells {r:1,c:1}, {r:1,c:2}", d.String())
+}
@@ -59,60 +59,6 @@ func (word *Word) String() string {
 	return strings.Join(parts, ",")
 }
 
-// RedoWord gets the last word change to the puzzle and re-applies it
-func (puzzle *Puzzle) RedoWord() {
-
-	// Return immediately if the redo stack is empty
-	if puzzle.redoWordStack.IsEmpty() {
-		return
-	}
-
-	// Pop the old Doable from the redo stack. This will give us the
-	// point and direction of the word.
-	oldDoable, _ := puzzle.redoWordStack.Pop()
-	oldWord := &oldDoable.word
-	oldText := oldDoable.text
-
-	// Construct a new Doable from the current grid for the word in the
-	// doable.
-	newDoable := NewDoable(puzzle, oldWord)
-
-	// Push the new Doable onto the undo stack.
-	puzzle.undoWordStack.Push(newDoable)
-
-	// Set the text of the old Doable in the current grid, but do not
-	// use the SetText method, because it pushes the word back on the
-	// undo stack.
-	puzzle.SetTextWithoutPush(oldWord, oldText)
-}
-
-// UndoWord undoes the last push to the undoWordStack
-func (puzzle *Puzzle) UndoWord() {
-
-	// Return immediately if the undo stack is empty
-	if puzzle.undoWordStack.IsEmpty() {
-		return
-	}
-
-	// Pop the old Doable from the undo stack. This will give us the
-	// point and direction of the word.
-	oldDoable, _ := puzzle.undoWordStack.Pop()
-	oldWord := &oldDoable.word
-	oldText := oldDoable.text
-
-	// Construct a new Doable from the current puzzle for the word in
-	// the doable.
-	newDoable := NewDoable(puzzle, oldWord)
-
-	// Push the new Doable onto the redo stack.
-	puzzle.redoWordStack.Push(newDoable)
-
-	// Set the text of the old Doable in the current grid, but do not
-	// use the SetText method, because it pushes the word back on the
-	// undo stack.
-	puzzle.SetTextWithoutPush(oldWord, oldText)
-}
-
 // WordIterator iterates through the points in a word, stopping when it
 // encounters a black cell or the edge of the grid.
 func (puzzle *Puzzle) WordIterator(point Point, dir Direction) <-chan Point {
@@ -0,0 +1,303 @@
+package model
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// WordIndex is an index of the dictionary that answers simple patterns
+// without scanning every word.  The words are grouped by length, and
+// for each length, there is a bitset of the words that have each letter
+// at each position.  A pattern is then answered by intersecting the
+// bitsets of its positions.
+//
+// The patterns that can be indexed are sequences of:
+//   - A letter, e.g., "A"
+//   - Any letter, "."
+//   - A character class, e.g., "[AEIOU]", "[A-D]", or "[^AEIOU]"
+type WordIndex struct {
+	byLength map[int]*lengthIndex
+}
+
+// lengthIndex is the part of the index for words of one length
+type lengthIndex struct {
+	words     []ScoredWord     // Words in dictionary order, best first
+	positions [][26]wordBitset // Words with each letter at each position
+}
+
+// wordBitset is a set of indices into the words of a lengthIndex
+type wordBitset []uint64
+
+// letterMask is a set of the letters A-Z, with bit 0 for A
+type letterMask uint32
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// All the letters A-Z
+const ALL_LETTERS = letterMask(1<<26 - 1)
+
+// ---------------------------------------------------------------------
+// Constructor
+// ---------------------------------------------------------------------
+
+// NewWordIndex creates an index of the words in a dictionary, which
+// must already be in dictionary order.
+func NewWordIndex(dict Dictionary) *WordIndex {
+	index := new(WordIndex)
+	index.byLength = make(map[int]*lengthIndex)
+	for _, sw := range dict {
+		n := len(sw.Word)
+		li, ok := index.byLength[n]
+		if !ok {
+			li = new(lengthIndex)
+			index.byLength[n] = li
+		}
+		li.words = append(li.words, sw)
+	}
+	for n, li := range index.byLength {
+		size := (len(li.words) + 63) / 64
+		li.positions = make([][26]wordBitset, n)
+		for i := range li.positions {
+			for letter := range li.positions[i] {
+				li.positions[i][letter] = make(wordBitset, size)
+			}
+		}
+		for w, sw := range li.words {
+			for i := 0; i < n; i++ {
+				li.positions[i][sw.Word[i]-'A'].set(w)
+			}
+		}
+	}
+	return index
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Each calls f with every word that matches the pattern, best first,
+// until f returns false.  It returns false (without calling f) if the
+// pattern cannot be answered by the index, in which case the caller
+// must use a regular expression instead.
+func (index *WordIndex) Each(pattern string, f func(ScoredWord) bool) bool {
+	masks, ok := parseIndexPattern(pattern)
+	if !ok {
+		return false
+	}
+	index.each(masks, f)
+	return true
+}
+
+// each calls f with every word that has an allowed letter at every
+// position, best first, until f returns false.
+func (index *WordIndex) each(masks []letterMask, f func(ScoredWord) bool) {
+	li, ok := index.byLength[len(masks)]
+	if !ok {
+		return
+	}
+	result := li.lookup(masks)
+	for w, word := range result {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			word &^= 1 << bit
+			if !f(li.words[w*64+bit]) {
+				return
+			}
+		}
+	}
+}
+
+// viable returns the number of words at or above the minimum score that
+// have an allowed letter at every position, and the letters that those
+// words have at each position.
+func (index *WordIndex) viable(masks []letterMask, minScore int) (int, []letterMask) {
+	letters := make([]letterMask, len(masks))
+	li, ok := index.byLength[len(masks)]
+	if !ok {
+		return 0, letters
+	}
+	result := li.lookup(masks)
+
+	// The words are sorted best first, so the ones at or above the
+	// minimum score come before all the others.
+	n := sort.Search(len(li.words), func(i int) bool {
+		return li.words[i].Score < minScore
+	})
+	for w := range result {
+		switch {
+		case w*64 >= n:
+			result[w] = 0
+		case w*64+64 > n:
+			result[w] &= 1<<(n-w*64) - 1
+		}
+	}
+
+	count := 0
+	for _, word := range result {
+		count += bits.OnesCount64(word)
+	}
+	if count == 0 {
+		return 0, letters
+	}
+	for i, mask := range masks {
+		for letter := 0; letter < 26; letter++ {
+			if mask&(1<<letter) != 0 && result.intersects(li.positions[i][letter]) {
+				letters[i] |= 1 << letter
+			}
+		}
+	}
+	return count, letters
+}
+
+// lookup returns the set of words that have an allowed letter at every
+// position.
+func (li *lengthIndex) lookup(masks []letterMask) wordBitset {
+	size := (len(li.words) + 63) / 64
+	result := make(wordBitset, size)
+	for i := range result {
+		result[i] = ^uint64(0)
+	}
+	if extra := len(li.words) % 64; extra != 0 {
+		result[size-1] = 1<<extra - 1
+	}
+
+	union := make(wordBitset, size)
+	for i, mask := range masks {
+		if mask == ALL_LETTERS {
+			continue
+		}
+
+		// Take the union of the fewer of the allowed letters and the
+		// disallowed letters.
+		negate := bits.OnesCount32(uint32(mask)) > 13
+		if negate {
+			mask = ALL_LETTERS &^ mask
+		}
+		clear(union)
+		for letter := 0; letter < 26; letter++ {
+			if mask&(1<<letter) != 0 {
+				union.or(li.positions[i][letter])
+			}
+		}
+		if negate {
+			result.andNot(union)
+		} else {
+			result.and(union)
+		}
+	}
+	return result
+}
+
+// intersects returns true if this set has a member in another one
+func (bs wordBitset) intersects(other wordBitset) bool {
+	for i := range bs {
+		if bs[i]&other[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// set adds an index to the set
+func (bs wordBitset) set(i int) {
+	bs[i/64] |= 1 << (i % 64)
+}
+
+// or adds the members of another set to this one
+func (bs wordBitset) or(other wordBitset) {
+	for i := range bs {
+		bs[i] |= other[i]
+	}
+}
+
+// and removes the members of this set that are not in another one
+func (bs wordBitset) and(other wordBitset) {
+	for i := range bs {
+		bs[i] &= other[i]
+	}
+}
+
+// andNot removes the members of this set that are in another one
+func (bs wordBitset) andNot(other wordBitset) {
+	for i := range bs {
+		bs[i] &^= other[i]
+	}
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// parseIndexPattern converts a pattern to the set of letters allowed at
+// each position.  It returns false if the pattern contains anything
+// other than letters, ".", and character classes.
+func parseIndexPattern(pattern string) ([]letterMask, bool) {
+	masks := make([]letterMask, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '.':
+			masks = append(masks, ALL_LETTERS)
+		case ch >= 'A' && ch <= 'Z':
+			masks = append(masks, 1<<(ch-'A'))
+		case ch == '[':
+			mask, n, ok := parseIndexClass(pattern[i+1:])
+			if !ok {
+				return nil, false
+			}
+			masks = append(masks, mask)
+			i += n
+		default:
+			return nil, false
+		}
+	}
+	return masks, true
+}
+
+// parseIndexClass parses a character class, starting just after the
+// "[".  It returns the allowed letters and the number of bytes through
+// the closing "]".
+func parseIndexClass(s string) (letterMask, int, bool) {
+	negate := len(s) > 0 && s[0] == '^'
+	i := 0
+	if negate {
+		i++
+	}
+	mask := letterMask(0)
+	for ; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ']':
+			if mask == 0 {
+				// An empty class is not allowed; "[]" is literal here
+				return 0, 0, false
+			}
+			if negate {
+				mask = ALL_LETTERS &^ mask
+			}
+			return mask, i + 1, true
+		case ch >= 'A' && ch <= 'Z':
+			if i+2 < len(s) && s[i+1] == '-' && s[i+2] != ']' {
+				last := s[i+2]
+				if last < ch || last > 'Z' {
+					return 0, 0, false
+				}
+				for letter := ch; letter <= last; letter++ {
+					mask |= 1 << (letter - 'A')
+				}
+				i += 2
+			} else {
+				mask |= 1 << (ch - 'A')
+			}
+		default:
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
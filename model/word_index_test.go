@@ -0,0 +1,142 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Patterns used to compare the index with regular expressions
+var indexPatterns = []string{
+	`[^AEIOU][AEIOU].E`,
+	`HEART`,
+	`.....`,
+	`A..Z.`,
+	`[A-DX-Z].[^S]S`,
+	`Q[^U]...`,
+	`...............`,
+	`.........ING`,
+	`XYZZYX`,
+}
+
+// indexWords returns all the words that the index matches
+func indexWords(index *WordIndex, pattern string) ([]ScoredWord, bool) {
+	have := make([]ScoredWord, 0)
+	ok := index.Each(pattern, func(sw ScoredWord) bool {
+		have = append(have, sw)
+		return true
+	})
+	return have, ok
+}
+
+// scanWords returns all the words that a regular expression matches
+func scanWords(pattern string) []ScoredWord {
+	want := make([]ScoredWord, 0)
+	match := regexpMatcher(pattern)
+	for _, sw := range dictionary {
+		if match(sw.Word) {
+			want = append(want, sw)
+		}
+	}
+	return want
+}
+
+func TestParseIndexPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []letterMask
+		wantOK  bool
+	}{
+		{"letters", "AB", []letterMask{1 << 0, 1 << 1}, true},
+		{"dot", "A.", []letterMask{1 << 0, ALL_LETTERS}, true},
+		{"class", "[AC]", []letterMask{1<<0 | 1<<2}, true},
+		{"range", "[B-D]", []letterMask{1<<1 | 1<<2 | 1<<3}, true},
+		{"negated", "[^B-Z]", []letterMask{1 << 0}, true},
+		{"star", "A*", nil, false},
+		{"group", "(A|B)", nil, false},
+		{"unclosed", "[AB", nil, false},
+		{"empty class", "[]", nil, false},
+		{"lower case", "a", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, ok := parseIndexPattern(tt.pattern)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
+
+func TestWordIndex_Each(t *testing.T) {
+	index := NewWordIndex(dictionary)
+	for _, pattern := range indexPatterns {
+		t.Run(pattern, func(t *testing.T) {
+			have, ok := indexWords(index, pattern)
+			assert.True(t, ok)
+			assert.Equal(t, scanWords(pattern), have)
+		})
+	}
+}
+
+func TestWordIndex_Each_Stop(t *testing.T) {
+	index := NewWordIndex(dictionary)
+	count := 0
+	index.Each(".....", func(sw ScoredWord) bool {
+		count++
+		return count < 3
+	})
+	assert.Equal(t, 3, count)
+}
+
+func TestWordIndex_Each_Unsupported(t *testing.T) {
+	index := NewWordIndex(dictionary)
+	_, ok := indexWords(index, "HEAR(T|S)")
+	assert.False(t, ok)
+
+	// GetMatchingWords falls back to a regular expression
+	have := make([]string, 0)
+	for word := range GetMatchingWords("HEAR(T|S)", make(chan struct{})) {
+		have = append(have, word)
+	}
+	assert.Equal(t, []string{"HEARS", "HEART"}, have)
+}
+
+// ---------------------------------------------------------------------
+// Benchmarks
+// ---------------------------------------------------------------------
+
+func BenchmarkMatch_Index(b *testing.B) {
+	index := NewWordIndex(dictionary)
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range indexPatterns {
+			index.Each(pattern, func(ScoredWord) bool { return true })
+		}
+	}
+}
+
+func BenchmarkMatch_Regexp(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range indexPatterns {
+			scanWords(pattern)
+		}
+	}
+}
+
+func BenchmarkNewWordIndex(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewWordIndex(dictionary)
+	}
+}
+
+func BenchmarkGetConstraints(b *testing.B) {
+	puzzle := NewPuzzle(15)
+	puzzle.RenumberCells()
+	puzzle.SetText(puzzle.LookupWordByNumber(1, DOWN), "S      ")
+	puzzle.SetText(puzzle.LookupWordByNumber(5, DOWN), "  T    ")
+	word := puzzle.LookupWordByNumber(1, ACROSS)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		puzzle.GetConstraints(word)
+	}
+}
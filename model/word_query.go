@@ -0,0 +1,241 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// WordQuery is a parsed word search query.  A query is a pattern
+// followed by optional clauses, separated by spaces.  The pattern is a
+// sequence of:
+//   - A letter, e.g., "A"
+//   - "?" (or ".") for any letter
+//   - "@" for a vowel (AEIOU) and "#" for a consonant
+//   - A letter set, e.g., "[ABC]", "[A-D]", or "[^AEIOU]"
+//   - "*" for any number of letters, including none
+//
+// The clauses are:
+//   - "+LETTERS": the word must contain all these letters (a letter
+//     listed twice must appear at least twice)
+//   - "=LETTERS": the word must be an anagram of these letters, where
+//     "?" stands for any letter
+//
+// For example, "C*T +A" finds words that start with C, end with T and
+// contain an A, and "=TEAR?" finds the five-letter words that contain
+// the letters of TEAR.  If there is no pattern, it is "*".
+//
+// Queries are matched directly, not by regular expressions, so that
+// arbitrary input from the REST API takes at most time proportional to
+// the pattern length times the word length.
+type WordQuery struct {
+	tokens        []queryToken
+	contains      [26]int // Letters the word must contain
+	anagram       [26]int // Letters of the anagram, if any
+	anagramLength int     // Length of the anagram, or 0 if none
+}
+
+// queryToken is one element of a pattern
+type queryToken struct {
+	letters letterMask // The letters allowed at this position
+	star    bool       // True for "*", any number of letters
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// The longest query that will be parsed
+const MAX_QUERY_LENGTH = 100
+
+// The vowels and the consonants
+var (
+	VOWELS     = lettersOf("AEIOU")
+	CONSONANTS = ALL_LETTERS &^ VOWELS
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// ParseWordQuery parses a query in the language described in WordQuery.
+// An error is returned if the query is too long, or if it contains
+// anything not in the language.
+func ParseWordQuery(query string) (*WordQuery, error) {
+	if len(query) > MAX_QUERY_LENGTH {
+		return nil, fmt.Errorf("query is longer than %d characters", MAX_QUERY_LENGTH)
+	}
+	q := new(WordQuery)
+	havePattern := false
+	for _, field := range strings.Fields(strings.ToUpper(query)) {
+		switch field[0] {
+		case '+':
+			if err := countLetters(field[1:], &q.contains, false); err != nil {
+				return nil, err
+			}
+		case '=':
+			q.anagram = [26]int{}
+			if err := countLetters(field[1:], &q.anagram, true); err != nil {
+				return nil, err
+			}
+			q.anagramLength = len(field) - 1
+		default:
+			if havePattern {
+				return nil, fmt.Errorf("more than one pattern in %q", query)
+			}
+			tokens, err := parseQueryPattern(field)
+			if err != nil {
+				return nil, err
+			}
+			q.tokens = tokens
+			havePattern = true
+		}
+	}
+	if !havePattern {
+		if q.anagramLength > 0 {
+			q.tokens, _ = parseQueryPattern(strings.Repeat("?", q.anagramLength))
+		} else {
+			q.tokens = []queryToken{{star: true}}
+		}
+	}
+	return q, nil
+}
+
+// parseQueryPattern converts a pattern to a list of tokens
+func parseQueryPattern(pattern string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z':
+			tokens = append(tokens, queryToken{letters: 1 << (ch - 'A')})
+		case ch == '?' || ch == '.':
+			tokens = append(tokens, queryToken{letters: ALL_LETTERS})
+		case ch == '@':
+			tokens = append(tokens, queryToken{letters: VOWELS})
+		case ch == '#':
+			tokens = append(tokens, queryToken{letters: CONSONANTS})
+		case ch == '*':
+			// Consecutive stars are the same as one
+			if n := len(tokens); n == 0 || !tokens[n-1].star {
+				tokens = append(tokens, queryToken{star: true})
+			}
+		case ch == '[':
+			letters, n, ok := parseIndexClass(pattern[i+1:])
+			if !ok {
+				return nil, fmt.Errorf("invalid letter set in %q", pattern)
+			}
+			tokens = append(tokens, queryToken{letters: letters})
+			i += n
+		default:
+			return nil, fmt.Errorf("invalid character %q in %q", ch, pattern)
+		}
+	}
+	return tokens, nil
+}
+
+// countLetters adds the letters of a clause to a set of counts.  If
+// blanks are allowed, "?" is accepted and not counted.
+func countLetters(letters string, counts *[26]int, blanks bool) error {
+	if letters == "" {
+		return fmt.Errorf("no letters in clause")
+	}
+	for _, ch := range letters {
+		switch {
+		case ch >= 'A' && ch <= 'Z':
+			counts[ch-'A']++
+		case ch == '?' && blanks:
+		default:
+			return fmt.Errorf("invalid character %q in %q", ch, letters)
+		}
+	}
+	return nil
+}
+
+// lettersOf returns the set of letters in a string
+func lettersOf(s string) letterMask {
+	mask := letterMask(0)
+	for _, ch := range s {
+		mask |= 1 << (ch - 'A')
+	}
+	return mask
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Matches returns true if the word matches the query
+func (q *WordQuery) Matches(word string) bool {
+	return q.matchesPattern(word) && q.matchesClauses(word)
+}
+
+// matchesPattern returns true if the word matches the pattern.  This is
+// the usual wildcard algorithm: when a token fails to match, go back to
+// the most recent "*" and let it take one more letter.
+func (q *WordQuery) matchesPattern(word string) bool {
+	t, w := 0, 0
+	starT, starW := -1, 0
+	for w < len(word) {
+		switch {
+		case t < len(q.tokens) && q.tokens[t].star:
+			starT, starW = t, w
+			t++
+		case t < len(q.tokens) && q.tokens[t].letters.has(word[w]):
+			t++
+			w++
+		case starT >= 0:
+			starW++
+			t, w = starT+1, starW
+		default:
+			return false
+		}
+	}
+	for t < len(q.tokens) && q.tokens[t].star {
+		t++
+	}
+	return t == len(q.tokens)
+}
+
+// matchesClauses returns true if the word satisfies the "contains" and
+// "anagram" clauses.
+func (q *WordQuery) matchesClauses(word string) bool {
+	var counts [26]int
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'A' || word[i] > 'Z' {
+			return false
+		}
+		counts[word[i]-'A']++
+	}
+	if q.anagramLength > 0 && len(word) != q.anagramLength {
+		return false
+	}
+	for i := range counts {
+		if counts[i] < q.contains[i] || counts[i] < q.anagram[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// masks returns the letters allowed at each position, if the pattern
+// has a fixed length (i.e., no "*"), so that it can be looked up in the
+// dictionary index.
+func (q *WordQuery) masks() ([]letterMask, bool) {
+	masks := make([]letterMask, len(q.tokens))
+	for i, token := range q.tokens {
+		if token.star {
+			return nil, false
+		}
+		masks[i] = token.letters
+	}
+	return masks, true
+}
+
+// has returns true if the letter is in the set
+func (mask letterMask) has(ch byte) bool {
+	return ch >= 'A' && ch <= 'Z' && mask&(1<<(ch-'A')) != 0
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWordQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"bad character", "AB$"},
+		{"regexp", "(A|B)*"},
+		{"unclosed set", "[AB"},
+		{"two patterns", "A* *B"},
+		{"empty contains", "A* +"},
+		{"bad anagram", "=AB1"},
+		{"blank in contains", "A* +?"},
+		{"too long", strings.Repeat("?", MAX_QUERY_LENGTH+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWordQuery(tt.query)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestWordQuery_Matches(t *testing.T) {
+	tests := []struct {
+		query string
+		word  string
+		want  bool
+	}{
+		{"HEART", "HEART", true},
+		{"h?art", "HEART", true},
+		{"H.ART", "HEART", true},
+		{"H@ART", "HEART", true},
+		{"H#ART", "HEART", false},
+		{"#@@#?", "HEART", true},
+		{"[GH]EART", "HEART", true},
+		{"[^GH]EART", "HEART", false},
+		{"[A-H]EART", "HEART", true},
+		{"H*", "HEART", true},
+		{"*T", "HEART", true},
+		{"*EA*", "HEART", true},
+		{"H*A*T", "HEART", true},
+		{"H**T", "HEART", true},
+		{"H*X", "HEART", false},
+		{"HEART*", "HEART", true},
+		{"????", "HEART", false},
+		{"*", "HEART", true},
+		{"H* +TA", "HEART", true},
+		{"H* +TT", "HEART", false},
+		{"=EARTH", "HEART", true},
+		{"=TEAR?", "HEART", true},
+		{"=TEAR", "HEART", false},
+		{"=TEARS", "HEART", false},
+		{"?E* =TEAR? +H", "HEART", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseWordQuery(tt.query)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, q.Matches(tt.word))
+		})
+	}
+}
+
+func TestWordQuery_Pathological(t *testing.T) {
+	// A pattern that makes a backtracking regexp engine take
+	// exponential time must still be quick.
+	q, err := ParseWordQuery(strings.Repeat("*A", 40) + "B")
+	assert.Nil(t, err)
+	assert.False(t, q.Matches(strings.Repeat("A", 60)))
+}
+
+func TestSearchWords(t *testing.T) {
+	defer useWordList(t, "HEART;60\nEARTH;70\nHATER;40\nRATHE;20\nHEARTH;30\nTREAT;50")()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"=HEART", []string{"EARTH", "HEART", "HATER", "RATHE"}},
+		{"H*", []string{"HEART", "HATER", "HEARTH"}},
+		{"#@@#? +T", []string{"HEART"}},
+		{"*T +TT", []string{"TREAT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseWordQuery(tt.query)
+			assert.Nil(t, err)
+			have := make([]string, 0)
+			for sw := range SearchWords(q, make(chan struct{})) {
+				have = append(have, sw.Word)
+			}
+			assert.Equal(t, tt.want, have)
+		})
+	}
+}
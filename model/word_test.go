@@ -1,7 +1,6 @@
 package model
 
 import (
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -71,48 +70,48 @@ func TestPuzzle_GetWordNumber(t *testing.T) {
 
 func TestPuzzle_RedoWord(t *testing.T) {
 	var (
-		word   *Word
-		doable Doable
+		word       *Word
+		undo, redo []*Command
 	)
 	puzzle := getGoodPuzzle()
+	puzzle.ClearHistory()
 
-	// Stacks should be empty
-	assert.Equal(t, 0, puzzle.undoWordStack.Len())
-	assert.Equal(t, 0, puzzle.redoWordStack.Len())
+	// History should be empty
+	undo, redo = puzzle.GetHistory()
+	assert.Equal(t, 0, len(undo))
+	assert.Equal(t, 0, len(redo))
 
-	// Now set the text in a word. Undo stack should contain the old
-	// value, and redo stack should be empty.
+	// Now set the text in a word. The change should be undoable, and
+	// there should be nothing to redo.
 	word = puzzle.LookupWord(NewPoint(8, 8), ACROSS)
 	puzzle.SetText(word, "GLOW")
 	assert.Equal(t, "GLOW", puzzle.GetText(word))
-	assert.Equal(t, 1, puzzle.undoWordStack.Len())
-	assert.Equal(t, 0, puzzle.redoWordStack.Len())
-	doable, _ = puzzle.undoWordStack.Peek()
-	assert.Equal(t, "    ", doable.text)
-	fmt.Printf("DEBUG: doable=%s\n", doable.String())
+	undo, redo = puzzle.GetHistory()
+	assert.Equal(t, 1, len(undo))
+	assert.Equal(t, 0, len(redo))
+	assert.Equal(t, "    ", undo[0].Before)
+	assert.Equal(t, "GLOW", undo[0].After)
 
-	// Now do the undo. Undo stack should now be empty, and redo stack
-	// should contain the new value.
-	puzzle.UndoWord()
+	// Now do the undo. The change should now be redoable.
+	puzzle.Undo()
 
 	assert.Equal(t, "    ", puzzle.GetText(word))
-	assert.Equal(t, 0, puzzle.undoWordStack.Len())
-	assert.Equal(t, 1, puzzle.redoWordStack.Len())
-	doable, _ = puzzle.redoWordStack.Peek()
-	assert.Equal(t, "GLOW", doable.text)
+	undo, redo = puzzle.GetHistory()
+	assert.Equal(t, 0, len(undo))
+	assert.Equal(t, 1, len(redo))
+	assert.Equal(t, "GLOW", redo[0].After)
 
 	// Do a redo, and the original grid should be there, with the last
-	// operation in the undo stack.
-	puzzle.RedoWord()
+	// operation undoable again.
+	puzzle.Redo()
 
 	assert.Equal(t, "GLOW", puzzle.GetText(word))
-	assert.Equal(t, 1, puzzle.undoWordStack.Len())
-	assert.Equal(t, 0, puzzle.redoWordStack.Len())
-	doable, _ = puzzle.undoWordStack.Peek()
-	assert.Equal(t, "    ", doable.text)
+	undo, redo = puzzle.GetHistory()
+	assert.Equal(t, 1, len(undo))
+	assert.Equal(t, 0, len(redo))
 
 	// Do one more undo, and the grid should be empty
-	puzzle.UndoWord()
+	puzzle.Undo()
 
 	for _, word := range puzzle.words {
 		text := puzzle.GetText(word)
@@ -123,38 +122,31 @@ func TestPuzzle_RedoWord(t *testing.T) {
 		}
 	}
 
-	puzzle.UndoWord() // Should do nothing
+	assert.Nil(t, puzzle.Undo()) // Should do nothing
 }
 
 func TestPuzzle_UndoWord(t *testing.T) {
 	var (
-		word   *Word
-		doable Doable
+		word       *Word
+		undo, redo []*Command
 	)
 	puzzle := getGoodPuzzle()
+	puzzle.ClearHistory()
 
-	// Stacks should be empty
-	assert.Equal(t, 0, puzzle.undoWordStack.Len())
-	assert.Equal(t, 0, puzzle.redoWordStack.Len())
-
-	// Now set the text in a word. UndoStack should contain the old
-	// value.
+	// Set the text in a word.  Redo should do nothing.
 	word = puzzle.LookupWord(NewPoint(8, 8), ACROSS)
 	puzzle.SetText(word, "GLOW")
 	assert.Equal(t, "GLOW", puzzle.GetText(word))
-	assert.Equal(t, 1, puzzle.undoWordStack.Len())
-	assert.Equal(t, 0, puzzle.redoWordStack.Len())
-	doable, _ = puzzle.undoWordStack.Peek()
-	assert.Equal(t, "    ", doable.text)
-	puzzle.RedoWord() // Should do nothing
-
-	// Now do the undo. Undo stack should now be empty, and redo stack
-	// should contain the new value.
-	puzzle.UndoWord()
-
-	assert.Equal(t, 0, puzzle.undoWordStack.Len())
-	assert.Equal(t, 1, puzzle.redoWordStack.Len())
-	doable, _ = puzzle.redoWordStack.Peek()
-	assert.Equal(t, "GLOW", doable.text)
+	assert.Nil(t, puzzle.Redo())
+	assert.Equal(t, "GLOW", puzzle.GetText(word))
 
+	// Now do the undo. The change should be redoable.
+	cmd := puzzle.Undo()
+	assert.Equal(t, WORD_COMMAND, cmd.Kind)
+	assert.Equal(t, word.point, cmd.Point)
+	assert.Equal(t, ACROSS, cmd.Dir)
+	undo, redo = puzzle.GetHistory()
+	assert.Equal(t, 0, len(undo))
+	assert.Equal(t, 1, len(redo))
+	assert.Equal(t, "GLOW", redo[0].After)
 }
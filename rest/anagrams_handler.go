@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// AnagramList is the response to an anagram request
+type AnagramList struct {
+	Letters string              `json:"letters"`
+	Type    string              `json:"type"`
+	Words   []model.ScoredWord  `json:"words,omitempty"`
+	Pairs   []model.AnagramPair `json:"pairs,omitempty"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// The types of anagram requests
+const (
+	ANAGRAM_EXACT = "exact"
+	ANAGRAM_SUB   = "sub"
+	ANAGRAM_PAIRS = "pairs"
+)
+
+// The most letters that can be anagrammed
+const MAX_ANAGRAM_LETTERS = 30
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// AnagramsHandler serves REST requests for:
+//
+//   - GET /anagrams?letters={letters}&type={type}&min={n}&limit={n}:
+//     Returns the anagrams of the letters.  The type is "exact" (the
+//     default) for words that use all the letters, "sub" for words that
+//     use some of them, or "pairs" for two-word anagrams.  For "sub" and
+//     "pairs", min is the minimum length of a word (default 3).  At most
+//     limit results are returned (default 100, maximum 1000).
+func AnagramsHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("Entering AnagramsHandler")
+
+	// Get the session
+	if _, err := GetSession(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get the parameters
+	badRequest := func(errmsg string) {
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusBadRequest)
+	}
+	params := r.URL.Query()
+	list := AnagramList{Letters: params.Get("letters"), Type: params.Get("type")}
+	if list.Type == "" {
+		list.Type = ANAGRAM_EXACT
+	}
+	if list.Letters == "" || len(list.Letters) > MAX_ANAGRAM_LETTERS {
+		badRequest(fmt.Sprintf("letters must have 1 to %d characters", MAX_ANAGRAM_LETTERS))
+		return
+	}
+	minLength, ok := intParam(params.Get("min"), 3, 1, MAX_ANAGRAM_LETTERS)
+	if !ok {
+		badRequest(fmt.Sprintf("invalid min %q", params.Get("min")))
+		return
+	}
+	limit, ok := intParam(params.Get("limit"), DEFAULT_WORD_LIMIT, 1, MAX_WORD_LIMIT)
+	if !ok {
+		badRequest(fmt.Sprintf("invalid limit %q", params.Get("limit")))
+		return
+	}
+
+	// Find the anagrams
+	switch list.Type {
+	case ANAGRAM_EXACT:
+		list.Words = model.FindAnagrams(list.Letters)
+	case ANAGRAM_SUB:
+		list.Words = model.FindSubAnagrams(list.Letters, minLength)
+	case ANAGRAM_PAIRS:
+		list.Pairs = model.FindTwoWordAnagrams(list.Letters, minLength, limit)
+	default:
+		badRequest(fmt.Sprintf("invalid type %q", list.Type))
+		return
+	}
+	if len(list.Words) > limit {
+		list.Words = list.Words[:limit]
+	}
+
+	jsonBlob, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonBlob)
+
+	log.Println("Leaving AnagramsHandler")
+}
+
+// intParam parses an optional integer parameter, returning the default
+// if it is empty, and false if it is not an integer in the range.
+func intParam(s string, defaultValue, min, max int) (int, bool) {
+	if s == "" {
+		return defaultValue, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// doAnagramsRequest sends a request to the anagrams handler and returns
+// the response
+func doAnagramsRequest(session *Session, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/anagrams?"+query, nil)
+	if session != nil {
+		req.AddCookie(session.NewSessionCookie())
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(AnagramsHandler)
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAnagramsHandler(t *testing.T) {
+	session, _ := newTestSession(t, "rest-anagrams")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-anagrams")
+	tests := []struct {
+		name       string
+		session    *Session
+		query      string
+		wantStatus int
+		wantWord   string
+		wantPairs  bool
+	}{
+		{"exact", session, "letters=TEARH", http.StatusOK, "HEART", false},
+		{"sub", session, "letters=HEARTS&type=sub&min=5&limit=1000", http.StatusOK, "HEART", false},
+		{"pairs", session, "letters=HEARTHAT&type=pairs&limit=1000", http.StatusOK, "", true},
+		{"no letters", session, "type=sub", http.StatusBadRequest, "", false},
+		{"bad type", session, "letters=HEART&type=xyz", http.StatusBadRequest, "", false},
+		{"bad min", session, "letters=HEART&min=x", http.StatusBadRequest, "", false},
+		{"no session", nil, "letters=HEART", http.StatusUnauthorized, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doAnagramsRequest(tt.session, tt.query)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if rr.Code != http.StatusOK {
+				return
+			}
+			list := new(AnagramList)
+			assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), list))
+			if tt.wantWord != "" {
+				found := false
+				for _, sw := range list.Words {
+					found = found || sw.Word == tt.wantWord
+				}
+				assert.True(t, found)
+			}
+			assert.Equal(t, tt.wantPairs, len(list.Pairs) > 0)
+		})
+	}
+}
@@ -0,0 +1,599 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// CollabMessage is a message sent over a collaboration WebSocket, in
+// either direction.  Which of the fields are used depends on the type:
+//
+// From the client:
+//
+//   - toggle: Toggles the black cell at (r, c)
+//   - letter: Sets the letter at (r, c) to letter (empty to clear it)
+//   - clue:   Sets the clue of word seq in direction dir
+//   - cursor: Moves the client's cursor to (r, c), facing dir
+//   - save:   Saves the shared puzzle and its history to the database
+//
+// Each edit (toggle, letter, or clue) must give the version of the
+// puzzle that the client last saw.
+//
+// From the server:
+//
+//   - state:  Sent on joining, with the puzzle, its version, the
+//     client's own ID, and the cursors of the other clients
+//   - op:     An edit that was applied, sent to every client (including
+//     the one that sent it) with the new version and puzzle
+//   - reject: An edit that was not applied, sent back to the client
+//     that sent it, with the reason and the current puzzle
+//   - cursor: Another client's cursor moved
+//   - leave:  Another client left, so its cursor should be removed
+//   - saved:  The puzzle was saved at the specified version
+//   - refresh: The puzzle was renamed or shared differently, with the
+//     puzzle under its current name
+//   - error:  A message could not be understood or is not allowed
+type CollabMessage struct {
+	Type     string         `json:"type"`
+	Version  int            `json:"version"`
+	Client   string         `json:"client,omitempty"`
+	Username string         `json:"username,omitempty"`
+	Row      int            `json:"r,omitempty"`
+	Col      int            `json:"c,omitempty"`
+	Dir      string         `json:"dir,omitempty"`
+	Seq      int            `json:"seq,omitempty"`
+	Letter   string         `json:"letter,omitempty"`
+	Clue     string         `json:"clue,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Puzzle   *PuzzleDetail  `json:"puzzle,omitempty"`
+	Cursors  []CollabCursor `json:"cursors,omitempty"`
+}
+
+// CollabCursor is where a client's cursor is in the grid.
+type CollabCursor struct {
+	Client   string `json:"client"`
+	Username string `json:"username"`
+	Row      int    `json:"r"`
+	Col      int    `json:"c"`
+	Dir      string `json:"dir"`
+}
+
+// collabRoom is the shared copy of a puzzle being edited by one or more
+// clients.  Edits are applied one at a time, in the order they arrive,
+// and each one increments the version.
+type collabRoom struct {
+	mu        sync.Mutex
+	id        int
+	owner     int
+	puzzle    *model.Puzzle
+	version   int
+	changed   map[string]int // Version at which each cell, clue, or the grid last changed
+	clients   map[string]*collabClient
+	cursors   map[string]CollabCursor
+	lastSaved int
+}
+
+// collabClient is one WebSocket connection to a room.
+type collabClient struct {
+	id       string
+	userid   int
+	username string
+	role     model.Role // Changed only while the room is locked
+	conn     *websocket.Conn
+	send     chan *CollabMessage // Messages waiting to be written to conn
+	mu       sync.Mutex          // Held while sending to or closing send
+	closed   bool
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+const (
+	COLLAB_SEND_BUFFER   = 64               // Messages queued for a client before it is dropped
+	COLLAB_WRITE_TIMEOUT = 10 * time.Second // Time allowed to write a message to a client
+	COLLAB_READ_LIMIT    = 16 * 1024        // Largest message accepted from a client, in bytes
+	COLLAB_PONG_TIMEOUT  = 60 * time.Second // Time allowed between a client's pongs (or first message)
+	COLLAB_PING_INTERVAL = 50 * time.Second // Time between pings, which must be less than COLLAB_PONG_TIMEOUT
+)
+
+// collabRooms are the rooms with at least one client, by puzzle ID.
+var (
+	collabRoomsMu sync.Mutex
+	collabRooms   = make(map[int]*collabRoom)
+)
+
+var collabUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// CollabHandler serves GET /collab/{id}, which upgrades to a WebSocket
+// on which clients edit a puzzle together, as described in
+// CollabMessage.  The user must be logged in and have at least view
+// access to the puzzle; only editors and the owner can send edits or
+// save.
+//
+// All the clients of a puzzle share one copy of it, loaded from the
+// database when the first one joins and discarded when the last one
+// leaves.  It is separate from the working copies that PuzzleHandler
+// keeps in each session, so changes made here are only seen there once
+// they are saved.
+//
+// Edits are applied in the order the server receives them.  An edit is
+// rejected if the cell or clue it changes, or for a clue the grid, has
+// changed since the version the client gave, so that no one overwrites
+// a change they have not seen.  Every client sees the same edits in the
+// same order, so they all end up with the same puzzle.
+//
+// The shared copy is saved over the puzzle with its ID, so it is never
+// saved under an old name, and not at all if the puzzle has been deleted
+// or saved by someone else since the room loaded it.  When the puzzle is
+// renamed, deleted, or shared differently through PuzzleHandler, each
+// client's access is checked again (see refreshCollabRoom).
+//
+// The server pings each client every COLLAB_PING_INTERVAL, and drops
+// one that does not answer within COLLAB_PONG_TIMEOUT or sends a
+// message larger than COLLAB_READ_LIMIT.
+func CollabHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("Entering CollabHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get the puzzle ID and check that the user can see the puzzle
+	idString := strings.Trim(r.URL.Path[len("/collab/"):], "/")
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		errmsg := fmt.Sprintf("invalid puzzle id %q", idString)
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusNotFound)
+		return
+	}
+	access, err := model.GetPuzzleAccess(session.USERID, id)
+	if err != nil {
+		pr := &puzzleRequest{w: w, r: r}
+		pr.dbError(err)
+		return
+	}
+
+	// Upgrade to a WebSocket, passing along the renewed session cookie
+	conn, err := collabUpgrader.Upgrade(w, r, http.Header{"Set-Cookie": w.Header()["Set-Cookie"]})
+	if err != nil {
+		log.Println(err) // The upgrader has already written the error
+		return
+	}
+	conn.SetReadLimit(COLLAB_READ_LIMIT)
+	conn.SetReadDeadline(time.Now().Add(COLLAB_PONG_TIMEOUT))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(COLLAB_PONG_TIMEOUT))
+	})
+	client := &collabClient{
+		id:       uuid.NewString(),
+		userid:   session.USERID,
+		username: session.USERNAME,
+		role:     access.Role,
+		conn:     conn,
+		send:     make(chan *CollabMessage, COLLAB_SEND_BUFFER),
+	}
+	go client.writeLoop()
+
+	room, err := joinCollabRoom(access, client)
+	if err != nil {
+		log.Println(err)
+		client.deliver(&CollabMessage{Type: "error", Error: err.Error()})
+		client.close()
+		return
+	}
+	defer room.leave(client)
+
+	// Apply the client's messages until it disconnects
+	for {
+		msg := new(CollabMessage)
+		if err := conn.ReadJSON(msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Println(err)
+			}
+			break
+		}
+		room.handle(client, msg)
+	}
+
+	log.Println("Leaving CollabHandler")
+}
+
+// joinCollabRoom adds a client to the room for a puzzle, creating the
+// room if it is the first client, and sends it the state message.  The
+// puzzle is loaded without holding collabRoomsMu, so that joining one
+// room does not hold up the others.  If another client creates the room
+// in the meantime, that room is joined instead.
+func joinCollabRoom(access *model.PuzzleAccess, client *collabClient) (*collabRoom, error) {
+	collabRoomsMu.Lock()
+	room, ok := collabRooms[access.ID]
+	if !ok {
+		collabRoomsMu.Unlock()
+		newRoom, err := newCollabRoom(access)
+		if err != nil {
+			return nil, err
+		}
+		collabRoomsMu.Lock()
+		room, ok = collabRooms[access.ID]
+		if !ok {
+			room = newRoom
+			collabRooms[access.ID] = room
+		}
+	}
+	defer collabRoomsMu.Unlock()
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.clients[client.id] = client
+	state := room.newMessage("state")
+	state.Client = client.id
+	state.Username = client.username
+	for _, cursor := range room.cursors {
+		state.Cursors = append(state.Cursors, cursor)
+	}
+	client.deliver(state)
+	return room, nil
+}
+
+// newCollabRoom creates a room for a puzzle, with the puzzle and its
+// history loaded from the database.
+func newCollabRoom(access *model.PuzzleAccess) (*collabRoom, error) {
+	puzzle, err := model.LoadPuzzle(access.Owner, access.Puzzlename)
+	if err != nil {
+		return nil, err
+	}
+	if err := puzzle.LoadHistory(access.Owner); err != nil {
+		return nil, err
+	}
+	room := new(collabRoom)
+	room.id = access.ID
+	room.owner = access.Owner
+	room.puzzle = puzzle
+	room.changed = make(map[string]int)
+	room.clients = make(map[string]*collabClient)
+	room.cursors = make(map[string]CollabCursor)
+	return room, nil
+}
+
+// refreshCollabRoom checks the access of each client in the room for a
+// puzzle again, if there is a room, after the puzzle has been renamed,
+// deleted, or shared differently.  A client that can no longer open the
+// puzzle is sent an error and disconnected.  The others are given their
+// current roles and sent a refresh message with the puzzle under its
+// current name.
+func refreshCollabRoom(id int) {
+	collabRoomsMu.Lock()
+	room, ok := collabRooms[id]
+	collabRoomsMu.Unlock()
+	if !ok {
+		return
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	puzzlename := ""
+	for _, client := range room.clients {
+		access, err := model.GetPuzzleAccess(client.userid, id)
+		if err != nil {
+			room.sendError(client, err)
+			client.close()
+			continue
+		}
+		client.role = access.Role
+		puzzlename = access.Puzzlename
+	}
+	if puzzlename != "" && puzzlename != room.puzzle.GetPuzzleName() {
+		room.puzzle.SetPuzzleName(puzzlename)
+	}
+	room.broadcast(nil, room.newMessage("refresh"))
+}
+
+// collabCellKey returns the key in collabRoom.changed for a cell.
+func collabCellKey(point model.Point) string {
+	x, y := point.ToXY()
+	return fmt.Sprintf("cell %d,%d", y+1, x+1)
+}
+
+// collabClueKey returns the key in collabRoom.changed for a clue.
+func collabClueKey(seq int, dir model.Direction) string {
+	return fmt.Sprintf("clue %d%s", seq, dir)
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// handle applies a message from a client.
+func (room *collabRoom) handle(client *collabClient, msg *CollabMessage) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	switch msg.Type {
+	case "cursor":
+		room.handleCursor(client, msg)
+	case "toggle", "letter", "clue":
+		if !client.role.Allows(model.EDIT_ROLE) {
+			room.sendError(client, fmt.Errorf("puzzle %d is shared with you as %q, which does not allow this",
+				room.id, client.role))
+			return
+		}
+		room.handleEdit(client, msg)
+	case "save":
+		if !client.role.Allows(model.EDIT_ROLE) {
+			room.sendError(client, fmt.Errorf("puzzle %d is shared with you as %q, which does not allow this",
+				room.id, client.role))
+			return
+		}
+		room.handleSave(client)
+	default:
+		room.sendError(client, fmt.Errorf("invalid message type %q", msg.Type))
+	}
+}
+
+// handleCursor records where the client's cursor is and tells the other
+// clients.
+func (room *collabRoom) handleCursor(client *collabClient, msg *CollabMessage) {
+	point := model.NewPoint(msg.Row, msg.Col)
+	if err := room.puzzle.ValidIndex(point); err != nil {
+		room.sendError(client, err)
+		return
+	}
+	dir, err := parseDirection(msg.Dir)
+	if err != nil {
+		room.sendError(client, err)
+		return
+	}
+	cursor := CollabCursor{
+		Client:   client.id,
+		Username: client.username,
+		Row:      msg.Row,
+		Col:      msg.Col,
+		Dir:      dir.String(),
+	}
+	room.cursors[client.id] = cursor
+	room.broadcast(client, &CollabMessage{
+		Type:     "cursor",
+		Version:  room.version,
+		Client:   cursor.Client,
+		Username: cursor.Username,
+		Row:      cursor.Row,
+		Col:      cursor.Col,
+		Dir:      cursor.Dir,
+	})
+}
+
+// handleEdit applies a toggle, letter, or clue message to the puzzle
+// and sends it to all the clients, or rejects it.
+func (room *collabRoom) handleEdit(client *collabClient, msg *CollabMessage) {
+	reject := func(err error) {
+		reply := room.newMessage("reject")
+		reply.Error = err.Error()
+		client.deliver(reply)
+	}
+	puzzle := room.puzzle
+
+	// Find what the edit changes, and check that none of it has changed
+	// since the version the client saw
+	var (
+		point model.Point
+		word  *model.Word
+		dir   model.Direction
+		keys  []string
+	)
+	switch msg.Type {
+	case "toggle", "letter":
+		point = model.NewPoint(msg.Row, msg.Col)
+		if err := puzzle.ValidIndex(point); err != nil {
+			reject(err)
+			return
+		}
+		keys = []string{collabCellKey(point)}
+		if msg.Type == "toggle" {
+			for _, p := range puzzle.SymmetricPoints(point) {
+				keys = append(keys, collabCellKey(p))
+			}
+		}
+	case "clue":
+		var err error
+		if dir, err = parseDirection(msg.Dir); err != nil {
+			reject(err)
+			return
+		}
+		if word = puzzle.LookupWordByNumber(msg.Seq, dir); word == nil {
+			reject(fmt.Errorf("no word %d %s", msg.Seq, dir))
+			return
+		}
+		keys = []string{"grid", collabClueKey(msg.Seq, dir)}
+	}
+	for _, key := range keys {
+		if room.changed[key] > msg.Version {
+			reject(fmt.Errorf("%s has changed since version %d", key, msg.Version))
+			return
+		}
+	}
+
+	// Apply it
+	switch msg.Type {
+	case "toggle":
+		puzzle.Toggle(point)
+		puzzle.RenumberCells()
+		keys = append(keys, "grid")
+	case "letter":
+		if puzzle.IsBlackCell(point) {
+			reject(fmt.Errorf("(%d,%d) is a black cell", msg.Row, msg.Col))
+			return
+		}
+		letter := strings.ToUpper(strings.TrimSpace(msg.Letter))
+		puzzle.SetLetter(point, letter)
+		msg.Letter = letter
+	case "clue":
+		if err := puzzle.SetClue(word, msg.Clue); err != nil {
+			reject(err)
+			return
+		}
+	}
+	room.version++
+	for _, key := range keys {
+		room.changed[key] = room.version
+	}
+
+	op := room.newMessage("op")
+	op.Client = client.id
+	op.Username = client.username
+	op.Row, op.Col = msg.Row, msg.Col
+	op.Seq, op.Dir = msg.Seq, msg.Dir
+	op.Letter, op.Clue = msg.Letter, msg.Clue
+	room.broadcast(nil, op)
+}
+
+// handleSave saves the puzzle and its history over the puzzle with the
+// room's ID in the database, and tells all the clients.
+func (room *collabRoom) handleSave(client *collabClient) {
+	if err := room.puzzle.SavePuzzleByID(room.id, false); err != nil {
+		room.sendError(client, err)
+		return
+	}
+	room.lastSaved = room.version
+	room.broadcast(nil, &CollabMessage{Type: "saved", Version: room.version, Client: client.id, Username: client.username})
+}
+
+// leave removes a client from the room, tells the others, and discards
+// the room if it was the last client.
+func (room *collabRoom) leave(client *collabClient) {
+	collabRoomsMu.Lock()
+	defer collabRoomsMu.Unlock()
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	delete(room.clients, client.id)
+	delete(room.cursors, client.id)
+	client.close()
+	room.broadcast(nil, &CollabMessage{Type: "leave", Version: room.version, Client: client.id, Username: client.username})
+	if len(room.clients) == 0 {
+		if room.lastSaved != room.version {
+			log.Printf("Discarding unsaved changes to puzzle %d\n", room.id)
+		}
+		delete(collabRooms, room.id)
+	}
+}
+
+// broadcast sends a message to every client except the specified one,
+// which may be nil.  The room must be locked.
+func (room *collabRoom) broadcast(except *collabClient, msg *CollabMessage) {
+	for _, client := range room.clients {
+		if client != except {
+			client.deliver(msg)
+		}
+	}
+}
+
+// sendError sends an error message to a client.  The room must be
+// locked.
+func (room *collabRoom) sendError(client *collabClient, err error) {
+	log.Println(err)
+	client.deliver(&CollabMessage{Type: "error", Version: room.version, Error: err.Error()})
+}
+
+// newMessage creates a message of the specified type with the current
+// version and puzzle.  The room must be locked.
+func (room *collabRoom) newMessage(msgType string) *CollabMessage {
+	return &CollabMessage{
+		Type:    msgType,
+		Version: room.version,
+		Puzzle:  NewPuzzleDetail(room.id, room.puzzle),
+	}
+}
+
+// deliver queues a message to be sent to the client.  If the client is
+// not keeping up, it is disconnected rather than holding up the room.
+func (client *collabClient) deliver(msg *CollabMessage) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.closed {
+		return
+	}
+	select {
+	case client.send <- msg:
+	default:
+		log.Printf("Dropping collaboration client %s, which is not keeping up\n", client.id)
+		client.closed = true
+		close(client.send)
+	}
+}
+
+// close stops sending to the client, which closes its connection once
+// the messages already queued have been written.
+func (client *collabClient) close() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if !client.closed {
+		client.closed = true
+		close(client.send)
+	}
+}
+
+// writeLoop sends the queued messages to the client until it is
+// closed, and pings it every COLLAB_PING_INTERVAL in between.
+func (client *collabClient) writeLoop() {
+	defer client.conn.Close()
+	ticker := time.NewTicker(COLLAB_PING_INTERVAL)
+	defer ticker.Stop()
+	fail := func(err error) {
+		log.Println(err)
+		client.close()
+		for range client.send {
+			// Drain until closed
+		}
+	}
+	for {
+		select {
+		case msg, ok := <-client.send:
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			client.conn.SetWriteDeadline(time.Now().Add(COLLAB_WRITE_TIMEOUT))
+			if err := client.conn.WriteJSON(msg); err != nil {
+				fail(err)
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(COLLAB_WRITE_TIMEOUT))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				fail(err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// dialCollab connects to the collaboration WebSocket of a puzzle as the
+// user of the specified session
+func dialCollab(t *testing.T, server *httptest.Server, session *Session, id int) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/collab/" + strconv.Itoa(id)
+	header := http.Header{}
+	header.Set("Cookie", SESSION_COOKIE+"="+session.ID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	assert.Nil(t, err)
+	return conn
+}
+
+// readCollab reads the next message from a collaboration WebSocket
+func readCollab(t *testing.T, conn *websocket.Conn) *CollabMessage {
+	msg := new(CollabMessage)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	assert.Nil(t, conn.ReadJSON(msg))
+	return msg
+}
+
+func TestCollabHandler(t *testing.T) {
+	const puzzlename = "rest-collab"
+	owner, id := newTestSession(t, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, puzzlename)
+	editor, removeEditor := newOtherSession(t, "rest-collab-editor")
+	defer removeEditor()
+	viewer, removeViewer := newOtherSession(t, "rest-collab-viewer")
+	defer removeViewer()
+	assert.Nil(t, model.SharePuzzle(TEST_USERID, puzzlename, editor.USERNAME, model.EDIT_ROLE))
+	assert.Nil(t, model.SharePuzzle(TEST_USERID, puzzlename, viewer.USERNAME, model.VIEW_ROLE))
+
+	server := httptest.NewServer(http.HandlerFunc(CollabHandler))
+	defer server.Close()
+
+	// Both join and get the puzzle at version 0
+	a := dialCollab(t, server, owner, id)
+	defer a.Close()
+	state := readCollab(t, a)
+	assert.Equal(t, "state", state.Type)
+	assert.Equal(t, 0, state.Version)
+	assert.Equal(t, []string{"   ", "   ", "   "}, state.Puzzle.Grid)
+	aID := state.Client
+
+	b := dialCollab(t, server, editor, id)
+	defer b.Close()
+	state = readCollab(t, b)
+	assert.Equal(t, "state", state.Type)
+	assert.Equal(t, 0, state.Version)
+
+	// Cursors are sent to the others
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "cursor", Row: 1, Col: 2, Dir: "across"}))
+	msg := readCollab(t, b)
+	assert.Equal(t, "cursor", msg.Type)
+	assert.Equal(t, aID, msg.Client)
+	assert.Equal(t, "saspeh", msg.Username)
+	assert.Equal(t, 2, msg.Col)
+	assert.Equal(t, "across", msg.Dir)
+
+	// Edits are sent to everyone, in order
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "toggle", Version: 0, Row: 1, Col: 1}))
+	for _, conn := range []*websocket.Conn{a, b} {
+		msg = readCollab(t, conn)
+		assert.Equal(t, "op", msg.Type)
+		assert.Equal(t, 1, msg.Version)
+		assert.Equal(t, aID, msg.Client)
+		assert.Equal(t, []string{".  ", "   ", "  ."}, msg.Puzzle.Grid)
+	}
+
+	// An edit of a cell that has changed since the client saw it is
+	// rejected, but one of a cell that has not is applied
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "letter", Version: 0, Row: 3, Col: 3, Letter: "x"}))
+	msg = readCollab(t, b)
+	assert.Equal(t, "reject", msg.Type)
+	assert.Equal(t, 1, msg.Version)
+	assert.Equal(t, "cell 3,3 has changed since version 0", msg.Error)
+
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "letter", Version: 0, Row: 2, Col: 2, Letter: "x"}))
+	for _, conn := range []*websocket.Conn{a, b} {
+		msg = readCollab(t, conn)
+		assert.Equal(t, "op", msg.Type)
+		assert.Equal(t, 2, msg.Version)
+		assert.Equal(t, "X", msg.Letter)
+		assert.Equal(t, []string{".  ", " X ", "  ."}, msg.Puzzle.Grid)
+	}
+
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "letter", Version: 2, Row: 1, Col: 1, Letter: "y"}))
+	msg = readCollab(t, b)
+	assert.Equal(t, "reject", msg.Type)
+	assert.Equal(t, "(1,1) is a black cell", msg.Error)
+
+	// A clue given before the grid changed is rejected
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "clue", Version: 0, Seq: 1, Dir: "down", Clue: "Stale"}))
+	msg = readCollab(t, a)
+	assert.Equal(t, "reject", msg.Type)
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "clue", Version: 2, Seq: 1, Dir: "down", Clue: "Fresh"}))
+	for _, conn := range []*websocket.Conn{a, b} {
+		msg = readCollab(t, conn)
+		assert.Equal(t, "op", msg.Type)
+		assert.Equal(t, 3, msg.Version)
+		assert.Equal(t, "Fresh", msg.Puzzle.Down[0].Clue)
+	}
+
+	// Save it, then leave
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "save"}))
+	for _, conn := range []*websocket.Conn{a, b} {
+		msg = readCollab(t, conn)
+		assert.Equal(t, "saved", msg.Type)
+		assert.Equal(t, 3, msg.Version)
+	}
+	puzzle, err := model.LoadPuzzle(TEST_USERID, puzzlename)
+	assert.Nil(t, err)
+	assert.Equal(t, "X", puzzle.GetLetter(model.NewPoint(2, 2)))
+	assert.True(t, puzzle.IsBlackCell(model.NewPoint(3, 3)))
+
+	a.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	msg = readCollab(t, b)
+	assert.Equal(t, "leave", msg.Type)
+	assert.Equal(t, aID, msg.Client)
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "cursor", Row: 2, Col: 2, Dir: "d"}))
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "cursor", Row: 2, Col: 2, Dir: "sideways"}))
+	msg = readCollab(t, b)
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, `invalid direction "sideways"`, msg.Error)
+
+	// A viewer can watch, but not edit
+	c := dialCollab(t, server, viewer, id)
+	defer c.Close()
+	state = readCollab(t, c)
+	assert.Equal(t, "state", state.Type)
+	assert.Equal(t, 3, state.Version)
+	assert.Equal(t, []CollabCursor{{Client: state.Cursors[0].Client, Username: editor.USERNAME, Row: 2, Col: 2, Dir: "down"}},
+		state.Cursors)
+	assert.Nil(t, c.WriteJSON(CollabMessage{Type: "toggle", Version: 3, Row: 2, Col: 2}))
+	msg = readCollab(t, c)
+	assert.Equal(t, "error", msg.Type)
+	assert.Nil(t, c.WriteJSON(CollabMessage{Type: "bogus"}))
+	msg = readCollab(t, c)
+	assert.Equal(t, `invalid message type "bogus"`, msg.Error)
+}
+
+func TestCollabHandler_Errors(t *testing.T) {
+	const puzzlename = "rest-collab-errors"
+	session, id := newTestSession(t, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, puzzlename)
+	other, removeOther := newOtherSession(t, "rest-collab-stranger")
+	defer removeOther()
+
+	tests := []struct {
+		name    string
+		session *Session
+		method  string
+		path    string
+		want    int
+	}{
+		{"no session", nil, "GET", "/collab/" + strconv.Itoa(id), http.StatusUnauthorized},
+		{"not shared", other, "GET", "/collab/" + strconv.Itoa(id), http.StatusNotFound},
+		{"bad id", session, "GET", "/collab/bogus", http.StatusNotFound},
+		{"bad method", session, "POST", "/collab/" + strconv.Itoa(id), http.StatusMethodNotAllowed},
+		{"not a websocket", session, "GET", "/collab/" + strconv.Itoa(id), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			if tt.session != nil {
+				req.AddCookie(tt.session.NewSessionCookie())
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(CollabHandler).ServeHTTP(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestCollabHandler_Refresh(t *testing.T) {
+	const puzzlename = "rest-collab-refresh"
+	owner, id := newTestSession(t, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-collab-renamed")
+	editor, removeEditor := newOtherSession(t, "rest-collab-refresh-editor")
+	defer removeEditor()
+	assert.Nil(t, model.SharePuzzle(TEST_USERID, puzzlename, editor.USERNAME, model.EDIT_ROLE))
+	url := "/puzzles/" + strconv.Itoa(id)
+
+	server := httptest.NewServer(http.HandlerFunc(CollabHandler))
+	defer server.Close()
+
+	a := dialCollab(t, server, owner, id)
+	defer a.Close()
+	readCollab(t, a)
+	b := dialCollab(t, server, editor, id)
+	defer b.Close()
+	readCollab(t, b)
+
+	// A rename is sent to everyone
+	rr := doPuzzleRequest(owner, "PATCH", url, `{"puzzlename": "rest-collab-renamed"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	for _, conn := range []*websocket.Conn{a, b} {
+		msg := readCollab(t, conn)
+		assert.Equal(t, "refresh", msg.Type)
+		assert.Equal(t, "rest-collab-renamed", msg.Puzzle.Puzzlename)
+	}
+
+	// An editor made a viewer can no longer edit
+	rr = doPuzzleRequest(owner, "PUT", url+"/shares/"+editor.USERNAME, `{"role": "view"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	readCollab(t, a)
+	readCollab(t, b)
+	assert.Nil(t, b.WriteJSON(CollabMessage{Type: "toggle", Version: 0, Row: 1, Col: 1}))
+	msg := readCollab(t, b)
+	assert.Equal(t, "error", msg.Type)
+
+	// A user it is no longer shared with is disconnected
+	rr = doPuzzleRequest(owner, "DELETE", url+"/shares/"+editor.USERNAME, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	msg = readCollab(t, b)
+	assert.Equal(t, "error", msg.Type)
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := b.ReadMessage()
+	assert.NotNil(t, err)
+	assert.Equal(t, "refresh", readCollab(t, a).Type)
+	assert.Equal(t, "leave", readCollab(t, a).Type)
+
+	// The room cannot be saved over a newer revision
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "toggle", Version: 0, Row: 1, Col: 1}))
+	assert.Equal(t, "op", readCollab(t, a).Type)
+	rr = doPuzzleRequest(owner, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, a.WriteJSON(CollabMessage{Type: "save"}))
+	msg = readCollab(t, a)
+	assert.Equal(t, "error", msg.Type)
+	assert.Contains(t, msg.Error, "has been saved as revision")
+
+	// Deleting the puzzle disconnects everyone, and it is not saved again
+	rr = doPuzzleRequest(owner, "DELETE", url, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	msg = readCollab(t, a)
+	assert.Equal(t, "error", msg.Type)
+	a.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = a.ReadMessage()
+	assert.NotNil(t, err)
+	_, err = model.LookupPuzzleName(TEST_USERID, id)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestCollabHandler_ReadLimit(t *testing.T) {
+	const puzzlename = "rest-collab-limit"
+	session, id := newTestSession(t, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, puzzlename)
+
+	server := httptest.NewServer(http.HandlerFunc(CollabHandler))
+	defer server.Close()
+
+	conn := dialCollab(t, server, session, id)
+	defer conn.Close()
+	readCollab(t, conn)
+
+	// A message that is too large closes the connection
+	clue := strings.Repeat("x", COLLAB_READ_LIMIT)
+	assert.Nil(t, conn.WriteJSON(CollabMessage{Type: "clue", Seq: 1, Dir: "across", Clue: clue}))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestCollabHandler_ConcurrentJoin(t *testing.T) {
+	const puzzlename = "rest-collab-join"
+	session, id := newTestSession(t, puzzlename)
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, puzzlename)
+
+	server := httptest.NewServer(http.HandlerFunc(CollabHandler))
+	defer server.Close()
+
+	// Clients that join at the same time all end up in the same room
+	const n = 5
+	conns := make(chan *websocket.Conn, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			conn := dialCollab(t, server, session, id)
+			readCollab(t, conn)
+			conns <- conn
+		}()
+	}
+	all := make([]*websocket.Conn, 0, n)
+	for i := 0; i < n; i++ {
+		conn := <-conns
+		defer conn.Close()
+		all = append(all, conn)
+	}
+	assert.Nil(t, all[0].WriteJSON(CollabMessage{Type: "toggle", Version: 0, Row: 1, Col: 1}))
+	for _, conn := range all {
+		msg := readCollab(t, conn)
+		assert.Equal(t, "op", msg.Type)
+		assert.Equal(t, 1, msg.Version)
+	}
+}
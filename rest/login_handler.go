@@ -1,13 +1,12 @@
 package rest
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/philhanna/cwcomp/model"
-	"github.com/philhanna/cwcomp/util"
 )
 
 // LoginHandler accepts a username and password from an HTTP POST payload
@@ -46,11 +45,15 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create a new session and store it in the session map
+	// Create a new session and store it in the session store
 	session := NewSession()
 	session.USERID = userid
 	session.USERNAME = username
-	Sessions[session.ID] = session
+	if err := Sessions.Save(session); err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// Send the session cookie back to client
 	cookie := session.NewSessionCookie()
@@ -62,42 +65,73 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Leaving LoginHandler")
 }
 
-// ValidateCredentials validates the login request according to the database
-// and returns the userID (an integer) and any error
-func ValidateCredentials(w http.ResponseWriter, username, password string) (int, error) {
-	var (
-		err            error
-		hashedPassword = util.Hash256(password)
-		userid         int
-		dbPassword     []byte
-	)
-
-	// Get the userid and password from the database for this username
-	con, _ := model.Connect()
-	defer con.Close()
-
-	rows, _ := con.Query(`SELECT userid, password FROM users WHERE username=?`, username)
-	defer rows.Close()
-
-	// Verify whether the username was found
-	userFound := rows.Next()
-	if !userFound {
-		errmsg := fmt.Sprintf("username %q not found in users table", username)
-		http.Error(w, errmsg, http.StatusUnauthorized)
-		err = fmt.Errorf(errmsg)
-		return 0, err
+// LogoutHandler ends the session named in the session cookie, if there
+// is one, and tells the client to discard the cookie.  It responds with
+// 204 No Content whether or not the user was logged in.
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Entering LogoutHandler")
+
+	// Set the required CORS header(s)
+	requester := r.Header.Get("Origin")
+	w.Header().Set("Access-Control-Allow-Origin", requester)
+	w.Header().Set("Access-Control-Allow-Headers", "Credentials")
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+	switch r.Method {
+	case http.MethodOptions:
+		log.Println("Handled preflight OPTIONS request")
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "POST")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Delete the session from the store
+	if cookie, err := r.Cookie(SESSION_COOKIE); err == nil {
+		if err := Sessions.Delete(cookie.Value); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
-	// Verify whether the hashed passwords match
-	rows.Scan(&userid, &dbPassword)
-	passwordsMatch := bytes.Equal(hashedPassword, dbPassword)
-	if !passwordsMatch {
-		errmsg := "passwords do not match"
-		http.Error(w, errmsg, http.StatusUnauthorized)
-		err = fmt.Errorf(errmsg)
-		return 0, err
+	// Replace the cookie with one that has already expired
+	http.SetCookie(w, &http.Cookie{
+		Name:     SESSION_COOKIE,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	})
+	w.WriteHeader(http.StatusNoContent)
+
+	log.Println("Leaving LogoutHandler")
+}
+
+// ValidateCredentials validates the login request according to the database
+// and returns the userID (an integer) and any error.  A password stored
+// in the old, unsalted form is upgraded when it is validated.
+func ValidateCredentials(w http.ResponseWriter, username, password string) (int, error) {
+	user, err := model.Authenticate(username, password)
+	if err != nil {
+		var errmsg string
+		status := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			errmsg = fmt.Sprintf("username %q not found in users table", username)
+		case errors.Is(err, model.ErrBadCredentials):
+			errmsg = "passwords do not match"
+		default:
+			errmsg = err.Error()
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, errmsg, status)
+		return 0, errors.New(errmsg)
 	}
 
 	// Everything OK
-	return userid, nil
+	return user.ID, nil
 }
@@ -81,3 +81,41 @@ func TestLoginHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestLogoutHandler(t *testing.T) {
+	session := NewSession()
+	session.USERID = TEST_USERID
+	session.USERNAME = "saspeh"
+	assert.Nil(t, Sessions.Save(session))
+
+	doLogout := func(method string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/logout", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(LogoutHandler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := doLogout("POST", session.NewSessionCookie())
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	cookies := rr.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, SESSION_COOKIE, cookies[0].Name)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+
+	// The session can no longer be used
+	_, err := Sessions.Get(session.ID)
+	assert.NotNil(t, err)
+	rr = doPuzzleRequest(session, "GET", "/puzzles/1", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Logging out again, or without a session, is harmless
+	rr = doLogout("POST", session.NewSessionCookie())
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doLogout("POST", nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doLogout("GET", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/philhanna/cwcomp"
+	"github.com/philhanna/cwcomp/model"
+)
+
+// TestMain runs the tests against a new database in a temporary
+// directory, rather than the one named in the configuration.
+func TestMain(m *testing.M) {
+	tmp, err := os.MkdirTemp("", "cwcomp_rest_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := *cwcomp.GetConfiguration()
+	config.DATABASE.NAME = filepath.Join(tmp, "cwcomp_test.db")
+	cwcomp.GetConfiguration = func() *cwcomp.Configuration {
+		return &config
+	}
+	if _, err := model.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(tmp)
+	os.Exit(code)
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// PuzzleDetail is the JSON representation of a single puzzle, returned
+// by the /puzzles/{id} resource.
+//
+// Each row of the grid is a string with '.' for a black cell and ' '
+// for an empty cell, as in the AcrossLite text format.  A rebus cell is
+// its letters enclosed in brackets, as in the text of a word (see
+// model.JoinCells).
+type PuzzleDetail struct {
+	ID         int          `json:"id"`
+	Puzzlename string       `json:"puzzlename"`
+	Rows       int          `json:"nrows"`
+	Cols       int          `json:"ncols"`
+	Symmetry   string       `json:"symmetry"`
+	Grid       []string     `json:"grid"`
+	Across     []WordDetail `json:"across"`
+	Down       []WordDetail `json:"down"`
+}
+
+// WordDetail is the JSON representation of one word in the puzzle.
+type WordDetail struct {
+	Seq    int    `json:"seq"`
+	Row    int    `json:"r"`
+	Col    int    `json:"c"`
+	Length int    `json:"length"`
+	Text   string `json:"text"`
+	Clue   string `json:"clue"`
+}
+
+// ---------------------------------------------------------------------
+// Constructor
+// ---------------------------------------------------------------------
+
+// NewPuzzleDetail creates the JSON representation of a puzzle.
+func NewPuzzleDetail(id int, puzzle *model.Puzzle) *PuzzleDetail {
+	pd := new(PuzzleDetail)
+	pd.ID = id
+	pd.Puzzlename = puzzle.GetPuzzleName()
+	pd.Rows = puzzle.GetRows()
+	pd.Cols = puzzle.GetCols()
+	pd.Symmetry = puzzle.GetSymmetry().String()
+
+	pd.Grid = make([]string, puzzle.GetRows())
+	for i, row := range model.PuzzleToTextMatrix(puzzle) {
+		for j, text := range row {
+			if text == "" {
+				row[j] = "."
+			}
+		}
+		pd.Grid[i] = model.JoinCells(row)
+	}
+
+	pd.Across = make([]WordDetail, 0)
+	pd.Down = make([]WordDetail, 0)
+	for _, nc := range model.GetNumberedCells(model.PuzzleToSimpleMatrix(puzzle)) {
+		if nc.StartA {
+			pd.Across = append(pd.Across, newWordDetail(puzzle, nc, model.ACROSS))
+		}
+		if nc.StartD {
+			pd.Down = append(pd.Down, newWordDetail(puzzle, nc, model.DOWN))
+		}
+	}
+	return pd
+}
+
+// newWordDetail creates the JSON representation of a word
+func newWordDetail(puzzle *model.Puzzle, nc model.NumberedCell, dir model.Direction) WordDetail {
+	wd := WordDetail{Seq: nc.Seq, Row: nc.Row, Col: nc.Col}
+	word := puzzle.LookupWordByNumber(nc.Seq, dir)
+	if word != nil {
+		wd.Length, _ = puzzle.GetLength(word)
+		wd.Text = puzzle.GetText(word)
+		wd.Clue, _ = puzzle.GetClue(word)
+	}
+	return wd
+}
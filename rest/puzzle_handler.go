@@ -1,16 +1,575 @@
 package rest
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/philhanna/cwcomp/svg"
 )
 
 // ---------------------------------------------------------------------
 // Type definitions
 // ---------------------------------------------------------------------
 
-// PuzzleHandler serves REST requests for a single puzzle
+// puzzleRequest is the context of a request for a single puzzle
+type puzzleRequest struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	session *Session
+	id      int
+	access  *model.PuzzleAccess // What the session's user can do with the puzzle
+	path    []string            // The parts of the path after /puzzles/{id}
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// PuzzleHandler serves REST requests for a single puzzle belonging to,
+// or shared with, the session's user:
+//
+//   - GET    /puzzles/{id}: Returns the grid and clues as JSON
+//   - PUT    /puzzles/{id}: Saves the puzzle (with ?overwrite=true, even if it has been saved since it was loaded)
+//   - DELETE /puzzles/{id}: Deletes the puzzle
+//   - PATCH  /puzzles/{id}: Renames the puzzle, given {"puzzlename": name}
+//   - POST   /puzzles/{id}/toggle: Toggles a black cell, given {"r": r, "c": c}
+//   - PUT    /puzzles/{id}/words/{seq}/{dir}/text: Sets the text of a word, given {"text": text}
+//   - PUT    /puzzles/{id}/words/{seq}/{dir}/clue: Sets the clue of a word, given {"clue": clue}
+//   - POST   /puzzles/{id}/undo: Undoes the last change
+//   - POST   /puzzles/{id}/redo: Redoes the last change that was undone
+//   - GET    /puzzles/{id}/validate: Returns the problems found in the puzzle as a JSON list
+//   - GET    /puzzles/{id}/stats: Returns the grid statistics as JSON
+//   - GET    /puzzles/{id}/heatmap: Returns the heat map as JSON (or as an SVG image, with ?format=svg)
+//   - GET    /puzzles/{id}/revisions[/...]: Lists, diffs, restores, and forks saved revisions (see handleRevisions)
+//   - GET    /puzzles/{id}/shares[/...]: Lists and changes the users the puzzle is shared with (see handleShares)
+//
+// Changes are made to a working copy of the puzzle kept in the session,
+// and are only written to the database by PUT, which also saves the
+// undo/redo history so that a later session can continue with it.  If
+// the puzzle has been saved, or a revision restored, by someone else
+// since the working copy was loaded, PUT returns a 409 error rather
+// than lose those changes.
+// Except for DELETE, validate, stats, heatmap, revisions, and shares,
+// each request returns the puzzle as JSON.
+//
+// A user that the puzzle is shared with as a viewer can only use the
+// GET requests, and one that it is shared with as an editor can also
+// change and save it.  Only the owner can rename, delete, fork, or
+// share it.  Any other user gets a 404 error, as if the puzzle did not
+// exist.
 func PuzzleHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/puzzles/"):]
-	fmt.Fprintf(w, "This is the handler for the puzzle with id %v", id)
+
+	log.Println("Entering PuzzleHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Only one request at a time can use the session's working copies
+	session.Lock()
+	defer session.Unlock()
+
+	// Get the puzzle ID and the sub-resource, if any
+	path := strings.Split(strings.Trim(r.URL.Path[len("/puzzles/"):], "/"), "/")
+	id, err := strconv.Atoi(path[0])
+	if err != nil {
+		errmsg := fmt.Sprintf("invalid puzzle id %q", path[0])
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusNotFound)
+		return
+	}
+	pr := &puzzleRequest{w: w, r: r, session: session, id: id, path: path[1:]}
+	pr.access, err = model.GetPuzzleAccess(session.USERID, id)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+
+	switch {
+	case len(pr.path) == 0:
+		switch r.Method {
+		case http.MethodGet:
+			pr.handleGet()
+		case http.MethodPut:
+			pr.handleSave()
+		case http.MethodDelete:
+			pr.handleDelete()
+		case http.MethodPatch:
+			pr.handleRename()
+		default:
+			pr.methodNotAllowed("GET, PUT, DELETE, PATCH")
+		}
+	case len(pr.path) == 1 && pr.path[0] == "toggle":
+		if r.Method != http.MethodPost {
+			pr.methodNotAllowed("POST")
+			return
+		}
+		pr.handleToggle()
+	case len(pr.path) == 1 && (pr.path[0] == "undo" || pr.path[0] == "redo"):
+		if r.Method != http.MethodPost {
+			pr.methodNotAllowed("POST")
+			return
+		}
+		pr.handleUndoRedo(pr.path[0] == "undo")
+	case len(pr.path) == 1 && pr.path[0] == "validate":
+		if r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		pr.handleValidate()
+	case len(pr.path) == 1 && pr.path[0] == "stats":
+		if r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		pr.handleStats()
+	case len(pr.path) == 1 && pr.path[0] == "heatmap":
+		if r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		pr.handleHeatMap()
+	case len(pr.path) >= 1 && pr.path[0] == "revisions":
+		pr.handleRevisions()
+	case len(pr.path) >= 1 && pr.path[0] == "shares":
+		pr.handleShares()
+	case len(pr.path) == 4 && pr.path[0] == "words":
+		if r.Method != http.MethodPut {
+			pr.methodNotAllowed("PUT")
+			return
+		}
+		pr.handleWord()
+	default:
+		errmsg := fmt.Sprintf("no such resource %q", r.URL.Path)
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusNotFound)
+	}
+
+	log.Println("Leaving PuzzleHandler")
+}
+
+// parseDirection parses a direction given as "a", "across", "d", or
+// "down".
+func parseDirection(s string) (model.Direction, error) {
+	switch strings.ToLower(s) {
+	case "a", "across":
+		return model.ACROSS, nil
+	case "d", "down":
+		return model.DOWN, nil
+	default:
+		return "", fmt.Errorf("invalid direction %q", s)
+	}
+}
+
+// puzzleToSVG creates an SVG image of the puzzle, with its rebus cells
+// and cell styles.
+func puzzleToSVG(puzzle *model.Puzzle) *svg.SVG {
+	image := svg.NewSVG(model.PuzzleToSimpleMatrix(puzzle))
+	for i, row := range model.PuzzleToTextMatrix(puzzle) {
+		for j, text := range row {
+			image.SetRebus(i+1, j+1, text)
+			style := puzzle.GetCellStyle(model.NewPoint(i+1, j+1))
+			image.SetCircled(i+1, j+1, style.Circled)
+			image.SetShade(i+1, j+1, style.Shade)
+			image.SetBars(i+1, j+1, string(style.Bars))
+		}
+	}
+	return image
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// getPuzzle returns the session's working copy of the puzzle, loading
+// it from the database if necessary, along with its undo/redo history
+// and the user's word list.  The word list is read again if it has
+// changed since the working copy got it.  If the puzzle cannot be found,
+// a 404 error is written to the response and nil is returned.
+func (pr *puzzleRequest) getPuzzle() *model.Puzzle {
+	if puzzle, ok := pr.session.PUZZLES[pr.id]; ok {
+		if !puzzle.GetUserWords().IsCurrent() && !pr.loadUserWords(puzzle) {
+			return nil
+		}
+		return puzzle
+	}
+	owner := pr.access.Owner
+	puzzle, err := model.LoadPuzzle(owner, pr.access.Puzzlename)
+	if err != nil {
+		pr.dbError(err)
+		return nil
+	}
+	if err := puzzle.LoadHistory(owner); err != nil {
+		pr.dbError(err)
+		return nil
+	}
+	if !pr.loadUserWords(puzzle) {
+		return nil
+	}
+	if pr.session.PUZZLES == nil {
+		pr.session.PUZZLES = make(map[int]*model.Puzzle)
+	}
+	pr.session.PUZZLES[pr.id] = puzzle
+	return puzzle
+}
+
+// loadUserWords reads the user's word list into the puzzle.  If it
+// cannot be read, a 500 error is written to the response and false is
+// returned.
+func (pr *puzzleRequest) loadUserWords(puzzle *model.Puzzle) bool {
+	userWords, err := model.LoadUserWords(pr.session.USERID)
+	if err != nil {
+		pr.error(err, http.StatusInternalServerError)
+		return false
+	}
+	puzzle.SetUserWords(userWords)
+	return true
+}
+
+// handleDelete deletes the puzzle from the database and the session.
+func (pr *puzzleRequest) handleDelete() {
+	if !pr.require(model.OWNER_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	if err := puzzle.DeletePuzzle(pr.access.Owner, pr.access.Puzzlename); err != nil {
+		pr.dbError(err)
+		return
+	}
+	delete(pr.session.PUZZLES, pr.id)
+	refreshCollabRoom(pr.id)
+	pr.w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGet returns the puzzle as JSON
+func (pr *puzzleRequest) handleGet() {
+	if !pr.require(model.VIEW_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	pr.writePuzzle(puzzle)
+}
+
+// handleRename renames the puzzle.  The new name must not already be
+// in use by this user.
+func (pr *puzzleRequest) handleRename() {
+	if !pr.require(model.OWNER_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	var body struct {
+		Puzzlename string `json:"puzzlename"`
+	}
+	if !pr.readBody(&body) {
+		return
+	}
+	newName := strings.TrimSpace(body.Puzzlename)
+	oldName := pr.access.Puzzlename
+	userid := pr.access.Owner
+	switch {
+	case newName == "":
+		pr.error(fmt.Errorf("puzzle name must not be empty"), http.StatusBadRequest)
+		return
+	case newName == oldName:
+	default:
+		if err := puzzle.RenamePuzzle(userid, oldName, newName); err != nil {
+			pr.dbError(err)
+			return
+		}
+		refreshCollabRoom(pr.id)
+	}
+	if puzzle.GetPuzzleName() != newName {
+		puzzle.SetPuzzleName(newName)
+	}
+	pr.writePuzzle(puzzle)
+}
+
+// handleSave writes the working copy of the puzzle and its history over
+// the puzzle with its ID in the database, so that a copy loaded before
+// the puzzle was renamed does not add another one under the old name.
+func (pr *puzzleRequest) handleSave() {
+	if !pr.require(model.EDIT_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	overwrite := pr.r.URL.Query().Get("overwrite") == "true"
+	if err := puzzle.SavePuzzleByID(pr.id, overwrite); err != nil {
+		pr.dbError(err)
+		return
+	}
+	pr.writePuzzle(puzzle)
+}
+
+// handleStats returns the grid statistics, as described in
+// model.GetStatistics.
+func (pr *puzzleRequest) handleStats() {
+	if !pr.require(model.VIEW_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	pr.writeJSON(puzzle.GetStatistics())
+}
+
+// handleHeatMap returns the heat map, as described in model.GetHeatMap,
+// either as JSON or as an SVG image of the grid with the cells colored
+// by the number of ways they can be filled.
+func (pr *puzzleRequest) handleHeatMap() {
+	if !pr.require(model.VIEW_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	heatMap := puzzle.GetHeatMap()
+	switch format := pr.r.URL.Query().Get("format"); format {
+	case "", "json":
+		pr.writeJSON(heatMap)
+	case "svg":
+		image := puzzleToSVG(puzzle)
+		for _, cell := range heatMap.Cells {
+			x, y := cell.Point.ToXY()
+			image.SetHeat(y+1, x+1, cell.Choices)
+		}
+		pr.w.Header().Set("Content-Type", "image/svg+xml")
+		pr.w.Write([]byte(image.GenerateSVG()))
+	default:
+		pr.error(fmt.Errorf("invalid format %q", format), http.StatusBadRequest)
+	}
+}
+
+// handleToggle toggles the black cell at the point given in the body
+// (and its symmetric twins), then renumbers the puzzle.
+func (pr *puzzleRequest) handleToggle() {
+	if !pr.require(model.EDIT_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	var body struct {
+		Row int `json:"r"`
+		Col int `json:"c"`
+	}
+	if !pr.readBody(&body) {
+		return
+	}
+	point := model.NewPoint(body.Row, body.Col)
+	if err := puzzle.ValidIndex(point); err != nil {
+		pr.error(err, http.StatusBadRequest)
+		return
+	}
+	puzzle.Toggle(point)
+	puzzle.RenumberCells()
+	pr.writePuzzle(puzzle)
+}
+
+// handleUndoRedo undoes or redoes the last change of any kind.  If the
+// change was a rename, the puzzle is also renamed in the database,
+// which only the owner can do.
+func (pr *puzzleRequest) handleUndoRedo(undo bool) {
+	if !pr.require(model.EDIT_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	oldName := puzzle.GetPuzzleName()
+	var cmd *model.Command
+	if undo {
+		cmd = puzzle.Undo()
+	} else {
+		cmd = puzzle.Redo()
+	}
+	if cmd == nil {
+		pr.error(fmt.Errorf("nothing to %s", pr.path[0]), http.StatusConflict)
+		return
+	}
+	newName := puzzle.GetPuzzleName()
+	if newName != oldName {
+		// Put the change back the way it was if the puzzle cannot be
+		// renamed in the database.  A puzzle that has never been saved
+		// is only renamed in the working copy.
+		revert := func() {
+			if undo {
+				puzzle.Redo()
+			} else {
+				puzzle.Undo()
+			}
+		}
+		if !pr.require(model.OWNER_ROLE) {
+			revert()
+			return
+		}
+		err := puzzle.RenamePuzzle(pr.access.Owner, pr.access.Puzzlename, newName)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			revert()
+			pr.dbError(err)
+			return
+		}
+		refreshCollabRoom(pr.id)
+	}
+	pr.writePuzzle(puzzle)
+}
+
+// handleValidate returns the list of problems found in the puzzle, as
+// described in model.Validate.
+func (pr *puzzleRequest) handleValidate() {
+	if !pr.require(model.VIEW_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+	pr.writeJSON(puzzle.Validate())
+}
+
+// handleWord sets the text or the clue of the word specified in the
+// path as /words/{seq}/{dir}/{text|clue}
+func (pr *puzzleRequest) handleWord() {
+	if !pr.require(model.EDIT_ROLE) {
+		return
+	}
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return
+	}
+
+	// Find the word
+	seq, err := strconv.Atoi(pr.path[1])
+	if err != nil {
+		pr.error(fmt.Errorf("invalid word number %q", pr.path[1]), http.StatusNotFound)
+		return
+	}
+	dir, err := parseDirection(pr.path[2])
+	if err != nil {
+		pr.error(err, http.StatusNotFound)
+		return
+	}
+	word := puzzle.LookupWordByNumber(seq, dir)
+	if word == nil {
+		pr.error(fmt.Errorf("no word %d %s", seq, dir), http.StatusNotFound)
+		return
+	}
+
+	// Update the text or the clue
+	var body struct {
+		Text string `json:"text"`
+		Clue string `json:"clue"`
+	}
+	switch pr.path[3] {
+	case "text":
+		if !pr.readBody(&body) {
+			return
+		}
+		if err := puzzle.SetText(word, strings.ToUpper(body.Text)); err != nil {
+			pr.error(err, http.StatusBadRequest)
+			return
+		}
+	case "clue":
+		if !pr.readBody(&body) {
+			return
+		}
+		if err := puzzle.SetClue(word, body.Clue); err != nil {
+			pr.error(err, http.StatusBadRequest)
+			return
+		}
+	default:
+		pr.error(fmt.Errorf("no such resource %q", pr.r.URL.Path), http.StatusNotFound)
+		return
+	}
+	pr.writePuzzle(puzzle)
+}
+
+// error logs an error and writes it to the response with the specified
+// status code.
+func (pr *puzzleRequest) error(err error, status int) {
+	log.Println(err)
+	http.Error(pr.w, err.Error(), status)
+}
+
+// dbError writes an error from the database to the response, with a 404
+// status if something was not found, 409 if a name was already used,
+// and 500 otherwise.
+func (pr *puzzleRequest) dbError(err error) {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		pr.error(err, http.StatusNotFound)
+	case errors.Is(err, model.ErrNameConflict), errors.Is(err, model.ErrChanged):
+		pr.error(err, http.StatusConflict)
+	default:
+		pr.error(err, http.StatusInternalServerError)
+	}
+}
+
+// require checks that the session's user has at least the specified
+// role for the puzzle.  If not, a 403 error is written to the response
+// and false is returned.
+func (pr *puzzleRequest) require(role model.Role) bool {
+	if !pr.access.Role.Allows(role) {
+		pr.error(fmt.Errorf("puzzle %d is shared with you as %q, which does not allow this",
+			pr.id, pr.access.Role), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// methodNotAllowed writes a 405 error with the allowed methods.
+func (pr *puzzleRequest) methodNotAllowed(allowed string) {
+	pr.w.Header().Set("Allow", allowed)
+	pr.error(fmt.Errorf("method %s not allowed", pr.r.Method), http.StatusMethodNotAllowed)
+}
+
+// readBody decodes the JSON request body into the specified value.  If
+// it cannot be decoded, a 400 error is written to the response and
+// false is returned.
+func (pr *puzzleRequest) readBody(v any) bool {
+	if err := json.NewDecoder(pr.r.Body).Decode(v); err != nil {
+		pr.error(fmt.Errorf("invalid request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writePuzzle writes the puzzle as JSON to the response.
+func (pr *puzzleRequest) writePuzzle(puzzle *model.Puzzle) {
+	pr.writeJSON(NewPuzzleDetail(pr.id, puzzle))
+}
+
+// writeJSON writes a value as JSON to the response.
+func (pr *puzzleRequest) writeJSON(v any) {
+	jsonBlob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		pr.error(err, http.StatusInternalServerError)
+		return
+	}
+	pr.w.Header().Set("Content-Type", "application/json")
+	pr.w.Write(jsonBlob)
 }
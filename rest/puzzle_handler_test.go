@@ -1,14 +1,64 @@
 package rest
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/philhanna/cwcomp/model"
 	"github.com/stretchr/testify/assert"
 )
 
+const TEST_USERID = 1
+
+// newTestSession creates a session for the test user and a puzzle saved
+// in the database, returning the session and the puzzle ID.
+func newTestSession(t *testing.T, puzzlename string) (*Session, int) {
+	session := NewSession()
+	session.USERID = TEST_USERID
+	session.USERNAME = "saspeh"
+	assert.Nil(t, Sessions.Save(session))
+
+	puzzle := model.NewPuzzle(3)
+	puzzle.SetPuzzleName(puzzlename)
+	assert.Nil(t, puzzle.SavePuzzle(TEST_USERID))
+
+	con, _ := model.Connect()
+	defer con.Close()
+	rows, err := con.Query(`SELECT id FROM puzzles WHERE userid=? AND puzzlename=?`,
+		TEST_USERID, puzzlename)
+	assert.Nil(t, err)
+	defer rows.Close()
+	var id int
+	assert.True(t, rows.Next())
+	rows.Scan(&id)
+
+	return session, id
+}
+
+// doPuzzleRequest sends a request to the puzzle handler and returns the
+// response
+func doPuzzleRequest(session *Session, method, url, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if session != nil {
+		req.AddCookie(session.NewSessionCookie())
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(PuzzleHandler)
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// decodePuzzle decodes the puzzle detail in a response
+func decodePuzzle(t *testing.T, rr *httptest.ResponseRecorder) *PuzzleDetail {
+	pd := new(PuzzleDetail)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), pd))
+	return pd
+}
+
 func TestPuzzleHandler(t *testing.T) {
 	tests := []struct {
 		name string
@@ -31,6 +81,241 @@ func TestPuzzleHandler(t *testing.T) {
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(PuzzleHandler)
 			handler.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		})
+	}
+}
+
+func TestNewPuzzleDetail_Rebus(t *testing.T) {
+	puzzle := model.NewPuzzle(3)
+	puzzle.RenumberCells()
+	word := puzzle.LookupWordByNumber(1, model.ACROSS)
+	assert.Nil(t, puzzle.SetText(word, "[ABC]DE"))
+	pd := NewPuzzleDetail(0, puzzle)
+	assert.Equal(t, []string{"[ABC]DE", "   ", "   "}, pd.Grid)
+	assert.Equal(t, "[ABC]DE", pd.Across[0].Text)
+}
+
+func TestPuzzleHandler_RefreshesUserWords(t *testing.T) {
+	session, id := newTestSession(t, "rest-userwords")
+	url := "/puzzles/" + strconv.Itoa(id)
+	assert.Equal(t, http.StatusOK, doPuzzleRequest(session, "GET", url, "").Code)
+	before := session.PUZZLES[id].GetUserWords()
+	assert.True(t, before.IsCurrent())
+
+	assert.Nil(t, model.AddUserWord(TEST_USERID, "ZZYZX", 40))
+	defer model.RemoveUserWord(TEST_USERID, "ZZYZX")
+	assert.False(t, before.IsCurrent())
+
+	assert.Equal(t, http.StatusOK, doPuzzleRequest(session, "GET", url, "").Code)
+	after := session.PUZZLES[id].GetUserWords()
+	assert.True(t, after.IsCurrent())
+	score, ok := after.GetScore("ZZYZX")
+	assert.True(t, ok)
+	assert.Equal(t, 40, score)
+}
+
+func TestPuzzleHandler_NotFound(t *testing.T) {
+	session, id := newTestSession(t, "rest-notfound")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-notfound")
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"bad id", "GET", "/puzzles/asdf", http.StatusNotFound},
+		{"no such id", "GET", "/puzzles/999999", http.StatusNotFound},
+		{"no such resource", "GET", "/puzzles/" + strconv.Itoa(id) + "/bogus", http.StatusNotFound},
+		{"bad method", "POST", "/puzzles/" + strconv.Itoa(id), http.StatusMethodNotAllowed},
+		{"no such word", "PUT", "/puzzles/" + strconv.Itoa(id) + "/words/99/across/text", http.StatusNotFound},
+		{"bad direction", "PUT", "/puzzles/" + strconv.Itoa(id) + "/words/1/sideways/text", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doPuzzleRequest(session, tt.method, tt.url, `{}`)
+			assert.Equal(t, tt.want, rr.Code)
 		})
 	}
 }
+
+func TestPuzzleHandler_Edit(t *testing.T) {
+	session, id := newTestSession(t, "rest-edit")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-edit")
+	url := "/puzzles/" + strconv.Itoa(id)
+
+	// Get the puzzle
+	rr := doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pd := decodePuzzle(t, rr)
+	assert.Equal(t, id, pd.ID)
+	assert.Equal(t, "rest-edit", pd.Puzzlename)
+	assert.Equal(t, []string{"   ", "   ", "   "}, pd.Grid)
+	assert.Equal(t, 3, len(pd.Across))
+
+	// Toggle a black cell, which also toggles its symmetric twin
+	rr = doPuzzleRequest(session, "POST", url+"/toggle", `{"r": 1, "c": 1}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pd = decodePuzzle(t, rr)
+	assert.Equal(t, []string{".  ", "   ", "  ."}, pd.Grid)
+
+	// Undo and redo the black cell
+	rr = doPuzzleRequest(session, "POST", url+"/undo", "")
+	assert.Equal(t, []string{"   ", "   ", "   "}, decodePuzzle(t, rr).Grid)
+	rr = doPuzzleRequest(session, "POST", url+"/redo", "")
+	assert.Equal(t, []string{".  ", "   ", "  ."}, decodePuzzle(t, rr).Grid)
+
+	// Set the text and clue of a word
+	rr = doPuzzleRequest(session, "PUT", url+"/words/3/across/text", `{"text": "cat"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(session, "PUT", url+"/words/3/a/clue", `{"clue": "Feline"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pd = decodePuzzle(t, rr)
+	assert.Equal(t, "CAT", pd.Across[1].Text)
+	assert.Equal(t, "Feline", pd.Across[1].Clue)
+
+	// Text that is too long is rejected
+	rr = doPuzzleRequest(session, "PUT", url+"/words/3/across/text", `{"text": "CATS"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Undo the clue, then the text, and redo them both
+	rr = doPuzzleRequest(session, "POST", url+"/undo", "")
+	pd = decodePuzzle(t, rr)
+	assert.Equal(t, "CAT", pd.Across[1].Text)
+	assert.Equal(t, "", pd.Across[1].Clue)
+	rr = doPuzzleRequest(session, "POST", url+"/undo", "")
+	assert.Equal(t, "   ", decodePuzzle(t, rr).Across[1].Text)
+	rr = doPuzzleRequest(session, "POST", url+"/redo", "")
+	rr = doPuzzleRequest(session, "POST", url+"/redo", "")
+	pd = decodePuzzle(t, rr)
+	assert.Equal(t, "CAT", pd.Across[1].Text)
+	assert.Equal(t, "Feline", pd.Across[1].Clue)
+	rr = doPuzzleRequest(session, "POST", url+"/redo", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Save, then reload from the database in a new session
+	rr = doPuzzleRequest(session, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	other := NewSession()
+	other.USERID = TEST_USERID
+	assert.Nil(t, Sessions.Save(other))
+	rr = doPuzzleRequest(other, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	pd = decodePuzzle(t, rr)
+	assert.Equal(t, []string{".  ", "CAT", "  ."}, pd.Grid)
+	assert.Equal(t, "Feline", pd.Across[1].Clue)
+
+	// The history was saved, too
+	rr = doPuzzleRequest(other, "POST", url+"/undo", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "", decodePuzzle(t, rr).Across[1].Clue)
+}
+
+func TestPuzzleHandler_SaveStaleCopy(t *testing.T) {
+	session, id := newTestSession(t, "rest-stale")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-stale-renamed")
+	url := "/puzzles/" + strconv.Itoa(id)
+	stale := NewSession()
+	stale.USERID = TEST_USERID
+	assert.Nil(t, Sessions.Save(stale))
+	assert.Equal(t, http.StatusOK, doPuzzleRequest(stale, "GET", url, "").Code)
+
+	// Saving a copy loaded before a rename keeps the new name
+	rr := doPuzzleRequest(session, "PATCH", url, `{"puzzlename": "rest-stale-renamed"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(stale, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "rest-stale-renamed", decodePuzzle(t, rr).Puzzlename)
+	names, err := model.NewPuzzle(3).GetPuzzleList(TEST_USERID)
+	assert.Nil(t, err)
+	assert.NotContains(t, names, "rest-stale")
+
+	// Saving a copy loaded before a delete does not bring it back
+	rr = doPuzzleRequest(session, "DELETE", url, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doPuzzleRequest(stale, "PUT", url, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	names, _ = model.NewPuzzle(3).GetPuzzleList(TEST_USERID)
+	assert.NotContains(t, names, "rest-stale-renamed")
+}
+
+func TestPuzzleHandler_RenameAndDelete(t *testing.T) {
+	session, id := newTestSession(t, "rest-rename")
+	_, otherID := newTestSession(t, "rest-rename-other")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-renamed")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-rename-other")
+	url := "/puzzles/" + strconv.Itoa(id)
+
+	// A name already used is a conflict
+	rr := doPuzzleRequest(session, "PATCH", url, `{"puzzlename": "rest-rename-other"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = doPuzzleRequest(session, "PATCH", url, `{"puzzlename": "rest-renamed"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "rest-renamed", decodePuzzle(t, rr).Puzzlename)
+	name, err := model.LookupPuzzleName(TEST_USERID, id)
+	assert.Nil(t, err)
+	assert.Equal(t, "rest-renamed", name)
+
+	// Delete it
+	rr = doPuzzleRequest(session, "DELETE", url, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// The other puzzle is still there
+	rr = doPuzzleRequest(session, "GET", "/puzzles/"+strconv.Itoa(otherID), "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestPuzzleHandler_Validate(t *testing.T) {
+	session, id := newTestSession(t, "rest-validate")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-validate")
+	url := "/puzzles/" + strconv.Itoa(id) + "/validate"
+
+	rr := doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	diagnostics := make([]model.Diagnostic, 0)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &diagnostics))
+	assert.Equal(t, model.EMPTY_CELL, diagnostics[0].Kind)
+	assert.Equal(t, 9, len(diagnostics[0].Points))
+
+	rr = doPuzzleRequest(session, "POST", url, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestPuzzleHandler_Stats(t *testing.T) {
+	session, id := newTestSession(t, "rest-stats")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-stats")
+	url := "/puzzles/" + strconv.Itoa(id) + "/stats"
+
+	rr := doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	stats := new(model.Statistics)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), stats))
+	assert.Equal(t, 6, stats.WordCount)
+	assert.Equal(t, map[int]int{3: 6}, stats.WordLengths)
+	assert.Equal(t, 1, stats.OpenSquares)
+}
+
+func TestPuzzleHandler_HeatMap(t *testing.T) {
+	session, id := newTestSession(t, "rest-heatmap")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-heatmap")
+	url := "/puzzles/" + strconv.Itoa(id) + "/heatmap"
+
+	rr := doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	heatMap := new(model.HeatMap)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), heatMap))
+	assert.Equal(t, 6, len(heatMap.Words))
+	assert.Equal(t, 9, len(heatMap.Cells))
+
+	rr = doPuzzleRequest(session, "GET", url+"?format=svg", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "<svg")
+
+	rr = doPuzzleRequest(session, "GET", url+"?format=png", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -16,10 +16,14 @@ type PuzzleEntries struct {
 	Entries []PuzzleEntry `json:"entries"`
 }
 
+// PuzzleEntry is one of the user's puzzles, or one shared with them by
+// its owner in the specified role.
 type PuzzleEntry struct {
-	ID         int    `json:"id"`
-	Puzzlename string `json:"puzzlename"`
-	Modified   string `json:"modified"`
+	ID         int        `json:"id"`
+	Puzzlename string     `json:"puzzlename"`
+	Modified   string     `json:"modified"`
+	Owner      string     `json:"owner,omitempty"`
+	Role       model.Role `json:"role,omitempty"`
 }
 
 // ---------------------------------------------------------------------
@@ -28,7 +32,8 @@ type PuzzleEntry struct {
 
 // PuzzlesHandler serves REST requests for:
 //
-//   - GET:  Returns a list of puzzles for this user
+//   - GET:  Returns a list of puzzles for this user, followed by those
+//     shared with this user by others
 //   - POST: Adds a new puzzle
 func PuzzlesHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -67,6 +72,23 @@ func PuzzlesHandler(w http.ResponseWriter, r *http.Request) {
 		entries.Entries = append(entries.Entries, entry)
 	}
 
+	// Add the puzzles shared with this user
+	shared, err := model.GetSharedPuzzles(userid)
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, sp := range shared {
+		entries.Entries = append(entries.Entries, PuzzleEntry{
+			ID:         sp.ID,
+			Puzzlename: sp.Puzzlename,
+			Modified:   sp.Modified,
+			Owner:      sp.Owner,
+			Role:       sp.Role,
+		})
+	}
+
 	// Convert the slice to JSON
 	jsonBlob, err := json.MarshalIndent(entries, "", "  ")
 	if err != nil {
@@ -0,0 +1,204 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// RevisionList is the JSON representation of the saved revisions of a
+// puzzle, returned by the /puzzles/{id}/revisions resource.
+type RevisionList struct {
+	ID        int              `json:"id"`
+	Revisions []model.Revision `json:"revisions"`
+}
+
+// RevisionDiff is the JSON representation of the differences between
+// two revisions of a puzzle.
+type RevisionDiff struct {
+	From        int                `json:"from"`
+	To          int                `json:"to"`
+	Differences []model.Difference `json:"differences"`
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// handleRevisions serves the requests for the saved revisions of a
+// puzzle:
+//
+//   - GET  /puzzles/{id}/revisions: Returns the list of revisions
+//   - GET  /puzzles/{id}/revisions/{n}: Returns revision n as JSON
+//   - GET  /puzzles/{id}/revisions/{n}/diff: Returns the differences
+//     from revision n to the latest one (or to revision m, with ?to=m)
+//   - POST /puzzles/{id}/revisions/{n}/restore: Makes revision n the
+//     current version, replacing the working copy
+//   - POST /puzzles/{id}/revisions/{n}/fork: Saves revision n as a new
+//     puzzle, given {"puzzlename": name}
+//
+// Viewers can list, get, and diff revisions, editors can also restore
+// them, and only the owner can fork them.
+func (pr *puzzleRequest) handleRevisions() {
+	if !pr.require(model.VIEW_ROLE) {
+		return
+	}
+	userid := pr.access.Owner
+	puzzlename := pr.access.Puzzlename
+
+	// The list of revisions
+	if len(pr.path) == 1 {
+		if pr.r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		revisions, err := model.GetRevisions(userid, puzzlename)
+		if err != nil {
+			pr.dbError(err)
+			return
+		}
+		pr.writeJSON(RevisionList{ID: pr.id, Revisions: revisions})
+		return
+	}
+
+	// A single revision
+	revision, err := strconv.Atoi(pr.path[1])
+	if err != nil || len(pr.path) > 3 {
+		pr.error(fmt.Errorf("no such resource %q", pr.r.URL.Path), http.StatusNotFound)
+		return
+	}
+	action := ""
+	if len(pr.path) == 3 {
+		action = pr.path[2]
+	}
+	switch action {
+	case "":
+		if pr.r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		puzzle, err := model.LoadRevision(userid, puzzlename, revision)
+		if err != nil {
+			pr.dbError(err)
+			return
+		}
+		pr.writePuzzle(puzzle)
+	case "diff":
+		if pr.r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		pr.handleRevisionDiff(puzzlename, revision)
+	case "restore":
+		if pr.r.Method != http.MethodPost {
+			pr.methodNotAllowed("POST")
+			return
+		}
+		if !pr.require(model.EDIT_ROLE) {
+			return
+		}
+		pr.handleRevisionRestore(puzzlename, revision)
+	case "fork":
+		if pr.r.Method != http.MethodPost {
+			pr.methodNotAllowed("POST")
+			return
+		}
+		if !pr.require(model.OWNER_ROLE) {
+			return
+		}
+		pr.handleRevisionFork(puzzlename, revision)
+	default:
+		pr.error(fmt.Errorf("no such resource %q", pr.r.URL.Path), http.StatusNotFound)
+	}
+}
+
+// handleRevisionDiff returns the differences between two revisions.
+// The second one is given by the query parameter "to", and is the
+// latest revision if not specified.
+func (pr *puzzleRequest) handleRevisionDiff(puzzlename string, from int) {
+	userid := pr.access.Owner
+	var to int
+	if s := pr.r.URL.Query().Get("to"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			pr.error(fmt.Errorf("invalid revision %q", s), http.StatusBadRequest)
+			return
+		}
+		to = n
+	} else {
+		revisions, err := model.GetRevisions(userid, puzzlename)
+		if err != nil {
+			pr.dbError(err)
+			return
+		}
+		if len(revisions) > 0 {
+			to = revisions[len(revisions)-1].Number
+		}
+	}
+	diffs, err := model.DiffRevisions(userid, puzzlename, from, to)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+	pr.writeJSON(RevisionDiff{From: from, To: to, Differences: diffs})
+}
+
+// handleRevisionFork saves a revision as a new puzzle with the name
+// given in the body, and returns the new puzzle with a 201 status.
+func (pr *puzzleRequest) handleRevisionFork(puzzlename string, revision int) {
+	userid := pr.access.Owner
+	var body struct {
+		Puzzlename string `json:"puzzlename"`
+	}
+	if !pr.readBody(&body) {
+		return
+	}
+	newName := strings.TrimSpace(body.Puzzlename)
+	if newName == "" {
+		pr.error(fmt.Errorf("puzzle name must not be empty"), http.StatusBadRequest)
+		return
+	}
+	fork, err := model.ForkRevision(userid, puzzlename, revision, newName)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+	id, err := model.LookupPuzzleID(userid, newName)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+	pr.w.Header().Set("Location", fmt.Sprintf("/puzzles/%d", id))
+	pr.w.Header().Set("Content-Type", "application/json")
+	pr.w.WriteHeader(http.StatusCreated)
+	pr.writeJSON(NewPuzzleDetail(id, fork))
+}
+
+// handleRevisionRestore makes a revision the current version of the
+// puzzle, and replaces the session's working copy with it.  Any unsaved
+// changes in the working copy are lost, and the saved undo/redo history
+// is cleared.  Working copies in other sessions and collaborative rooms
+// are not changed, but they can no longer be saved over the restored
+// revision (without ?overwrite=true, see handleSave).
+func (pr *puzzleRequest) handleRevisionRestore(puzzlename string, revision int) {
+	userid := pr.access.Owner
+	working := pr.getPuzzle()
+	if working == nil {
+		return
+	}
+	puzzle, err := model.RestoreRevision(userid, puzzlename, revision)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+	puzzle.SetUserWords(working.GetUserWords())
+	pr.session.PUZZLES[pr.id] = puzzle
+	pr.writePuzzle(puzzle)
+}
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPuzzleHandler_Revisions(t *testing.T) {
+	session, id := newTestSession(t, "rest-revisions")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-revisions")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-revisions-fork")
+	url := "/puzzles/" + strconv.Itoa(id)
+
+	// Change the puzzle and save it as the second revision
+	rr := doPuzzleRequest(session, "PUT", url+"/words/1/across/text", `{"text": "CAT"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(session, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// List the revisions
+	rr = doPuzzleRequest(session, "GET", url+"/revisions", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	list := new(RevisionList)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), list))
+	assert.Equal(t, id, list.ID)
+	assert.Equal(t, 2, len(list.Revisions))
+
+	// Get the first revision
+	rr = doPuzzleRequest(session, "GET", url+"/revisions/1", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"   ", "   ", "   "}, decodePuzzle(t, rr).Grid)
+
+	// Compare it with the latest
+	rr = doPuzzleRequest(session, "GET", url+"/revisions/1/diff", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	diff := new(RevisionDiff)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), diff))
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	assert.Equal(t, 3, len(diff.Differences))
+	assert.Equal(t, model.CELL_DIFFERENCE, diff.Differences[0].Kind)
+	assert.Equal(t, "C", diff.Differences[0].After)
+
+	rr = doPuzzleRequest(session, "GET", url+"/revisions/2/diff?to=2", "")
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), diff))
+	assert.Equal(t, 0, len(diff.Differences))
+
+	// Fork the first revision as a new puzzle
+	rr = doPuzzleRequest(session, "POST", url+"/revisions/1/fork", `{"puzzlename": "rest-revisions-fork"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	pd := decodePuzzle(t, rr)
+	assert.NotEqual(t, id, pd.ID)
+	assert.Equal(t, "rest-revisions-fork", pd.Puzzlename)
+	assert.Equal(t, "/puzzles/"+strconv.Itoa(pd.ID), rr.Header().Get("Location"))
+	rr = doPuzzleRequest(session, "POST", url+"/revisions/1/fork", `{"puzzlename": "rest-revisions-fork"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Restore the first revision, which replaces the working copy
+	rr = doPuzzleRequest(session, "POST", url+"/revisions/1/restore", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"   ", "   ", "   "}, decodePuzzle(t, rr).Grid)
+	rr = doPuzzleRequest(session, "GET", url, "")
+	assert.Equal(t, []string{"   ", "   ", "   "}, decodePuzzle(t, rr).Grid)
+	rr = doPuzzleRequest(session, "GET", url+"/revisions", "")
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), list))
+	assert.Equal(t, 3, len(list.Revisions))
+
+	// Errors
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"no such revision", "GET", url + "/revisions/9", http.StatusNotFound},
+		{"bad revision", "GET", url + "/revisions/first", http.StatusNotFound},
+		{"no such action", "GET", url + "/revisions/1/bogus", http.StatusNotFound},
+		{"bad diff", "GET", url + "/revisions/1/diff?to=last", http.StatusBadRequest},
+		{"bad method", "GET", url + "/revisions/1/restore", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doPuzzleRequest(session, tt.method, tt.url, "")
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestPuzzleHandler_SaveAfterRestore(t *testing.T) {
+	session, id := newTestSession(t, "rest-restore-stale")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-restore-stale")
+	url := "/puzzles/" + strconv.Itoa(id)
+	stale := NewSession()
+	stale.USERID = TEST_USERID
+	assert.Nil(t, Sessions.Save(stale))
+	rr := doPuzzleRequest(stale, "PUT", url+"/words/1/across/text", `{"text": "CAT"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Another session restores a revision, so the stale copy is refused
+	rr = doPuzzleRequest(session, "POST", url+"/revisions/1/restore", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(stale, "PUT", url, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// The restoring session can still save, and the stale one can
+	// overwrite it on purpose
+	rr = doPuzzleRequest(session, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(stale, "PUT", url+"?overwrite=true", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "CAT", decodePuzzle(t, rr).Grid[0])
+	rr = doPuzzleRequest(stale, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
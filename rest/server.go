@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/philhanna/cwcomp"
+	"github.com/philhanna/cwcomp/model"
 )
 
 // ---------------------------------------------------------------------
@@ -20,10 +21,35 @@ func HandleRequests() {
 	port := config.SERVER.PORT
 	hostAndPort := fmt.Sprintf("%s:%d", host, port)
 
+	// Bring the database schema up to date
+	if _, err := model.Migrate(); err != nil {
+		log.Fatalf("Could not migrate the database: %v\n", err)
+	}
+
+	// Choose where sessions are kept, and delete them as they expire
+	switch config.SERVER.SESSIONS {
+	case "memory":
+		Sessions = NewMemorySessionStore()
+	case "", "database":
+		Sessions = NewSQLiteSessionStore()
+	default:
+		log.Fatalf("Invalid session store %q in configuration\n", config.SERVER.SESSIONS)
+	}
+	stopSweeper := StartSessionSweeper(Sessions, SESSION_SWEEP_INTERVAL)
+	defer stopSweeper()
+
 	// Define the handler functions
 	http.HandleFunc("/login", LoginHandler)
+	http.HandleFunc("/logout", LogoutHandler)
+	http.HandleFunc("/register", RegisterHandler)
+	http.HandleFunc("/user", UserHandler)
+	http.HandleFunc("/user/password", PasswordHandler)
 	http.HandleFunc("/puzzles", PuzzlesHandler)
 	http.HandleFunc("/puzzles/", PuzzleHandler)
+	http.HandleFunc("/collab/", CollabHandler)
+	http.HandleFunc("/words", WordsHandler)
+	http.HandleFunc("/suggestions", SuggestionsHandler)
+	http.HandleFunc("/anagrams", AnagramsHandler)
 
 	// Start the server
 	log.Printf("Starting server on %v\n", hostAndPort)
@@ -4,20 +4,28 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/philhanna/cwcomp/model"
 )
 
 // ---------------------------------------------------------------------
 // Type definitions
 // ---------------------------------------------------------------------
 
+// Session is a logged-in user.  A handler that uses the working copies
+// of the user's puzzles must hold the session's lock while it does, so
+// that two requests in the same session do not change them at once.
 type Session struct {
 	ID       string
 	EXPIRES  time.Time
 	USERID   int
 	USERNAME string
+	PUZZLES  map[int]*model.Puzzle // Working copies of puzzles, by ID
+	mu       sync.Mutex            // Held while a request uses PUZZLES
+	expiryMu sync.Mutex            // Held while EXPIRES is read or renewed
 }
 
 // ---------------------------------------------------------------------
@@ -25,11 +33,15 @@ type Session struct {
 // ---------------------------------------------------------------------
 
 const (
-	SESSION_COOKIE = "session_id"
+	SESSION_COOKIE           = "session_id"
+	SESSION_TIMEOUT          = 30 * time.Minute // Time from the last request until a session expires
+	SESSION_SWEEP_INTERVAL   = 5 * time.Minute  // Time between deletions of expired sessions
+	SESSION_PERSIST_INTERVAL = time.Minute      // Least change in expiration time that is written to the database
 )
 
-// Sessions is a map of session IDs to session pointers
-var Sessions = make(map[string]*Session)
+// Sessions is where the sessions are kept.  It is in memory until
+// HandleRequests replaces it with the store named in the configuration.
+var Sessions SessionStore = NewMemorySessionStore()
 
 // ---------------------------------------------------------------------
 // Constructor
@@ -40,7 +52,8 @@ var Sessions = make(map[string]*Session)
 func NewSession() *Session {
 	ps := new(Session)
 	ps.ID = uuid.NewString()
-	ps.EXPIRES = time.Now().Add(30 * time.Minute)
+	ps.EXPIRES = time.Now().Add(SESSION_TIMEOUT)
+	ps.PUZZLES = make(map[int]*model.Puzzle)
 	return ps
 }
 
@@ -48,21 +61,45 @@ func NewSession() *Session {
 // Methods
 // ---------------------------------------------------------------------
 
+// Expiration returns the time when the session expires.
+func (ps *Session) Expiration() time.Time {
+	ps.expiryMu.Lock()
+	defer ps.expiryMu.Unlock()
+	return ps.EXPIRES
+}
+
+// IsExpired returns true if the session has expired.
+func (ps *Session) IsExpired() bool {
+	return ps.Expiration().Before(time.Now())
+}
+
+// Lock locks the session's working copies of puzzles.
+func (ps *Session) Lock() {
+	ps.mu.Lock()
+}
+
+// Unlock unlocks the session's working copies of puzzles.
+func (ps *Session) Unlock() {
+	ps.mu.Unlock()
+}
+
 // Renew extends the session expiration time to another 30 minutes.
 func (ps *Session) Renew() {
-	ps.EXPIRES = time.Now().Add(30 * time.Minute)
+	ps.expiryMu.Lock()
+	defer ps.expiryMu.Unlock()
+	ps.EXPIRES = time.Now().Add(SESSION_TIMEOUT)
 }
 
 // NewSessionCookie creates a session cookie and returns a pointer to it.
 func (ps *Session) NewSessionCookie() *http.Cookie {
 	cookie := http.Cookie{
-		Name:     "session",
+		Name:     SESSION_COOKIE,
 		Value:    ps.ID,
 		Path:     "/",                     // Set the cookie path to the root
 		HttpOnly: true,                    // Ensure the cookie is only accessible via HTTP(S)
 		Secure:   true,                    // Send the cookie only over HTTPS
 		SameSite: http.SameSiteStrictMode, // Enforce strict same-site policy
-		Expires:  ps.EXPIRES,              // Set an expiration time for the cookie
+		Expires:  ps.Expiration(),         // Set an expiration time for the cookie
 	}
 	return &cookie
 }
@@ -72,15 +109,16 @@ func (ps *Session) NewSessionCookie() *http.Cookie {
 // ---------------------------------------------------------------------
 
 // GetSession gets the session ID from the request cookie and looks up
-// the correct session in the session map. If there is no cookie, or if
-// the session does not exist or has expired, a 401 HTTP error is
-// written to the response
+// the correct session in the session store.  If there is no cookie, or
+// if the session does not exist or has expired, an error is returned.
+// Otherwise the session is renewed, and the cookie with its new
+// expiration time is added to the response.
 func GetSession(w http.ResponseWriter, r *http.Request) (*Session, error) {
 
 	// Check for the existence of a session cookie, which will contain
 	// the session ID and an expiration time. If no session ID is found,
 	// or if the session is expired, go to the login screen.
-	cookie, err := r.Cookie("session")
+	cookie, err := r.Cookie(SESSION_COOKIE)
 	if err != nil {
 		err := fmt.Errorf("no session cookie found: %v", err)
 		log.Println(err)
@@ -89,20 +127,59 @@ func GetSession(w http.ResponseWriter, r *http.Request) (*Session, error) {
 
 	session_id := cookie.Value
 
-	// Get the session from the map
-	session, ok := Sessions[session_id]
-	if !ok {
-		err := fmt.Errorf("session id %q not found in session map", session_id)
+	// Get the session from the store
+	session, err := Sessions.Get(session_id)
+	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
 	// Check for expired session
-	if session.EXPIRES.Before(time.Now()) {
+	if session.IsExpired() {
 		err := fmt.Errorf("session id %q has expired", session_id)
+		log.Println(err)
+		Sessions.Delete(session_id)
+		return nil, err
+	}
+
+	// Keep it alive for another 30 minutes
+	session.Renew()
+	if err := Sessions.Save(session); err != nil {
 		log.Println(err)
 		return nil, err
 	}
+	http.SetCookie(w, session.NewSessionCookie())
 
 	return session, nil
 }
+
+// StartSessionSweeper deletes the expired sessions from the store at
+// the specified interval, in the background, until the returned
+// function is called.
+func StartSessionSweeper(store SessionStore, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				n, err := store.DeleteExpired(now)
+				switch {
+				case err != nil:
+					log.Printf("Could not delete expired sessions: %v\n", err)
+				case n > 0:
+					log.Printf("Deleted %d expired session(s)\n", n)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
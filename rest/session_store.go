@@ -0,0 +1,226 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// SessionStore is where sessions are kept between requests.  Its
+// methods can be called from concurrent handlers.
+type SessionStore interface {
+	// Get returns the session with the specified ID, which may have
+	// expired, or an error if there is none.
+	Get(id string) (*Session, error)
+
+	// Save adds a session, or updates the one with the same ID.
+	Save(session *Session) error
+
+	// Delete removes the session with the specified ID, if there is one.
+	Delete(id string) error
+
+	// DeleteExpired removes the sessions that expired before the
+	// specified time, and returns how many there were.
+	DeleteExpired(now time.Time) (int, error)
+}
+
+// MemorySessionStore keeps sessions in memory, so they are lost when
+// the server stops.
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// SQLiteSessionStore keeps sessions in the sessions table of the
+// database, so that users stay logged in when the server restarts.  The
+// working copies of puzzles are only kept in memory, so a session that
+// is read back from the database starts with none.
+//
+// Renewing a session only moves its expiration time a little, so to
+// avoid writing to the database on every request, a session that is
+// saved again is only written if its expiration time has moved by at
+// least SESSION_PERSIST_INTERVAL.  After a restart, a session may
+// therefore expire up to that much sooner than it would have.
+type SQLiteSessionStore struct {
+	mu        sync.Mutex
+	cache     map[string]*Session  // Sessions already read, so each request gets the same one
+	persisted map[string]time.Time // Expiration times last written to the database
+	writeMu   sync.Mutex           // Held while the database is written, so writes are in order
+}
+
+// ---------------------------------------------------------------------
+// Constructor
+// ---------------------------------------------------------------------
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	store := new(MemorySessionStore)
+	store.sessions = make(map[string]*Session)
+	return store
+}
+
+// NewSQLiteSessionStore creates a session store in the database.
+func NewSQLiteSessionStore() *SQLiteSessionStore {
+	store := new(SQLiteSessionStore)
+	store.cache = make(map[string]*Session)
+	store.persisted = make(map[string]time.Time)
+	return store
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// Get returns the session with the specified ID.
+func (store *MemorySessionStore) Get(id string) (*Session, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session id %q not found in session map", id)
+	}
+	return session, nil
+}
+
+// Save adds or updates a session.
+func (store *MemorySessionStore) Save(session *Session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.sessions[session.ID] = session
+	return nil
+}
+
+// Delete removes a session.
+func (store *MemorySessionStore) Delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.sessions, id)
+	return nil
+}
+
+// DeleteExpired removes the expired sessions.
+func (store *MemorySessionStore) DeleteExpired(now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	n := 0
+	for id, session := range store.sessions {
+		if session.Expiration().Before(now) {
+			delete(store.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Get returns the session with the specified ID, reading it from the
+// database if it is not already in memory.
+func (store *SQLiteSessionStore) Get(id string) (*Session, error) {
+	store.mu.Lock()
+	session, ok := store.cache[id]
+	store.mu.Unlock()
+	if ok {
+		return session, nil
+	}
+
+	// Read it without holding the lock, so that other requests are not
+	// held up by the database
+	record, err := model.LoadSession(id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("session id %q not found in session store", id)
+		}
+		return nil, err
+	}
+	session = new(Session)
+	session.ID = record.ID
+	session.EXPIRES = record.Expires
+	session.USERID = record.UserID
+	session.USERNAME = record.Username
+	session.PUZZLES = make(map[int]*model.Puzzle)
+
+	// Another request may have read it in the meantime
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if cached, ok := store.cache[id]; ok {
+		return cached, nil
+	}
+	store.cache[id] = session
+	store.persisted[id] = record.Expires
+	return session, nil
+}
+
+// Save adds or updates a session in the database.  A session that is
+// already there is not written again unless its expiration time has
+// moved by at least SESSION_PERSIST_INTERVAL.
+func (store *SQLiteSessionStore) Save(session *Session) error {
+	if store.isPersisted(session) {
+		return nil
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if store.isPersisted(session) {
+		return nil
+	}
+
+	expires := session.Expiration()
+	err := model.SaveSession(&model.SessionRecord{
+		ID:       session.ID,
+		UserID:   session.USERID,
+		Username: session.USERNAME,
+		Expires:  expires,
+	})
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.cache[session.ID] = session
+	store.persisted[session.ID] = expires
+	return nil
+}
+
+// isPersisted returns true if the session is in the database with an
+// expiration time within SESSION_PERSIST_INTERVAL of its own.
+func (store *SQLiteSessionStore) isPersisted(session *Session) bool {
+	expires := session.Expiration()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	last, ok := store.persisted[session.ID]
+	return ok && store.cache[session.ID] == session &&
+		expires.Sub(last).Abs() < SESSION_PERSIST_INTERVAL
+}
+
+// Delete removes a session from the database.
+func (store *SQLiteSessionStore) Delete(id string) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+
+	store.mu.Lock()
+	delete(store.cache, id)
+	delete(store.persisted, id)
+	store.mu.Unlock()
+	return model.DeleteSession(id)
+}
+
+// DeleteExpired removes the expired sessions from the database.
+func (store *SQLiteSessionStore) DeleteExpired(now time.Time) (int, error) {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+
+	store.mu.Lock()
+	for id, session := range store.cache {
+		if session.Expiration().Before(now) {
+			delete(store.cache, id)
+			delete(store.persisted, id)
+		}
+	}
+	store.mu.Unlock()
+	return model.DeleteExpiredSessions(now)
+}
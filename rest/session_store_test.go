@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// sessionStores returns each kind of session store, by name
+func sessionStores() map[string]SessionStore {
+	return map[string]SessionStore{
+		"memory":   NewMemorySessionStore(),
+		"database": NewSQLiteSessionStore(),
+	}
+}
+
+// newUserSession creates a session for the test user that expires at
+// the specified time.
+func newUserSession(expires time.Time) *Session {
+	session := NewSession()
+	session.USERID = TEST_USERID
+	session.USERNAME = "saspeh"
+	session.EXPIRES = expires
+	return session
+}
+
+func TestSessionStore(t *testing.T) {
+	for name, store := range sessionStores() {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			live := newUserSession(now.Add(time.Hour))
+			dead := newUserSession(now.Add(-time.Minute))
+			assert.Nil(t, store.Save(live))
+			assert.Nil(t, store.Save(dead))
+
+			session, err := store.Get(live.ID)
+			assert.Nil(t, err)
+			assert.Same(t, live, session)
+			assert.Equal(t, "saspeh", session.USERNAME)
+
+			n, err := store.DeleteExpired(now)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, n)
+			_, err = store.Get(dead.ID)
+			assert.NotNil(t, err)
+
+			assert.Nil(t, store.Delete(live.ID))
+			assert.Nil(t, store.Delete(live.ID))
+			_, err = store.Get(live.ID)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestSessionStore_Concurrent(t *testing.T) {
+	for name, store := range sessionStores() {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					session := newUserSession(time.Now().Add(time.Hour))
+					assert.Nil(t, store.Save(session))
+					for j := 0; j < 5; j++ {
+						have, err := store.Get(session.ID)
+						assert.Nil(t, err)
+						have.Renew()
+						assert.Nil(t, store.Save(have))
+						_, err = store.DeleteExpired(time.Now())
+						assert.Nil(t, err)
+					}
+					assert.Nil(t, store.Delete(session.ID))
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestSQLiteSessionStore_Restart(t *testing.T) {
+	session := newUserSession(time.Now().Add(time.Hour))
+	assert.Nil(t, NewSQLiteSessionStore().Save(session))
+
+	// A new store, as if the server had restarted, reads it back
+	store := NewSQLiteSessionStore()
+	defer store.Delete(session.ID)
+	have, err := store.Get(session.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, session.USERID, have.USERID)
+	assert.Equal(t, session.USERNAME, have.USERNAME)
+	assert.True(t, session.EXPIRES.Truncate(time.Second).Equal(have.EXPIRES))
+	assert.Empty(t, have.PUZZLES)
+}
+
+func TestSQLiteSessionStore_SaveInterval(t *testing.T) {
+	store := NewSQLiteSessionStore()
+	session := newUserSession(time.Now().Add(time.Hour))
+	assert.Nil(t, store.Save(session))
+	defer store.Delete(session.ID)
+
+	// Remove the row behind the store's back, to see when it is written
+	persisted := func() bool {
+		_, err := model.LoadSession(session.ID)
+		return err == nil
+	}
+	assert.Nil(t, model.DeleteSession(session.ID))
+
+	// A small change in the expiration time is not written
+	session.EXPIRES = session.EXPIRES.Add(SESSION_PERSIST_INTERVAL / 2)
+	assert.Nil(t, store.Save(session))
+	assert.False(t, persisted())
+
+	// A larger one is
+	session.EXPIRES = session.EXPIRES.Add(SESSION_PERSIST_INTERVAL)
+	assert.Nil(t, store.Save(session))
+	assert.True(t, persisted())
+}
+
+func TestStartSessionSweeper(t *testing.T) {
+	store := NewMemorySessionStore()
+	dead := newUserSession(time.Now().Add(-time.Minute))
+	assert.Nil(t, store.Save(dead))
+
+	stop := StartSessionSweeper(store, time.Millisecond)
+	defer stop()
+	assert.Eventually(t, func() bool {
+		_, err := store.Get(dead.ID)
+		return err != nil
+	}, time.Second, time.Millisecond)
+	stop()
+}
+
+func TestGetSession(t *testing.T) {
+	saved := Sessions
+	defer func() { Sessions = saved }()
+	Sessions = NewMemorySessionStore()
+
+	getSession := func(cookie *http.Cookie) (*Session, *httptest.ResponseRecorder, error) {
+		req, _ := http.NewRequest("GET", "/puzzles", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		session, err := GetSession(rr, req)
+		return session, rr, err
+	}
+
+	// Each request renews the session and its cookie
+	session := newUserSession(time.Now().Add(time.Minute))
+	assert.Nil(t, Sessions.Save(session))
+	have, rr, err := getSession(session.NewSessionCookie())
+	assert.Nil(t, err)
+	assert.Same(t, session, have)
+	assert.True(t, session.Expiration().After(time.Now().Add(SESSION_TIMEOUT-time.Minute)))
+	cookies := rr.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, SESSION_COOKIE, cookies[0].Name)
+	assert.Equal(t, session.ID, cookies[0].Value)
+	assert.True(t, cookies[0].Expires.After(time.Now().Add(SESSION_TIMEOUT-time.Minute)))
+
+	// An expired session is deleted
+	session.EXPIRES = time.Now().Add(-time.Minute)
+	_, _, err = getSession(session.NewSessionCookie())
+	assert.Equal(t, fmt.Sprintf("session id %q has expired", session.ID), err.Error())
+	_, err = Sessions.Get(session.ID)
+	assert.NotNil(t, err)
+
+	// No cookie, or an unknown session
+	_, _, err = getSession(nil)
+	assert.NotNil(t, err)
+	_, _, err = getSession(&http.Cookie{Name: SESSION_COOKIE, Value: "bogus"})
+	assert.NotNil(t, err)
+}
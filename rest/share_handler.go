@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// ShareList is the JSON representation of the users a puzzle is shared
+// with, returned by the /puzzles/{id}/shares resource.
+type ShareList struct {
+	ID     int           `json:"id"`
+	Shares []model.Share `json:"shares"`
+}
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// handleShares serves the requests for the users a puzzle is shared
+// with:
+//
+//   - GET    /puzzles/{id}/shares: Returns the list of users and roles
+//   - PUT    /puzzles/{id}/shares/{username}: Shares the puzzle with the
+//     user, given {"role": "view"} or {"role": "edit"}
+//   - DELETE /puzzles/{id}/shares/{username}: Stops sharing the puzzle
+//     with the user
+//
+// Anyone who can open the puzzle can list the shares, but only the
+// owner can change them.
+func (pr *puzzleRequest) handleShares() {
+	owner := pr.access.Owner
+	puzzlename := pr.access.Puzzlename
+
+	// The list of shares
+	if len(pr.path) == 1 {
+		if pr.r.Method != http.MethodGet {
+			pr.methodNotAllowed("GET")
+			return
+		}
+		if !pr.require(model.VIEW_ROLE) {
+			return
+		}
+		pr.writeShares()
+		return
+	}
+
+	// A single share
+	if len(pr.path) != 2 {
+		pr.error(fmt.Errorf("no such resource %q", pr.r.URL.Path), http.StatusNotFound)
+		return
+	}
+	username := pr.path[1]
+	switch pr.r.Method {
+	case http.MethodPut:
+		if !pr.require(model.OWNER_ROLE) {
+			return
+		}
+		var body struct {
+			Role string `json:"role"`
+		}
+		if !pr.readBody(&body) {
+			return
+		}
+		role, err := model.RoleFromString(body.Role)
+		if err != nil {
+			pr.error(err, http.StatusBadRequest)
+			return
+		}
+		if username == pr.session.USERNAME {
+			pr.error(fmt.Errorf("cannot share a puzzle with its owner"), http.StatusBadRequest)
+			return
+		}
+		if err := model.SharePuzzle(owner, puzzlename, username, role); err != nil {
+			pr.dbError(err)
+			return
+		}
+		refreshCollabRoom(pr.id)
+		pr.writeShares()
+	case http.MethodDelete:
+		if !pr.require(model.OWNER_ROLE) {
+			return
+		}
+		if err := model.UnsharePuzzle(owner, puzzlename, username); err != nil {
+			pr.dbError(err)
+			return
+		}
+		refreshCollabRoom(pr.id)
+		pr.w.WriteHeader(http.StatusNoContent)
+	default:
+		pr.methodNotAllowed("PUT, DELETE")
+	}
+}
+
+// writeShares writes the list of users the puzzle is shared with as
+// JSON to the response.
+func (pr *puzzleRequest) writeShares() {
+	shares, err := model.GetShares(pr.access.Owner, pr.access.Puzzlename)
+	if err != nil {
+		pr.dbError(err)
+		return
+	}
+	pr.writeJSON(ShareList{ID: pr.id, Shares: shares})
+}
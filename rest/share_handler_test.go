@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOtherSession adds a second user to the database and creates a
+// session for them, returning the session and a function that removes
+// the user again.
+func newOtherSession(t *testing.T, username string) (*Session, func()) {
+	con, _ := model.Connect()
+	defer con.Close()
+	result, err := con.Exec(`INSERT INTO users (username, password, created) VALUES(?, ?, ?)`,
+		username, []byte{0}, time.Now().Format(time.RFC3339))
+	assert.Nil(t, err)
+	userid, _ := result.LastInsertId()
+
+	session := NewSession()
+	session.USERID = int(userid)
+	session.USERNAME = username
+	assert.Nil(t, Sessions.Save(session))
+
+	return session, func() {
+		con, _ := model.Connect()
+		defer con.Close()
+		con.Exec(`DELETE FROM users WHERE userid=?`, userid)
+	}
+}
+
+func TestPuzzleHandler_Shares(t *testing.T) {
+	session, id := newTestSession(t, "rest-shares")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-shares")
+	other, removeOther := newOtherSession(t, "rest-shares-user")
+	defer removeOther()
+	url := "/puzzles/" + strconv.Itoa(id)
+	shareURL := url + "/shares/rest-shares-user"
+
+	// Until it is shared, the other user cannot see the puzzle
+	rr := doPuzzleRequest(other, "GET", url, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Share it as a viewer, who can look but not touch
+	rr = doPuzzleRequest(session, "PUT", shareURL, `{"role": "view"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	list := new(ShareList)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), list))
+	assert.Equal(t, []model.Share{{Username: "rest-shares-user", Role: model.VIEW_ROLE}}, list.Shares)
+
+	rr = doPuzzleRequest(other, "GET", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(other, "GET", url+"/revisions", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(other, "POST", url+"/toggle", `{"r": 1, "c": 1}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Make them an editor, who can change and save it
+	rr = doPuzzleRequest(session, "PUT", shareURL, `{"role": "edit"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(other, "PUT", url+"/words/1/across/text", `{"text": "DOG"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doPuzzleRequest(other, "PUT", url, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	puzzle, err := model.LoadPuzzle(TEST_USERID, "rest-shares")
+	assert.Nil(t, err)
+	assert.Equal(t, "DOG", puzzle.GetText(puzzle.LookupWordByNumber(1, model.ACROSS)))
+
+	// But not rename, delete, fork, or share it
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{"rename", "PATCH", url, `{"puzzlename": "stolen"}`},
+		{"delete", "DELETE", url, ""},
+		{"fork", "POST", url + "/revisions/1/fork", `{"puzzlename": "stolen"}`},
+		{"share", "PUT", url + "/shares/saspeh", `{"role": "view"}`},
+		{"unshare", "DELETE", shareURL, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doPuzzleRequest(other, tt.method, tt.url, tt.body)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		})
+	}
+
+	// Errors
+	rr = doPuzzleRequest(session, "PUT", url+"/shares/bogus", `{"role": "view"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doPuzzleRequest(session, "PUT", shareURL, `{"role": "owner"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doPuzzleRequest(session, "PUT", url+"/shares/saspeh", `{"role": "view"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Stop sharing it
+	rr = doPuzzleRequest(session, "DELETE", shareURL, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doPuzzleRequest(other, "GET", url, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doPuzzleRequest(session, "DELETE", shareURL, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
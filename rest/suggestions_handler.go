@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// SuggestionsSummary is the data of the start and done events of a
+// suggestions stream.  Count is only set in the done event.
+type SuggestionsSummary struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// SuggestionsHandler streams the words that match a pattern, best
+// first, as Server-Sent Events, so that they can be shown as soon as
+// they are found instead of when the whole dictionary has been scanned:
+//
+//   - GET /suggestions?pattern={query}: Matches a query, such as
+//     "C?T +A", in the same language as /words (see model.WordQuery)
+//   - GET /suggestions?puzzle={id}&seq={seq}&dir={dir}: Matches the
+//     letters already in a word of the session's working copy of a
+//     puzzle, with "?" for each empty cell
+//
+// Both take an optional limit={n} on the number of words (default 100,
+// maximum 1000).  The dictionary is merged with the user's words.
+//
+// The stream is a start event with the pattern, a word event for each
+// word, with its score, and a done event with the pattern and the number
+// of words.  If the client disconnects first, the scan is stopped.
+func SuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("Entering SuggestionsHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		errmsg := "streaming is not supported"
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusInternalServerError)
+		return
+	}
+
+	// Get the pattern and the limit
+	params := r.URL.Query()
+	var pattern string
+	switch {
+	case params.Has("pattern"):
+		pattern = params.Get("pattern")
+	case params.Has("puzzle"):
+		pattern, ok = getWordPattern(w, r, session)
+		if !ok {
+			return
+		}
+	default:
+		errmsg := "either pattern or puzzle must be given"
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusBadRequest)
+		return
+	}
+	query, err := model.ParseWordQuery(pattern)
+	if err != nil {
+		errmsg := fmt.Sprintf("invalid pattern %q: %v", pattern, err)
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusBadRequest)
+		return
+	}
+	limit, ok := intParam(params.Get("limit"), DEFAULT_WORD_LIMIT, 1, MAX_WORD_LIMIT)
+	if !ok {
+		errmsg := fmt.Sprintf("invalid limit %q", params.Get("limit"))
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusBadRequest)
+		return
+	}
+	userWords, err := model.LoadUserWords(session.USERID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Stream the words as they are found, until there are no more, the
+	// limit is reached, or the client goes away
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	summary := SuggestionsSummary{Pattern: pattern}
+	writeEvent(w, "start", summary)
+	flusher.Flush()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	words := userWords.SearchWords(query, stop)
+	done := r.Context().Done()
+loop:
+	for summary.Count < limit {
+		select {
+		case <-done:
+			log.Printf("Client went away after %d suggestions for %q\n", summary.Count, pattern)
+			return
+		case sw, ok := <-words:
+			if !ok {
+				break loop
+			}
+			writeEvent(w, "word", sw)
+			flusher.Flush()
+			summary.Count++
+		}
+	}
+	writeEvent(w, "done", summary)
+	flusher.Flush()
+
+	log.Println("Leaving SuggestionsHandler")
+}
+
+// getWordPattern returns the pattern for the word given by the puzzle,
+// seq, and dir parameters, with "?" for each empty cell.  If it cannot
+// be found, an error is written to the response and false is returned.
+func getWordPattern(w http.ResponseWriter, r *http.Request, session *Session) (string, bool) {
+	params := r.URL.Query()
+	id, err := strconv.Atoi(params.Get("puzzle"))
+	if err != nil {
+		errmsg := fmt.Sprintf("invalid puzzle id %q", params.Get("puzzle"))
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusNotFound)
+		return "", false
+	}
+	pr := &puzzleRequest{w: w, r: r, session: session, id: id}
+	pr.access, err = model.GetPuzzleAccess(session.USERID, id)
+	if err != nil {
+		pr.dbError(err)
+		return "", false
+	}
+
+	// Only hold the session while the pattern is found, not while the
+	// words are streamed
+	session.Lock()
+	defer session.Unlock()
+	puzzle := pr.getPuzzle()
+	if puzzle == nil {
+		return "", false
+	}
+	seq, err := strconv.Atoi(params.Get("seq"))
+	if err != nil {
+		pr.error(fmt.Errorf("invalid word number %q", params.Get("seq")), http.StatusNotFound)
+		return "", false
+	}
+	dir, err := parseDirection(params.Get("dir"))
+	if err != nil {
+		pr.error(err, http.StatusNotFound)
+		return "", false
+	}
+	word := puzzle.LookupWordByNumber(seq, dir)
+	if word == nil {
+		pr.error(fmt.Errorf("no word %d %s", seq, dir), http.StatusNotFound)
+		return "", false
+	}
+	return strings.ReplaceAll(puzzle.GetAnswer(word), " ", "?"), true
+}
+
+// writeEvent writes a Server-Sent Event with the value as JSON data.
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	jsonBlob, err := json.Marshal(v)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonBlob)
+}
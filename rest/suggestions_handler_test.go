@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// sseEvent is one event of a Server-Sent Events stream
+type sseEvent struct {
+	event string
+	data  string
+}
+
+// doSuggestionsRequest sends a request to the suggestions handler and
+// returns the response
+func doSuggestionsRequest(ctx context.Context, session *Session, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/suggestions?"+query, nil)
+	if session != nil {
+		req.AddCookie(session.NewSessionCookie())
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SuggestionsHandler)
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// parseEvents splits a Server-Sent Events stream into its events
+func parseEvents(body string) []sseEvent {
+	events := make([]sseEvent, 0)
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		ev := sseEvent{}
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestSuggestionsHandler(t *testing.T) {
+	session, id := newTestSession(t, "rest-suggestions")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-suggestions")
+	rr := doPuzzleRequest(session, "PUT", "/puzzles/"+strconv.Itoa(id)+"/words/1/across/text", `{"text": "C T"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	puzzleQuery := "puzzle=" + strconv.Itoa(id) + "&seq=1&dir=across"
+
+	tests := []struct {
+		name        string
+		query       string
+		wantPattern string
+		wantCount   int
+	}{
+		{"pattern", "pattern=" + url.QueryEscape("HEART"), "HEART", 1},
+		{"limit", "pattern=" + url.QueryEscape("H*") + "&limit=5", "H*", 5},
+		{"clauses", "pattern=" + url.QueryEscape("C?T +A") + "&limit=3", "C?T +A", 1},
+		{"no matches", "pattern=" + url.QueryEscape("XQXQX"), "XQXQX", 0},
+		{"puzzle word", puzzleQuery + "&limit=3", "C?T", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doSuggestionsRequest(context.Background(), session, tt.query)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+			events := parseEvents(rr.Body.String())
+			assert.Equal(t, tt.wantCount+2, len(events))
+
+			start, done := events[0], events[len(events)-1]
+			assert.Equal(t, "start", start.event)
+			assert.Equal(t, "done", done.event)
+			summary := new(SuggestionsSummary)
+			assert.Nil(t, json.Unmarshal([]byte(done.data), summary))
+			assert.Equal(t, tt.wantPattern, summary.Pattern)
+			assert.Equal(t, tt.wantCount, summary.Count)
+
+			// Best first
+			query, err := model.ParseWordQuery(tt.wantPattern)
+			assert.Nil(t, err)
+			lastScore := 1 << 30
+			for _, ev := range events[1 : len(events)-1] {
+				assert.Equal(t, "word", ev.event)
+				sw := new(model.ScoredWord)
+				assert.Nil(t, json.Unmarshal([]byte(ev.data), sw))
+				assert.True(t, query.Matches(sw.Word), sw.Word)
+				assert.True(t, sw.Score <= lastScore)
+				lastScore = sw.Score
+			}
+		})
+	}
+}
+
+func TestSuggestionsHandler_Cancel(t *testing.T) {
+	session, _ := newTestSession(t, "rest-suggestions-cancel")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-suggestions-cancel")
+
+	// A client that has gone away gets no done event
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr := doSuggestionsRequest(ctx, session, "pattern=*&limit=1000")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	events := parseEvents(rr.Body.String())
+	assert.Equal(t, "start", events[0].event)
+	assert.NotEqual(t, "done", events[len(events)-1].event)
+	assert.Less(t, len(events), 1000)
+}
+
+func TestSuggestionsHandler_Errors(t *testing.T) {
+	session, id := newTestSession(t, "rest-suggestions-errors")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-suggestions-errors")
+	puzzleQuery := "puzzle=" + strconv.Itoa(id)
+
+	tests := []struct {
+		name    string
+		session *Session
+		query   string
+		want    int
+	}{
+		{"no session", nil, "pattern=C.T", http.StatusUnauthorized},
+		{"no pattern", session, "", http.StatusBadRequest},
+		{"bad pattern", session, "pattern=" + url.QueryEscape("C(T"), http.StatusBadRequest},
+		{"bad clause", session, "pattern=" + url.QueryEscape("C?T +1"), http.StatusBadRequest},
+		{"bad limit", session, "pattern=C.T&limit=0", http.StatusBadRequest},
+		{"bad puzzle", session, "puzzle=bogus&seq=1&dir=a", http.StatusNotFound},
+		{"no puzzle", session, "puzzle=0&seq=1&dir=a", http.StatusNotFound},
+		{"bad seq", session, puzzleQuery + "&seq=x&dir=a", http.StatusNotFound},
+		{"bad dir", session, puzzleQuery + "&seq=1&dir=x", http.StatusNotFound},
+		{"no word", session, puzzleQuery + "&seq=9&dir=a", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doSuggestionsRequest(context.Background(), tt.session, tt.query)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// RegisterHandler accepts a username and password from an HTTP POST
+// payload, the same as LoginHandler, and adds a new user with them.  The
+// new user is logged in, and returned as JSON with a 201 status.
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Entering RegisterHandler")
+
+	// Set the required CORS header(s)
+	requester := r.Header.Get("Origin")
+	w.Header().Set("Access-Control-Allow-Origin", requester)
+	w.Header().Set("Access-Control-Allow-Headers", "Credentials")
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+	switch r.Method {
+	case http.MethodOptions:
+		log.Println("Handled preflight OPTIONS request")
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Add the user
+	r.ParseForm()
+	user, err := model.CreateUser(r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		log.Println(err)
+		status := http.StatusBadRequest
+		if errors.Is(err, model.ErrNameConflict) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Log them in
+	session := NewSession()
+	session.USERID = user.ID
+	session.USERNAME = user.Username
+	if err := Sessions.Save(session); err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, session.NewSessionCookie())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeUser(w, user)
+
+	log.Println("Leaving RegisterHandler")
+}
+
+// UserHandler serves REST requests for the session's user:
+//
+//   - GET: Returns the user's profile as JSON
+//   - PUT: Updates the email, author, and address fields from the JSON
+//     in the body.  Fields that are not in the body are left as they
+//     are, and the username cannot be changed.
+func UserHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Entering UserHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := model.GetUser(session.USERID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(user); err != nil {
+			log.Println(err)
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		user.ID = session.USERID
+		user.Username = session.USERNAME
+		if err := model.UpdateProfile(user); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if user, err = model.GetUser(session.USERID); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeUser(w, user)
+
+	log.Println("Leaving UserHandler")
+}
+
+// PasswordHandler changes the session's user's password, given
+// {"old_password": old, "new_password": new} in a PUT request.
+func PasswordHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Entering PasswordHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", "PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println(err)
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = model.ChangePassword(session.USERID, body.OldPassword, body.NewPassword)
+	if err != nil {
+		log.Println(err)
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, model.ErrBadCredentials):
+			status = http.StatusForbidden
+		case errors.Is(err, model.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	log.Println("Leaving PasswordHandler")
+}
+
+// writeUser writes the user as JSON to the response.
+func writeUser(w http.ResponseWriter, user *model.User) {
+	jsonBlob, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(jsonBlob)
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// doUserRequest sends a request to a user handler and returns the
+// response
+func doUserRequest(handler http.HandlerFunc, session *Session, method, url, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if session != nil {
+		req.AddCookie(session.NewSessionCookie())
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// doRegister posts a username and password to the register handler
+func doRegister(username, password string) *httptest.ResponseRecorder {
+	cred := url.Values{}
+	cred.Set("username", username)
+	cred.Set("password", password)
+	req, _ := http.NewRequest("POST", "/register", strings.NewReader(cred.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(RegisterHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRegisterHandler(t *testing.T) {
+	const username = "rest-register-user"
+	defer func() {
+		con, _ := model.Connect()
+		defer con.Close()
+		con.Exec(`DELETE FROM users WHERE username=?`, username)
+	}()
+
+	// Register a new user, who is logged in
+	rr := doRegister(username, "first password")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	user := new(model.User)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), user))
+	assert.Equal(t, username, user.Username)
+	cookies := rr.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	session, err := Sessions.Get(cookies[0].Value)
+	assert.Nil(t, err)
+	assert.NotNil(t, session)
+	assert.Equal(t, user.ID, session.USERID)
+
+	// Update the profile
+	rr = doUserRequest(UserHandler, session, "PUT", "/user",
+		`{"username": "ignored", "author_name": "Rest Author", "address_city": "Cary"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doUserRequest(UserHandler, session, "GET", "/user", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), user))
+	assert.Equal(t, username, user.Username)
+	assert.Equal(t, "Rest Author", user.AuthorName)
+	assert.Equal(t, "Cary", user.AddressCity)
+
+	// Change the password
+	rr = doUserRequest(PasswordHandler, session, "PUT", "/user/password",
+		`{"old_password": "wrong password", "new_password": "second password"}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = doUserRequest(PasswordHandler, session, "PUT", "/user/password",
+		`{"old_password": "first password", "new_password": "short"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doUserRequest(PasswordHandler, session, "PUT", "/user/password",
+		`{"old_password": "first password", "new_password": "second password"}`)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	w := httptest.NewRecorder()
+	_, err = ValidateCredentials(w, username, "first password")
+	assert.NotNil(t, err)
+	userid, err := ValidateCredentials(w, username, "second password")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, userid)
+
+	// Errors
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     int
+	}{
+		{"taken", username, "another password", http.StatusConflict},
+		{"short password", "rest-register-other", "short", http.StatusBadRequest},
+		{"no username", "", "long enough", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doRegister(tt.username, tt.password)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	rr = doUserRequest(UserHandler, nil, "GET", "/user", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr = doUserRequest(PasswordHandler, session, "GET", "/user/password", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/philhanna/cwcomp/model"
+)
+
+// ---------------------------------------------------------------------
+// Type definitions
+// ---------------------------------------------------------------------
+
+// WordList is the response to a word search
+type WordList struct {
+	Query string             `json:"query"`
+	Words []model.ScoredWord `json:"words"`
+}
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// The default and maximum number of words returned by a search
+const (
+	DEFAULT_WORD_LIMIT = 100
+	MAX_WORD_LIMIT     = 1000
+)
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// WordsHandler serves REST requests for:
+//
+//   - GET /words?q={query}&limit={n}: Returns the words that match the
+//     query, best first, from the dictionary merged with the user's
+//     words.  The query language is described in model.WordQuery.  At
+//     most limit words are returned (default 100, maximum 1000).
+func WordsHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Println("Entering WordsHandler")
+
+	// Get the session
+	session, err := GetSession(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse the query and the limit
+	queryString := r.URL.Query().Get("q")
+	query, err := model.ParseWordQuery(queryString)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, ok := intParam(r.URL.Query().Get("limit"), DEFAULT_WORD_LIMIT, 1, MAX_WORD_LIMIT)
+	if !ok {
+		errmsg := fmt.Sprintf("invalid limit %q", r.URL.Query().Get("limit"))
+		log.Println(errmsg)
+		http.Error(w, errmsg, http.StatusBadRequest)
+		return
+	}
+
+	// Search the dictionary
+	userWords, err := model.LoadUserWords(session.USERID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	list := WordList{Query: queryString, Words: make([]model.ScoredWord, 0)}
+	stop := make(chan struct{})
+	for sw := range userWords.SearchWords(query, stop) {
+		list.Words = append(list.Words, sw)
+		if len(list.Words) == limit {
+			break
+		}
+	}
+	close(stop)
+
+	jsonBlob, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonBlob)
+
+	log.Println("Leaving WordsHandler")
+}
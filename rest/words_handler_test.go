@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/philhanna/cwcomp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// doWordsRequest sends a request to the words handler and returns the
+// response
+func doWordsRequest(session *Session, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/words?"+query, nil)
+	if session != nil {
+		req.AddCookie(session.NewSessionCookie())
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(WordsHandler)
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestWordsHandler(t *testing.T) {
+	session, _ := newTestSession(t, "rest-words")
+	defer model.NewPuzzle(3).DeletePuzzle(TEST_USERID, "rest-words")
+	tests := []struct {
+		name       string
+		session    *Session
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"good", session, "q=" + url.QueryEscape("HE@RT"), http.StatusOK, 1},
+		{"limit", session, "q=" + url.QueryEscape("H*") + "&limit=5", http.StatusOK, 5},
+		{"bad query", session, "q=" + url.QueryEscape("(A|B)"), http.StatusBadRequest, 0},
+		{"bad limit", session, "q=H*&limit=0", http.StatusBadRequest, 0},
+		{"no session", nil, "q=H*", http.StatusUnauthorized, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doWordsRequest(tt.session, tt.query)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if rr.Code != http.StatusOK {
+				return
+			}
+			list := new(WordList)
+			assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), list))
+			assert.Equal(t, tt.wantCount, len(list.Words))
+		})
+	}
+}
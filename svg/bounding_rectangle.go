@@ -13,11 +13,11 @@ func (svg *SVG) BoundingRectangle() string {
 	sb.WriteString("\n<!-- Bounding rectangle -->\n")
 	sb.WriteString(fmt.Sprintf(
 		"<rect width=%q height=%q fill=%q stroke=%q stroke-width=%q/>\n",
-		strconv.Itoa(svg.nPixels), // width
-		strconv.Itoa(svg.nPixels), // height
-		"white",                   // fill
-		"black",                   // stroke
-		"2",                       // stroke-width
+		strconv.Itoa(svg.width),  // width
+		strconv.Itoa(svg.height), // height
+		"white",                  // fill
+		"black",                  // stroke
+		"2",                      // stroke-width
 	))
 	return sb.String()
 }
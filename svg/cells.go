@@ -2,6 +2,7 @@ package svg
 
 import (
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 )
@@ -13,9 +14,9 @@ const BLACK_CELL = '\x00'
 func (svg *SVG) Cells() string {
 	sb := strings.Builder{}
 	sb.WriteString("\n<!-- Cells -->\n")
-	for r := 1; r <= svg.n; r++ {
+	for r := 1; r <= svg.nRows; r++ {
 		yBase := (r - 1) * BOXSIZE
-		for c := 1; c <= svg.n; c++ {
+		for c := 1; c <= svg.nCols; c++ {
 			xBase := (c - 1) * BOXSIZE
 			if svg.cells[r-1][c-1] == BLACK_CELL {
 				sb.WriteString(fmt.Sprintf(
@@ -26,6 +27,19 @@ func (svg *SVG) Cells() string {
 					strconv.Itoa(BOXSIZE),
 					"black",
 				))
+			} else if text, ok := svg.rebus[(r-1)*svg.nCols+(c-1)]; ok {
+				fontSize := REBUS_FONT_FACTOR / len(text)
+				if fontSize < REBUS_MIN_FONT_SIZE {
+					fontSize = REBUS_MIN_FONT_SIZE
+				}
+				sb.WriteString(fmt.Sprintf(
+					"<text x=%q y=%q font-size=%q font-family=%q>%s</text>\n",
+					strconv.Itoa(xBase+REBUS_X_OFFSET),
+					strconv.Itoa(yBase+LETTER_Y_OFFSET),
+					fmt.Sprintf("%dpt", fontSize),
+					LETTER_FONT_FAMILY,
+					html.EscapeString(text),
+				))
 			} else {
 				letter := svg.cells[r-1][c-1]
 				if letter != ' ' {
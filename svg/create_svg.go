@@ -9,9 +9,14 @@ import (
 // ---------------------------------------------------------------------
 
 type SVG struct {
-	n       int      // Number of either rows or columns in the grid (assumed to be the same)
-	nPixels int      // Width or height of grid in pixels
-	cells   [][]byte // Simple matrix representation of the grid
+	nRows  int               // Number of rows in the grid
+	nCols  int               // Number of columns in the grid
+	width  int               // Width of grid in pixels
+	height int               // Height of grid in pixels
+	cells  [][]byte          // Simple matrix representation of the grid
+	rebus  map[int]string    // Full text of rebus cells, by cell index
+	styles map[int]cellStyle // Circled, shaded, or barred cells, by cell index
+	heat   map[int]int       // Number of ways to fill cells, by cell index
 }
 
 // ---------------------------------------------------------------------
@@ -31,6 +36,18 @@ const (
 	NUMBER_X_OFFSET  = 2
 	NUMBER_Y_OFFSET  = 10
 	NUMBER_FONT_SIZE = "8pt"
+
+	// The font size (in points) of a rebus cell is this factor divided
+	// by the number of letters, so that the text fits in the box, but
+	// it is never smaller than the minimum.
+	REBUS_X_OFFSET      = 2
+	REBUS_FONT_FACTOR   = 35
+	REBUS_MIN_FONT_SIZE = 4
+
+	// Circles are inset from the sides of the cell by this margin, and
+	// bars are drawn with this stroke width.
+	CIRCLE_MARGIN = 1
+	BAR_WIDTH     = "3"
 )
 
 // ---------------------------------------------------------------------
@@ -39,12 +56,19 @@ const (
 
 // NewSVG will create a new SVG object from an abstract matrix of bytes.
 // This for convenience of unit testing, since it does not need any
-// reference to the model.
+// reference to the model.  The matrix need not be square.
 func NewSVG(cells [][]byte) *SVG {
 	svg := new(SVG)
-	svg.n = len(cells)
-	svg.nPixels = svg.n * BOXSIZE
+	svg.nRows = len(cells)
+	if svg.nRows > 0 {
+		svg.nCols = len(cells[0])
+	}
+	svg.width = svg.nCols * BOXSIZE
+	svg.height = svg.nRows * BOXSIZE
 	svg.cells = cells
+	svg.rebus = make(map[int]string)
+	svg.styles = make(map[int]cellStyle)
+	svg.heat = make(map[int]int)
 	return svg
 }
 
@@ -57,14 +81,36 @@ func (svg *SVG) GenerateSVG() string {
 	sb := strings.Builder{}
 	sb.WriteString(svg.Root())
 	sb.WriteString(svg.BoundingRectangle())
+	if len(svg.styles) > 0 {
+		sb.WriteString(svg.Shading())
+	}
+	if len(svg.heat) > 0 {
+		sb.WriteString(svg.HeatMap())
+	}
 	sb.WriteString(svg.VerticalLines())
 	sb.WriteString(svg.HorizontalLines())
 	sb.WriteString(svg.Cells())
+	if len(svg.styles) > 0 {
+		sb.WriteString(svg.Circles())
+		sb.WriteString(svg.Bars())
+	}
 	sb.WriteString(svg.WordNumbers())
 	sb.WriteString(svg.EndRoot())
 	return sb.String()
 }
 
+// SetRebus sets the full text of a rebus cell, which is drawn in a
+// smaller font than an ordinary letter so that it fits in the cell.
+// The row and column are relative to 1, not 0.
+func (svg *SVG) SetRebus(r, c int, text string) {
+	index := (r-1)*svg.nCols + (c - 1)
+	if len(text) > 1 {
+		svg.rebus[index] = text
+	} else {
+		delete(svg.rebus, index)
+	}
+}
+
 // EndRoot creates the closing </svg> element.
 func (svg *SVG) EndRoot() string {
 	return "\n</svg>\n"
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -33,8 +34,105 @@ func TestSVG_GenerateSVG(t *testing.T) {
 	log.Printf("SVG output written to %v\n", filename)
 }
 
+func TestSVG_GenerateSVG_Rectangular(t *testing.T) {
+	cells := [][]byte{
+		{BLK, 'N', 'O', 'W', ' '},
+		{'B', 'L', 'U', 'E', ' '},
+		{' ', ' ', ' ', ' ', BLK},
+	}
+	svg := NewSVG(cells)
+	have := svg.GenerateSVG()
+	if !strings.Contains(have, `width="160" height="96"`) {
+		t.Errorf("SVG root has wrong dimensions:\n%s", svg.Root())
+	}
+}
+
 func getOutputDirectory() string {
 	output := filepath.Join(os.TempDir(), "cwcomp")
 	os.MkdirAll(output, 0750)
 	return output
 }
+
+func TestSVG_GenerateSVG_Rebus(t *testing.T) {
+	cells := [][]byte{
+		{'H', 'A', BLK},
+		{' ', ' ', ' '},
+	}
+	svg := NewSVG(cells)
+	svg.SetRebus(1, 1, "HEART")
+	have := svg.GenerateSVG()
+	if !strings.Contains(have, `font-size="7pt" font-family="monospace">HEART</text>`) {
+		t.Errorf("rebus cell not drawn in a smaller font:\n%s", svg.Cells())
+	}
+	if strings.Contains(have, `>H</text>`) {
+		t.Errorf("rebus cell also drawn as a single letter:\n%s", svg.Cells())
+	}
+}
+
+func TestSVG_GenerateSVG_Styles(t *testing.T) {
+	cells := [][]byte{
+		{'H', 'A', BLK},
+		{' ', ' ', ' '},
+	}
+	svg := NewSVG(cells)
+	svg.SetCircled(1, 1, true)
+	svg.SetShade(1, 2, "#C0C0C0")
+	svg.SetBars(2, 1, "rb")
+	svg.SetCircled(1, 3, true) // Black cells are not styled
+	have := svg.GenerateSVG()
+
+	wants := []string{
+		`<circle cx="16" cy="16" r="15" fill="none"`,
+		`<rect x="32" y="0" width="32" height="32" fill="#C0C0C0"/>`,
+		`<line x1="32" x2="32" y1="32" y2="64" stroke="black" stroke-width="3"/>`,
+		`<line x1="0" x2="32" y1="64" y2="64" stroke="black" stroke-width="3"/>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(have, want) {
+			t.Errorf("missing %s in:\n%s", want, have)
+		}
+	}
+	if strings.Count(have, "<circle") != 1 {
+		t.Errorf("black cell was circled:\n%s", svg.Circles())
+	}
+
+	// Removing all the attributes removes the style
+	svg.SetShade(1, 2, "")
+	svg.SetBars(2, 1, "")
+	svg.SetCircled(1, 1, false)
+	svg.SetCircled(1, 3, false)
+	if strings.Contains(svg.GenerateSVG(), "<!-- Circles -->") {
+		t.Errorf("styles not removed")
+	}
+}
+
+func TestSVG_GenerateSVG_HeatMap(t *testing.T) {
+	cells := [][]byte{
+		{'H', 'A', BLK},
+		{' ', ' ', ' '},
+	}
+	svg := NewSVG(cells)
+	svg.SetHeat(1, 1, 0)
+	svg.SetHeat(1, 2, 3)
+	svg.SetHeat(2, 1, 20)
+	svg.SetHeat(2, 2, 1000)
+	svg.SetHeat(1, 3, 0) // Black cells are not colored
+	have := svg.GenerateSVG()
+
+	wants := []string{
+		`<rect x="0" y="0" width="32" height="32" fill="#FF0000" fill-opacity="0.5"/>`,
+		`<rect x="32" y="0" width="32" height="32" fill="#FFA500" fill-opacity="0.5"/>`,
+		`<rect x="0" y="32" width="32" height="32" fill="#FFFF00" fill-opacity="0.5"/>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(have, want) {
+			t.Errorf("missing %s in:\n%s", want, have)
+		}
+	}
+	if strings.Count(have, "fill-opacity") != 3 {
+		t.Errorf("wrong cells colored:\n%s", svg.HeatMap())
+	}
+	if strings.Index(have, "<!-- Heat map -->") > strings.Index(have, "<!-- Vertical lines -->") {
+		t.Errorf("heat map drawn over the grid lines")
+	}
+}
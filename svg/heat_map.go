@@ -0,0 +1,88 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Constants and variables
+// ---------------------------------------------------------------------
+
+// Cells in a heat map are colored by the number of ways they can be
+// filled: a cell that cannot be filled at all is dead, and otherwise
+// it is hot or warm if it has at most the specified number of choices.
+// The colors are drawn translucent so that shading still shows.
+const (
+	HEAT_DEAD_COLOR   = "#FF0000"
+	HEAT_HOT_COLOR    = "#FFA500"
+	HEAT_WARM_COLOR   = "#FFFF00"
+	HEAT_OPACITY      = "0.5"
+	HEAT_HOT_CHOICES  = 5
+	HEAT_WARM_CHOICES = 50
+)
+
+// ---------------------------------------------------------------------
+// Methods
+// ---------------------------------------------------------------------
+
+// SetHeat sets the number of ways a cell can be filled, which is shown
+// as a color in the heat map layer.  A negative number removes the cell
+// from the heat map.  The row and column are relative to 1, not 0.
+func (svg *SVG) SetHeat(r, c int, choices int) {
+	index := (r-1)*svg.nCols + (c - 1)
+	if choices < 0 {
+		delete(svg.heat, index)
+	} else {
+		svg.heat[index] = choices
+	}
+}
+
+// HeatMap generates the colored rectangles of the heat map.  Like the
+// shading, these are drawn before the grid lines.
+func (svg *SVG) HeatMap() string {
+	sb := strings.Builder{}
+	sb.WriteString("\n<!-- Heat map -->\n")
+	for r := 1; r <= svg.nRows; r++ {
+		for c := 1; c <= svg.nCols; c++ {
+			choices, ok := svg.heat[(r-1)*svg.nCols+(c-1)]
+			if !ok || svg.cells[r-1][c-1] == BLACK_CELL {
+				continue
+			}
+			color := HeatColor(choices)
+			if color == "" {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(
+				"<rect x=%q y=%q width=%q height=%q fill=%q fill-opacity=%q/>\n",
+				strconv.Itoa((c-1)*BOXSIZE),
+				strconv.Itoa((r-1)*BOXSIZE),
+				strconv.Itoa(BOXSIZE),
+				strconv.Itoa(BOXSIZE),
+				color,
+				HEAT_OPACITY,
+			))
+		}
+	}
+	return sb.String()
+}
+
+// ---------------------------------------------------------------------
+// Functions
+// ---------------------------------------------------------------------
+
+// HeatColor returns the heat map color for a cell with this number of
+// choices, or "" if it has enough that it is not colored.
+func HeatColor(choices int) string {
+	switch {
+	case choices == 0:
+		return HEAT_DEAD_COLOR
+	case choices <= HEAT_HOT_CHOICES:
+		return HEAT_HOT_COLOR
+	case choices <= HEAT_WARM_CHOICES:
+		return HEAT_WARM_COLOR
+	default:
+		return ""
+	}
+}
@@ -10,15 +10,15 @@ import (
 func (svg *SVG) HorizontalLines() string {
 	sb := strings.Builder{}
 	sb.WriteString("\n<!-- Horizontal lines -->\n")
-	for x := 0; x < svg.n; x++ {
+	for y := 0; y < svg.nRows; y++ {
 		sb.WriteString(fmt.Sprintf(
 			"<line x1=%q x2=%q y1=%q y2=%q stroke=%q stroke-width=%q/>\n",
-			"0",                       // x1
-			strconv.Itoa(svg.nPixels), // x2
-			strconv.Itoa(x*BOXSIZE),   // y1
-			strconv.Itoa(x*BOXSIZE),   // y2
-			"black",                   // stroke
-			"0.5",                     // stroke-width
+			"0",                     // x1
+			strconv.Itoa(svg.width), // x2
+			strconv.Itoa(y*BOXSIZE), // y1
+			strconv.Itoa(y*BOXSIZE), // y2
+			"black",                 // stroke
+			"0.5",                   // stroke-width
 		))
 	}
 	return sb.String()
@@ -16,16 +16,15 @@ func (svg *SVG) Root() string {
 	sb.WriteString(fmt.Sprintf("<svg xmlns=%q xmlns:xlink=%q id=%q",
 		XMLNS,
 		XMLNS_XLINK,
-		fmt.Sprintf("svg%dx%d", svg.n, svg.n),
+		fmt.Sprintf("svg%dx%d", svg.nCols, svg.nRows),
 	))
 
 	// Add the width and height
-	sizeAttr := strconv.Itoa(svg.nPixels)
-	sb.WriteString(fmt.Sprintf(" width=%q", sizeAttr))
-	sb.WriteString(fmt.Sprintf(" height=%q", sizeAttr))
+	sb.WriteString(fmt.Sprintf(" width=%q", strconv.Itoa(svg.width)))
+	sb.WriteString(fmt.Sprintf(" height=%q", strconv.Itoa(svg.height)))
 
 	// Add the viewport
-	vattr := fmt.Sprintf("%d %d %d %d", 0, 0, svg.nPixels, svg.nPixels)
+	vattr := fmt.Sprintf("%d %d %d %d", 0, 0, svg.width, svg.height)
 	sb.WriteString(fmt.Sprintf(" viewport=%q", vattr))
 
 	// Done
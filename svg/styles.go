@@ -0,0 +1,142 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// cellStyle is the style of one cell of the grid
+type cellStyle struct {
+	circled bool   // True if a circle is drawn in the cell
+	shade   string // Fill color, or "" if not shaded
+	bars    string // Sides with bars, as letters T, R, B, and L
+}
+
+// SetCircled sets whether a circle is drawn in the cell.  The row and
+// column are relative to 1, not 0.
+func (svg *SVG) SetCircled(r, c int, circled bool) {
+	svg.updateStyle(r, c, func(style *cellStyle) {
+		style.circled = circled
+	})
+}
+
+// SetShade sets the color with which the cell is filled, e.g., "gray"
+// or "#C0C0C0".  An empty color removes the shading.  The row and
+// column are relative to 1, not 0.
+func (svg *SVG) SetShade(r, c int, color string) {
+	svg.updateStyle(r, c, func(style *cellStyle) {
+		style.shade = color
+	})
+}
+
+// SetBars sets the sides of the cell on which a heavy bar is drawn, as
+// a string of the letters T, R, B, and L.  The row and column are
+// relative to 1, not 0.
+func (svg *SVG) SetBars(r, c int, bars string) {
+	svg.updateStyle(r, c, func(style *cellStyle) {
+		style.bars = strings.ToUpper(bars)
+	})
+}
+
+// updateStyle applies a change to the style of a cell, removing it if
+// it is left with no attributes.
+func (svg *SVG) updateStyle(r, c int, update func(*cellStyle)) {
+	index := (r-1)*svg.nCols + (c - 1)
+	style := svg.styles[index]
+	update(&style)
+	if style == (cellStyle{}) {
+		delete(svg.styles, index)
+	} else {
+		svg.styles[index] = style
+	}
+}
+
+// Shading generates the filled rectangles of shaded cells.  These are
+// drawn before the grid lines so that they do not hide them.
+func (svg *SVG) Shading() string {
+	sb := strings.Builder{}
+	sb.WriteString("\n<!-- Shading -->\n")
+	svg.forEachStyle(func(xBase, yBase int, style cellStyle) {
+		if style.shade == "" {
+			return
+		}
+		sb.WriteString(fmt.Sprintf(
+			"<rect x=%q y=%q width=%q height=%q fill=%q/>\n",
+			strconv.Itoa(xBase),
+			strconv.Itoa(yBase),
+			strconv.Itoa(BOXSIZE),
+			strconv.Itoa(BOXSIZE),
+			html.EscapeString(style.shade),
+		))
+	})
+	return sb.String()
+}
+
+// Circles generates the circles in circled cells.
+func (svg *SVG) Circles() string {
+	sb := strings.Builder{}
+	sb.WriteString("\n<!-- Circles -->\n")
+	svg.forEachStyle(func(xBase, yBase int, style cellStyle) {
+		if !style.circled {
+			return
+		}
+		sb.WriteString(fmt.Sprintf(
+			"<circle cx=%q cy=%q r=%q fill=%q stroke=%q stroke-width=%q/>\n",
+			strconv.Itoa(xBase+BOXSIZE/2),
+			strconv.Itoa(yBase+BOXSIZE/2),
+			strconv.Itoa(BOXSIZE/2-CIRCLE_MARGIN),
+			"none",
+			"black",
+			"0.5",
+		))
+	})
+	return sb.String()
+}
+
+// Bars generates the heavy bars on the sides of barred cells.
+func (svg *SVG) Bars() string {
+	sb := strings.Builder{}
+	sb.WriteString("\n<!-- Bars -->\n")
+	svg.forEachStyle(func(xBase, yBase int, style cellStyle) {
+		for _, side := range style.bars {
+			x1, y1, x2, y2 := xBase, yBase, xBase+BOXSIZE, yBase+BOXSIZE
+			switch side {
+			case 'T':
+				y2 = yBase
+			case 'R':
+				x1 = x2
+			case 'B':
+				y1 = y2
+			case 'L':
+				x2 = x1
+			default:
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(
+				"<line x1=%q x2=%q y1=%q y2=%q stroke=%q stroke-width=%q/>\n",
+				strconv.Itoa(x1),
+				strconv.Itoa(x2),
+				strconv.Itoa(y1),
+				strconv.Itoa(y2),
+				"black",
+				BAR_WIDTH,
+			))
+		}
+	})
+	return sb.String()
+}
+
+// forEachStyle calls a function with the upper left corner and the
+// style of each styled cell, in row major order.
+func (svg *SVG) forEachStyle(f func(xBase, yBase int, style cellStyle)) {
+	for r := 1; r <= svg.nRows; r++ {
+		for c := 1; c <= svg.nCols; c++ {
+			style, ok := svg.styles[(r-1)*svg.nCols+(c-1)]
+			if ok && svg.cells[r-1][c-1] != BLACK_CELL {
+				f((c-1)*BOXSIZE, (r-1)*BOXSIZE, style)
+			}
+		}
+	}
+}
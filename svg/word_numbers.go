@@ -11,8 +11,8 @@ import (
 
 type WordNumber struct {
 	Seq int
-	Row int // 1, 2, ..., n
-	Col int // 1, 2, ..., n
+	Row int // 1, 2, ..., nRows
+	Col int // 1, 2, ..., nCols
 }
 
 // ---------------------------------------------------------------------
@@ -39,11 +39,10 @@ func (svg *SVG) WordNumbers() string {
 // of an across word and/or a down word.
 func (svg *SVG) GetNumberedCells() []WordNumber {
 	cells := svg.cells
-	var n = len(cells)
 	var seq = 0
 	ncs := make([]WordNumber, 0)
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
+	for i := 0; i < svg.nRows; i++ {
+		for j := 0; j < svg.nCols; j++ {
 			if cells[i][j] != BLACK_CELL {
 				startD := (i == 0) || (cells[i-1][j] == BLACK_CELL)
 				startA := (j == 0) || (cells[i][j-1] == BLACK_CELL)
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passwords must be at least this long, and bcrypt only uses the first
+// 72 bytes of them, so longer ones are refused rather than truncated.
+const (
+	MIN_PASSWORD_LENGTH = 8
+	MAX_PASSWORD_LENGTH = 72
+)
+
+// HashPassword returns a salted bcrypt hash of the password, which
+// includes the salt and cost so that CheckPassword needs nothing else.
+// New passwords should be checked with ValidatePassword first; this
+// only refuses ones that are too long for bcrypt.
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// CheckPassword returns true if the password matches the stored hash.
+// The hash is either one made by HashPassword or, for users created
+// before it was used, an unsalted Hash256.  In that case, or if the
+// bcrypt cost has since been raised, needsUpgrade is also true, and the
+// caller should replace the stored hash with a new one.
+func CheckPassword(hash []byte, password string) (ok bool, needsUpgrade bool) {
+	if !isBcryptHash(hash) {
+		ok = subtle.ConstantTimeCompare(hash, Hash256(password)) == 1
+		return ok, ok
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost(hash)
+	return true, err != nil || cost < bcrypt.DefaultCost
+}
+
+// ValidatePassword returns an error if a new password is too short or
+// too long.
+func ValidatePassword(password string) error {
+	switch {
+	case len(password) < MIN_PASSWORD_LENGTH:
+		return fmt.Errorf("password must be at least %d characters long", MIN_PASSWORD_LENGTH)
+	case len(password) > MAX_PASSWORD_LENGTH:
+		return fmt.Errorf("password must be at most %d bytes long", MAX_PASSWORD_LENGTH)
+	}
+	return nil
+}
+
+// isBcryptHash returns true if the hash is in the bcrypt format, which
+// starts with "$2a$", "$2b$", or "$2y$", rather than a raw SHA-256.
+func isBcryptHash(hash []byte) bool {
+	return len(hash) > 4 && bytes.HasPrefix(hash, []byte("$2")) && hash[3] == '$'
+}
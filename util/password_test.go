@@ -0,0 +1,68 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.Nil(t, err)
+	assert.True(t, isBcryptHash(hash))
+
+	// The same password hashes differently each time, because of the salt
+	other, err := HashPassword("correct horse")
+	assert.Nil(t, err)
+	assert.NotEqual(t, string(hash), string(other))
+
+	_, err = HashPassword(string(make([]byte, 73)))
+	assert.NotNil(t, err)
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, _ := HashPassword("correct horse")
+	cheap, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	legacy := Hash256("waffle")
+
+	tests := []struct {
+		name             string
+		hash             []byte
+		password         string
+		wantOK           bool
+		wantNeedsUpgrade bool
+	}{
+		{"bcrypt", hash, "correct horse", true, false},
+		{"bcrypt wrong", hash, "wrong horse", false, false},
+		{"bcrypt low cost", cheap, "correct horse", true, true},
+		{"legacy", legacy, "waffle", true, true},
+		{"legacy wrong", legacy, "wffl", false, false},
+		{"empty", []byte{}, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsUpgrade := CheckPassword(tt.hash, tt.password)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantNeedsUpgrade, needsUpgrade)
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"good", "12345678", false},
+		{"too short", "1234567", true},
+		{"too long", string(make([]byte, 73)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}